@@ -2,14 +2,21 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"Lisa/internal/ai"
+	"Lisa/internal/alerts"
+	"Lisa/internal/bot"
 	"Lisa/internal/config"
+	"Lisa/internal/database"
+	"Lisa/internal/jira"
 	"Lisa/internal/whatsapp"
 )
 
@@ -40,14 +47,268 @@ func main() {
 	// Inicializar servicios
 	log.Println("INIT: Inicializando servicios...")
 
-	// 1. WhatsApp Client
+	// Alertas para administradores
+	notifier := alerts.NewNotifier()
+	for _, url := range cfg.Alerts.WebhookURLs {
+		notifier.AddChannel(alerts.NewWebhookChannel(url))
+	}
+
+	// 1. Base de datos
+	log.Println("DB: Conectando a PostgreSQL...")
+	repo, err := database.New(ctx, cfg.WhatsApp.DatabaseURI)
+	if err != nil {
+		log.Fatalf("ERROR: No se pudo conectar a la base de datos: %v", err)
+	}
+	defer repo.Close()
+
+	if err := repo.Migrate(ctx); err != nil {
+		log.Fatalf("ERROR: No se pudieron aplicar las migraciones: %v", err)
+	}
+
+	// 2. WhatsApp Client
 	log.Println("WA: Inicializando cliente WhatsApp...")
 	waClient, err := whatsapp.NewClient(cfg)
 	if err != nil {
 		log.Fatalf("ERROR: No se pudo crear cliente WhatsApp: %v", err)
 	}
 
-	// Conectar WhatsApp
+	// Archivar cambios de participantes (auditoría de acceso a los grupos). Se
+	// guardan en segundo plano para no frenar los eventos de WhatsApp si la
+	// base de datos tarda.
+	participantEvents := make(chan *database.ParticipantEvent, 256)
+	go func() {
+		for evt := range participantEvents {
+			saveCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+			if err := repo.SaveParticipantEvent(saveCtx, evt); err != nil {
+				log.Printf("DB: %v", err)
+			}
+			cancel()
+		}
+	}()
+	waClient.Events().Subscribe(func(evt whatsapp.Event) {
+		switch evt.Type {
+		case whatsapp.EventParticipantJoined, whatsapp.EventParticipantLeft,
+			whatsapp.EventParticipantPromoted, whatsapp.EventParticipantDemoted:
+			select {
+			case participantEvents <- &database.ParticipantEvent{
+				GroupJID:    evt.Chat.String(),
+				GroupName:   evt.ChatName,
+				Participant: evt.Participant.String(),
+				Action:      string(evt.Type),
+				Actor:       evt.Actor.String(),
+				Reason:      evt.Reason,
+				CreatedAt:   evt.Timestamp,
+			}:
+			default:
+				log.Printf("DB: Cola de participantes llena, se descarta %s de %s en %s", evt.Type, evt.Participant, evt.Chat)
+			}
+		}
+	})
+
+	// Avisar a los administradores cuando la sesión se cierra y hay que volver a vincular
+	waClient.Events().Subscribe(func(evt whatsapp.Event) {
+		switch evt.Type {
+		case whatsapp.EventLoggedOut:
+			go notifier.Notify(ctx, alerts.Alert{
+				Level:   alerts.LevelCritical,
+				Title:   "WhatsApp: sesion cerrada",
+				Message: fmt.Sprintf("WhatsApp cerro la sesion de Lisa (%s). Se elimino el dispositivo y se inicio una nueva vinculacion.", evt.Reason),
+			})
+		case whatsapp.EventPairingCode:
+			go notifier.Notify(ctx, alerts.Alert{
+				Level:   alerts.LevelWarning,
+				Title:   "WhatsApp: codigo de vinculacion",
+				Message: fmt.Sprintf("Ingresa el codigo %s en WhatsApp > Dispositivos vinculados > Vincular con numero de telefono.", evt.Reason),
+			})
+		case whatsapp.EventPaired:
+			go notifier.Notify(ctx, alerts.Alert{
+				Level:   alerts.LevelInfo,
+				Title:   "WhatsApp: dispositivo vinculado",
+				Message: "Lisa quedo vinculada con WhatsApp correctamente.",
+			})
+		}
+	})
+
+	// 3. Jira (opcional)
+	var jiraClient *jira.Client
+	if cfg.Jira.URL != "" && cfg.Jira.Token != "" {
+		log.Printf("JIRA: Inicializando cliente Jira (%s)...", cfg.Jira.Mode)
+		jiraClient, err = jira.NewClient(cfg.Jira)
+		if err != nil {
+			log.Fatalf("ERROR: No se pudo crear el cliente de Jira: %v", err)
+		}
+		jiraClient.SetAttachmentStore(repo)
+	}
+
+	// 4. IA: Gemini si hay GEMINI_API_KEY; si no, el triaje clasifica por palabras clave y
+	// los resúmenes y los borradores de tickets se arman sin IA
+	var gemini *ai.GeminiClient
+	if cfg.Gemini.APIKey != "" {
+		log.Printf("AI: Inicializando Gemini (%s)...", cfg.Gemini.Model)
+		gemini, err = ai.NewGeminiClient(cfg.Gemini)
+		if err != nil {
+			log.Fatalf("ERROR: No se pudo crear el cliente de Gemini: %v", err)
+		}
+	} else {
+		log.Println("AI: WARN: GEMINI_API_KEY no configurado, el triaje clasifica por palabras clave y los resumenes y borradores de tickets se arman sin IA")
+	}
+	classifier := ai.NewClassifier(gemini)
+	var summarizer *ai.Summarizer
+	var drafter *ai.TicketDrafter
+	if gemini != nil {
+		summarizer = ai.NewSummarizer(gemini)
+		drafter = ai.NewTicketDrafter(gemini)
+	}
+
+	// Comandos del chat (!ayuda, !ticket, !estado, !buscar, !resumen)
+	waServices := whatsapp.CommandServices{OnCall: cfg.WhatsApp.OnCallJIDs}
+	if jiraClient != nil {
+		waServices.Tickets = jiraClient
+		waServices.Workflow = jiraClient
+		waServices.Search = jiraClient
+		waServices.Attachments = jiraClient
+	}
+	if summarizer != nil {
+		waServices.Summarizer = summarizer
+	}
+	if err := whatsapp.RegisterDefaultCommands(waClient.Commands(), waServices); err != nil {
+		log.Fatalf("ERROR: No se pudieron registrar los comandos de WhatsApp: %v", err)
+	}
+
+	// 5. Discord Bot
+	log.Println("DC: Inicializando bot de Discord...")
+	dcBot, err := bot.New(cfg.Discord)
+	if err != nil {
+		log.Fatalf("ERROR: No se pudo crear el bot de Discord: %v", err)
+	}
+
+	// Servicios de los comandos y del triaje
+	dcServices := bot.Services{WhatsApp: waClient, Links: repo, Database: repo, Classifier: classifier}
+	if jiraClient != nil {
+		dcServices.Tickets = jiraClient
+		dcServices.Workflow = jiraClient
+		dcServices.Directory = jiraClient
+	}
+	if summarizer != nil {
+		dcServices.Summarizer = summarizer
+	}
+	if drafter != nil {
+		dcServices.Drafter = drafter
+	}
+
+	// Puente entre chats de WhatsApp y canales de Discord
+	if len(cfg.Discord.BridgeChannels) > 0 || cfg.Discord.ThreadChannelID != "" {
+		bridge, err := bot.NewBridge(dcBot, waClient, repo)
+		if err != nil {
+			log.Fatalf("ERROR: No se pudo crear el puente WhatsApp-Discord: %v", err)
+		}
+		defer bridge.Close()
+		dcServices.Bridge = bridge
+		waClient.Events().Subscribe(bridge.HandleEvent)
+		if cfg.Discord.ThreadChannelID != "" {
+			log.Printf("DC: Puente activo (%d chats con canal propio, el resto en hilos)", len(cfg.Discord.BridgeChannels))
+		} else {
+			log.Printf("DC: Puente activo para %d chats", len(cfg.Discord.BridgeChannels))
+		}
+	}
+
+	if err := bot.RegisterDefaultCommands(dcBot, dcServices); err != nil {
+		log.Fatalf("ERROR: No se pudieron registrar los comandos de Discord: %v", err)
+	}
+
+	// Estado de las integraciones: presencia del bot y /health
+	health := bot.NewHealth(dcBot, dcServices)
+	if dcServices.Bridge != nil {
+		health.AddQueue("Puente", dcServices.Bridge.QueueDepth)
+	}
+	if err := health.Register(dcBot.Commands()); err != nil {
+		log.Fatalf("ERROR: No se pudieron registrar los comandos de Discord: %v", err)
+	}
+
+	log.Println("DC: Conectando al gateway de Discord...")
+	if err := dcBot.Open(); err != nil {
+		log.Fatalf("ERROR: No se pudo conectar a Discord: %v", err)
+	}
+
+	defer func() {
+		log.Println("DC: Desconectando bot de Discord...")
+		dcBot.Close()
+	}()
+
+	if cfg.Discord.AlertChannelID != "" {
+		notifier.AddChannel(bot.NewAlertChannel(dcBot, cfg.Discord.AlertChannelID))
+	}
+
+	// Triaje con reacciones sobre los mensajes del puente
+	if dcServices.Bridge != nil && len(cfg.Discord.ReactionActions) > 0 {
+		if dcServices.Tickets == nil || dcServices.Workflow == nil {
+			log.Println("DC: WARN: Jira no está configurado, el triaje por reacciones queda desactivado")
+		} else if _, err := bot.NewReactions(dcBot, dcServices, repo); err != nil {
+			log.Fatalf("ERROR: No se pudo crear el triaje por reacciones: %v", err)
+		} else {
+			log.Printf("DC: Triaje por reacciones activo (%d acciones)", len(cfg.Discord.ReactionActions))
+		}
+	}
+
+	// Triaje de mensajes detectados por la IA
+	var triage *bot.Triage
+	if cfg.Discord.TriageChannelID != "" {
+		triage, err = bot.NewTriage(dcBot, cfg.Discord.TriageChannelID, dcServices, repo)
+		if err != nil {
+			log.Fatalf("ERROR: No se pudo crear el triaje: %v", err)
+		}
+		waClient.Events().Subscribe(triage.HandleEvent)
+		health.AddQueue("Triaje", triage.Pending)
+		log.Printf("DC: Triaje activo en %s", cfg.Discord.TriageChannelID)
+	}
+
+	// Canales de Discord donde reportan clientes: se archivan y, si el triaje
+	// está activo, pasan por el mismo triaje que WhatsApp
+	if len(cfg.Discord.SourceChannels) > 0 {
+		archive := bot.NewMessageArchive(waClient.History(), repo)
+		health.AddQueue("Archivo", archive.Pending)
+		sinks := []bot.Ingestor{archive}
+		if triage != nil {
+			sinks = append(sinks, triage)
+		} else {
+			log.Println("DC: WARN: Triaje desactivado, los mensajes de DISCORD_SOURCE_CHANNELS solo se archivan")
+		}
+		if _, err := bot.NewDiscordSource(dcBot, sinks...); err != nil {
+			log.Fatalf("ERROR: No se pudieron monitorear los canales de Discord: %v", err)
+		}
+		log.Printf("DC: Monitoreando %d canales de Discord", len(cfg.Discord.SourceChannels))
+	}
+
+	// Webhook de Jira: avisa las novedades de los tickets en WhatsApp y Discord
+	if jiraClient != nil && cfg.Jira.WebhookSecret != "" {
+		updates := bot.NewTicketUpdates(dcBot, dcServices, repo, cfg.Jira.UpdateTemplates)
+		webhook, err := jiraClient.WebhookHandler(cfg.Jira.WebhookSecret, updates.Handle)
+		if err != nil {
+			log.Fatalf("ERROR: No se pudo crear el webhook de Jira: %v", err)
+		}
+
+		mux := http.NewServeMux()
+		mux.Handle(cfg.Jira.WebhookPath, webhook)
+		server := &http.Server{
+			Addr:              cfg.GetServerAddress(),
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go func() {
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("ERROR: Servidor HTTP: %v", err)
+			}
+		}()
+		defer func() {
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			server.Shutdown(shutdownCtx)
+		}()
+		log.Printf("JIRA: Webhook escuchando en %s%s", cfg.GetServerAddress(), cfg.Jira.WebhookPath)
+	}
+
+	// Conectar WhatsApp después de Discord, para que el puente y las alertas
+	// (por ejemplo, el código de vinculación) ya estén activos
 	log.Println("WA: Conectando a WhatsApp...")
 	if err := waClient.Connect(); err != nil {
 		log.Fatalf("ERROR: No se pudo conectar a WhatsApp: %v", err)
@@ -59,17 +320,7 @@ func main() {
 		waClient.Disconnect()
 	}()
 
-	// TODO: Inicializar otros servicios
-	/*
-		// 2. Discord Bot
-		log.Println("DC: Inicializando bot de Discord...")
-
-		// 3. Gemini AI
-		log.Println("AI: Inicializando Gemini AI...")
-
-		// 4. Jira Client
-		log.Println("JIRA: Inicializando cliente Jira...")
-	*/
+	go health.Run(ctx)
 
 	// Goroutine para mostrar status
 	go func() {
@@ -85,33 +336,18 @@ func main() {
 				waStatus := "DESCONECTADO"
 				if waClient.IsConnected() {
 					waStatus = "CONECTADO"
+				} else if waClient.State() == whatsapp.StateProxyError {
+					waStatus = fmt.Sprintf("ERROR DE PROXY (%s)", waClient.StateReason())
+				}
+				dcStatus := "DESCONECTADO"
+				if dcBot.IsReady() {
+					dcStatus = "CONECTADO"
 				}
-				log.Printf("STATUS: Lisa Bot funcionando - WhatsApp: %s", waStatus)
+				log.Printf("STATUS: Lisa Bot funcionando - WhatsApp: %s - Discord: %s", waStatus, dcStatus)
 			}
 		}
 	}()
 
-	// TODO: Aquí inicializaremos los servicios uno por uno
-	/*
-		// Inicializar servicios
-		log.Println("INIT: Inicializando servicios...")
-
-		// 1. Base de datos
-		log.Println("DB: Conectando a PostgreSQL...")
-
-		// 2. WhatsApp Client
-		log.Println("WA: Inicializando cliente WhatsApp...")
-
-		// 3. Discord Bot
-		log.Println("DC: Inicializando bot de Discord...")
-
-		// 4. Gemini AI
-		log.Println("AI: Inicializando Gemini AI...")
-
-		// 5. Jira Client
-		log.Println("JIRA: Inicializando cliente Jira...")
-	*/
-
 	log.Println("OK: Lisa Bot iniciado correctamente")
 	log.Println("INFO: Presiona Ctrl+C para detener el bot")
 
@@ -169,7 +405,7 @@ func printConfigStatus(cfg *config.Config) {
 
 	// Jira
 	if cfg.Jira.URL != "" && cfg.Jira.Token != "" {
-		log.Printf("  OK: Jira: Configurado (%s)", cfg.Jira.URL)
+		log.Printf("  OK: Jira: Configurado (%s, %s)", cfg.Jira.URL, cfg.Jira.Mode)
 	} else {
 		log.Println("  WARN: Jira: Configuracion incompleta (opcional)")
 	}
@@ -178,7 +414,7 @@ func printConfigStatus(cfg *config.Config) {
 	if cfg.Gemini.APIKey != "" {
 		log.Printf("  OK: Gemini AI: Configurado (modelo: %s)", cfg.Gemini.Model)
 	} else {
-		log.Println("  ERROR: Gemini AI: API Key faltante")
+		log.Println("  WARN: Gemini AI: API Key faltante (el triaje usa palabras clave)")
 	}
 
 	log.Println("")
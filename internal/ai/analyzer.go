@@ -1 +1,63 @@
 package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"Lisa/pkg/types"
+)
+
+// transcriptMaxLength largo máximo de la conversación que se envía a Gemini;
+// si es más larga se descartan los mensajes más antiguos
+const transcriptMaxLength = 20000
+
+// Summarizer resume conversaciones con Gemini para !resumen y /summary
+type Summarizer struct {
+	gemini *GeminiClient
+}
+
+// NewSummarizer crea el resumidor
+func NewSummarizer(gemini *GeminiClient) *Summarizer {
+	return &Summarizer{gemini: gemini}
+}
+
+// Summarize resume los mensajes, del más antiguo al más nuevo
+func (s *Summarizer) Summarize(ctx context.Context, messages []types.MessageInfo) (string, error) {
+	if len(messages) == 0 {
+		return "", fmt.Errorf("no hay mensajes para resumir")
+	}
+	summary, err := s.gemini.GenerateText(ctx, summarizeSystemPrompt, fmt.Sprintf(summarizePrompt, transcript(messages)))
+	if err != nil {
+		return "", err
+	}
+	if summary == "" {
+		return "", fmt.Errorf("gemini devolvió un resumen vacío")
+	}
+	return summary, nil
+}
+
+// transcript arma la conversación como "[fecha] nombre: texto", recortada a
+// transcriptMaxLength
+func transcript(messages []types.MessageInfo) string {
+	lines := make([]string, 0, len(messages))
+	size := 0
+	for i := len(messages) - 1; i >= 0; i-- {
+		msg := messages[i]
+		name := msg.PushName
+		if name == "" {
+			name = msg.Sender
+		}
+		line := fmt.Sprintf("[%s] %s: %s", msg.Timestamp.Format("2006-01-02 15:04"), name, msg.Text)
+		if size+len(line) > transcriptMaxLength && len(lines) > 0 {
+			break
+		}
+		size += len(line) + 1
+		lines = append(lines, line)
+	}
+
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+	return strings.Join(lines, "\n")
+}
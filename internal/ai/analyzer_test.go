@@ -0,0 +1,64 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"Lisa/pkg/types"
+)
+
+func TestSummarizerSummarize(t *testing.T) {
+	messages := []types.MessageInfo{
+		{PushName: "Ana", Text: "No puedo entrar al sistema", Timestamp: time.Date(2026, 3, 2, 9, 15, 0, 0, time.UTC)},
+		{Sender: "5491100000000", Text: "¿Qué error le aparece?", Timestamp: time.Date(2026, 3, 2, 9, 20, 0, 0, time.UTC)},
+	}
+
+	tests := []struct {
+		name     string
+		status   int
+		text     string
+		messages []types.MessageInfo
+		want     string
+		wantErr  bool
+	}{
+		{name: "resumen", status: http.StatusOK, text: "  - Ana no puede entrar al sistema.\n", messages: messages, want: "- Ana no puede entrar al sistema."},
+		{name: "respuesta vacía", status: http.StatusOK, text: " ", messages: messages, wantErr: true},
+		{name: "error de gemini", status: http.StatusTooManyRequests, messages: messages, wantErr: true},
+		{name: "sin mensajes", status: http.StatusOK, text: "nada", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSummarizer(fakeGemini(t, tt.status, tt.text))
+			got, err := s.Summarize(context.Background(), tt.messages)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Summarize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Summarize() = %q, se esperaba %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTranscript(t *testing.T) {
+	messages := []types.MessageInfo{
+		{PushName: "Ana", Text: "Hola", Timestamp: time.Date(2026, 3, 2, 9, 15, 0, 0, time.UTC)},
+		{Sender: "5491100000000", Text: "Buen día", Timestamp: time.Date(2026, 3, 2, 9, 16, 0, 0, time.UTC)},
+	}
+	want := "[2026-03-02 09:15] Ana: Hola\n[2026-03-02 09:16] 5491100000000: Buen día"
+	if got := transcript(messages); got != want {
+		t.Errorf("transcript() = %q, se esperaba %q", got, want)
+	}
+
+	long := []types.MessageInfo{
+		{PushName: "Ana", Text: strings.Repeat("a", transcriptMaxLength)},
+		{PushName: "Ana", Text: "último"},
+	}
+	if got := transcript(long); strings.Contains(got, "aaa") || !strings.HasSuffix(got, "Ana: último") {
+		t.Errorf("transcript() no descartó el mensaje más antiguo: %.60q", got)
+	}
+}
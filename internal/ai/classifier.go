@@ -1 +1,184 @@
 package ai
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"Lisa/pkg/types"
+)
+
+// Confianza de una clasificación por palabras clave según cuántas
+// coincidencias tuvo: una sola alcanza el mínimo del triaje
+const (
+	keywordBaseConfidence  = 0.5
+	keywordMatchConfidence = 0.15
+	keywordMaxConfidence   = 0.9
+)
+
+// summaryMaxLength largo máximo del resumen por palabras clave
+const summaryMaxLength = 80
+
+// Palabras clave (en minúsculas y sin tildes) de cada categoría
+var (
+	bugKeywords = []string{
+		"error", "falla", "fallo", "no funciona", "no anda", "no carga", "no abre",
+		"no responde", "no puedo", "no me deja", "se cae", "se cayo", "caido", "caida",
+		"se colgo", "colgado", "bloqueado", "pantalla en blanco", "excepcion", "bug",
+		"crash", "broken", "not working", "doesn't work", "lento", "timeout",
+	}
+	requestKeywords = []string{
+		"quisiera", "necesito", "necesitamos", "me gustaria", "nos gustaria", "solicito",
+		"solicitud", "podrian agregar", "pueden agregar", "agregar", "habilitar",
+		"dar de alta", "alta de", "crear un usuario", "cambiar", "modificar", "nueva funcion",
+		"please add", "feature request",
+	}
+	questionKeywords = []string{
+		"?", "como hago", "como puedo", "donde", "cuando", "cual es", "consulta",
+		"pregunta", "saben si", "how do",
+	}
+	urgentKeywords = []string{
+		"urgente", "urgencia", "caido", "caida", "produccion", "todos los usuarios",
+		"nadie puede", "critico", "asap",
+	}
+)
+
+// accentReplacer quita tildes y signos de apertura para comparar palabras clave
+var accentReplacer = strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u", "ñ", "n", "¿", "", "¡", "")
+
+// KeywordClassifier clasifica por palabras clave, sin IA. Se usa cuando no hay
+// GEMINI_API_KEY o Gemini falla, para que el triaje siga funcionando.
+type KeywordClassifier struct{}
+
+// Classify clasifica el mensaje. Ante empate gana bug, luego pedido y luego consulta.
+func (KeywordClassifier) Classify(ctx context.Context, msg types.MessageInfo) (*types.Classification, error) {
+	text := normalize(msg.Text)
+
+	category, score := types.CategoryOther, 0
+	for _, candidate := range []struct {
+		category types.IssueCategory
+		keywords []string
+	}{
+		{types.CategoryBug, bugKeywords},
+		{types.CategoryRequest, requestKeywords},
+		{types.CategoryQuestion, questionKeywords},
+	} {
+		if n := countMatches(text, candidate.keywords); n > score {
+			category, score = candidate.category, n
+		}
+	}
+
+	c := &types.Classification{
+		Category:   category,
+		Confidence: keywordBaseConfidence,
+		Summary:    summarize(msg.Text),
+	}
+	if score > 0 {
+		c.Confidence = keywordBaseConfidence + keywordMatchConfidence*float64(score)
+		if c.Confidence > keywordMaxConfidence {
+			c.Confidence = keywordMaxConfidence
+		}
+	}
+	switch category {
+	case types.CategoryBug:
+		c.IssueType = "Bug"
+	case types.CategoryRequest:
+		c.IssueType = "Task"
+	}
+	if c.Actionable() && countMatches(text, urgentKeywords) > 0 {
+		c.Priority = "High"
+	}
+	return c, nil
+}
+
+// Classifier clasifica con Gemini y, si no está configurado o falla, por
+// palabras clave
+type Classifier struct {
+	gemini   *GeminiClient
+	fallback KeywordClassifier
+}
+
+// NewClassifier crea el clasificador. gemini puede ser nil.
+func NewClassifier(gemini *GeminiClient) *Classifier {
+	return &Classifier{gemini: gemini}
+}
+
+// Classify clasifica el mensaje
+func (c *Classifier) Classify(ctx context.Context, msg types.MessageInfo) (*types.Classification, error) {
+	if c.gemini != nil {
+		result, err := c.classifyWithGemini(ctx, msg)
+		if err == nil {
+			return result, nil
+		}
+		log.Printf("AI: Gemini no pudo clasificar %s, se usan palabras clave: %v", msg.ID, err)
+	}
+	return c.fallback.Classify(ctx, msg)
+}
+
+// Ping verifica la conexión con Gemini. Sin Gemini no hay nada que verificar.
+func (c *Classifier) Ping(ctx context.Context) error {
+	if c.gemini == nil {
+		return nil
+	}
+	return c.gemini.Ping(ctx)
+}
+
+func (c *Classifier) classifyWithGemini(ctx context.Context, msg types.MessageInfo) (*types.Classification, error) {
+	sender := msg.PushName
+	if sender == "" {
+		sender = msg.Sender
+	}
+	chat := msg.GroupName
+	if chat == "" {
+		chat = msg.From
+	}
+	prompt := fmt.Sprintf(classifyPrompt, sender, chat, msg.Text)
+
+	var result types.Classification
+	if err := c.gemini.GenerateJSON(ctx, classifySystemPrompt, prompt, &result); err != nil {
+		return nil, err
+	}
+
+	switch result.Category {
+	case types.CategoryBug, types.CategoryRequest, types.CategoryQuestion, types.CategoryOther:
+	default:
+		return nil, fmt.Errorf("categoría desconocida %q", result.Category)
+	}
+	if result.Confidence < 0 || result.Confidence > 1 {
+		return nil, fmt.Errorf("confianza fuera de rango: %v", result.Confidence)
+	}
+	if strings.TrimSpace(result.Summary) == "" {
+		result.Summary = summarize(msg.Text)
+	}
+	return &result, nil
+}
+
+// countMatches cantidad de palabras clave presentes en el texto
+func countMatches(text string, keywords []string) int {
+	n := 0
+	for _, k := range keywords {
+		if strings.Contains(text, k) {
+			n++
+		}
+	}
+	return n
+}
+
+// normalize pasa a minúsculas y quita las tildes
+func normalize(text string) string {
+	return accentReplacer.Replace(strings.ToLower(text))
+}
+
+// summarize primera línea u oración del mensaje, recortada
+func summarize(text string) string {
+	text = strings.TrimSpace(text)
+	if i := strings.IndexAny(text, ".\n!?"); i > 0 {
+		text = text[:i]
+	}
+	text = strings.Join(strings.Fields(text), " ")
+	if r := []rune(text); len(r) > summaryMaxLength {
+		text = strings.TrimSpace(string(r[:summaryMaxLength-1])) + "…"
+	}
+	return text
+}
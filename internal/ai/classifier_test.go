@@ -0,0 +1,124 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"Lisa/internal/config"
+	"Lisa/pkg/types"
+)
+
+func TestKeywordClassifier(t *testing.T) {
+	tests := []struct {
+		text       string
+		category   types.IssueCategory
+		priority   string
+		actionable bool
+	}{
+		{"El sistema no funciona desde esta mañana", types.CategoryBug, "", true},
+		{"URGENTE: producción caída, nadie puede entrar", types.CategoryBug, "High", true},
+		{"Necesito que habiliten el módulo de reportes", types.CategoryRequest, "", true},
+		{"¿Cómo hago para exportar las facturas?", types.CategoryQuestion, "", false},
+		{"No puedo iniciar sesión, ¿qué hago?", types.CategoryBug, "", true},
+		{"Muchas gracias por la ayuda", types.CategoryOther, "", false},
+	}
+
+	for _, tt := range tests {
+		c, err := KeywordClassifier{}.Classify(context.Background(), types.MessageInfo{Text: tt.text})
+		if err != nil {
+			t.Fatalf("Classify(%q): %v", tt.text, err)
+		}
+		if c.Category != tt.category {
+			t.Errorf("Classify(%q).Category = %q, se esperaba %q", tt.text, c.Category, tt.category)
+		}
+		if c.Priority != tt.priority {
+			t.Errorf("Classify(%q).Priority = %q, se esperaba %q", tt.text, c.Priority, tt.priority)
+		}
+		// Una sola palabra clave alcanza la confianza mínima del triaje (0.6)
+		if got := c.Actionable() && c.Confidence >= 0.6; got != tt.actionable {
+			t.Errorf("Classify(%q) publicable = %v, se esperaba %v (confianza %.2f)", tt.text, got, tt.actionable, c.Confidence)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"No carga la app. Probé dos veces", "No carga la app"},
+		{"  varias\n líneas  ", "varias"},
+		{strings.Repeat("a", 100), strings.Repeat("a", 79) + "…"},
+	}
+	for _, tt := range tests {
+		if got := summarize(tt.in); got != tt.want {
+			t.Errorf("summarize(%q) = %q, se esperaba %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// fakeGemini responde generateContent con el texto indicado
+func fakeGemini(t *testing.T, status int, text string) *GeminiClient {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-goog-api-key") != "clave" {
+			t.Errorf("falta la clave en la petición")
+		}
+		if !strings.HasSuffix(r.URL.Path, "/models/gemini-test:generateContent") {
+			t.Errorf("ruta inesperada %s", r.URL.Path)
+		}
+		w.WriteHeader(status)
+		if status != http.StatusOK {
+			w.Write([]byte(`{"error": {"message": "cuota agotada"}}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"candidates": []interface{}{map[string]interface{}{
+				"content": map[string]interface{}{"parts": []interface{}{map[string]string{"text": text}}},
+			}},
+		})
+	}))
+	t.Cleanup(srv.Close)
+
+	g, err := NewGeminiClient(config.GeminiConfig{APIKey: "clave", Model: "gemini-test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.baseURL = srv.URL
+	return g
+}
+
+func TestClassifierUsesGemini(t *testing.T) {
+	g := fakeGemini(t, http.StatusOK, "```json\n"+`{"category": "request", "confidence": 0.8, "summary": "Alta de usuario", "suggestion": "Lo revisamos."}`+"\n```")
+	c, err := NewClassifier(g).Classify(context.Background(), types.MessageInfo{Text: "gracias"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Category != types.CategoryRequest || c.Summary != "Alta de usuario" || c.Suggestion != "Lo revisamos." {
+		t.Errorf("clasificación inesperada: %+v", c)
+	}
+}
+
+func TestClassifierFallsBackToKeywords(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		text   string
+	}{
+		{"error de la API", http.StatusTooManyRequests, ""},
+		{"categoría inválida", http.StatusOK, `{"category": "spam", "confidence": 0.9}`},
+		{"JSON inválido", http.StatusOK, `no es JSON`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := fakeGemini(t, tt.status, tt.text)
+			c, err := NewClassifier(g).Classify(context.Background(), types.MessageInfo{Text: "La app no funciona"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.Category != types.CategoryBug {
+				t.Errorf("Category = %q, se esperaba bug por palabras clave", c.Category)
+			}
+		})
+	}
+}
@@ -1 +1,189 @@
 package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"Lisa/internal/config"
+)
+
+const (
+	// geminiBaseURL API REST de Gemini
+	geminiBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+
+	// geminiTimeout tiempo máximo de cada petición si el contexto no tiene plazo
+	geminiTimeout = 30 * time.Second
+
+	// geminiMaxBody tamaño máximo de la respuesta que se lee
+	geminiMaxBody = 1 << 20
+)
+
+// GeminiClient cliente de la API generateContent de Gemini
+type GeminiClient struct {
+	baseURL string
+	apiKey  string
+	model   string
+	http    *http.Client
+}
+
+// NewGeminiClient crea el cliente con GEMINI_API_KEY y GEMINI_MODEL
+func NewGeminiClient(cfg config.GeminiConfig) (*GeminiClient, error) {
+	if cfg.APIKey == "" {
+		return nil, fmt.Errorf("falta GEMINI_API_KEY")
+	}
+	model := strings.TrimPrefix(cfg.Model, "models/")
+	if model == "" {
+		return nil, fmt.Errorf("falta GEMINI_MODEL")
+	}
+	return &GeminiClient{
+		baseURL: geminiBaseURL,
+		apiKey:  cfg.APIKey,
+		model:   model,
+		http:    &http.Client{Timeout: geminiTimeout},
+	}, nil
+}
+
+// Model devuelve el modelo configurado
+func (g *GeminiClient) Model() string {
+	return g.model
+}
+
+// Ping verifica la clave consultando el modelo
+func (g *GeminiClient) Ping(ctx context.Context) error {
+	return g.call(ctx, http.MethodGet, "", nil, nil)
+}
+
+type geminiPart struct {
+	Text string `json:"text"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiRequest struct {
+	SystemInstruction *geminiContent  `json:"systemInstruction,omitempty"`
+	Contents          []geminiContent `json:"contents"`
+	GenerationConfig  struct {
+		Temperature      float64 `json:"temperature"`
+		ResponseMimeType string  `json:"responseMimeType,omitempty"`
+	} `json:"generationConfig"`
+}
+
+type geminiResponse struct {
+	Candidates []struct {
+		Content      geminiContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
+	} `json:"candidates"`
+	PromptFeedback *struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback"`
+}
+
+// GenerateText pide una respuesta en texto libre
+func (g *GeminiClient) GenerateText(ctx context.Context, system, prompt string) (string, error) {
+	return g.generate(ctx, system, prompt, "")
+}
+
+// GenerateJSON pide una respuesta en JSON y la decodifica en out
+func (g *GeminiClient) GenerateJSON(ctx context.Context, system, prompt string, out interface{}) error {
+	text, err := g.generate(ctx, system, prompt, "application/json")
+	if err != nil {
+		return err
+	}
+	// Algunos modelos encierran el JSON en un bloque de código
+	text = strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(text, "```json"), "```"), "```")
+	if err := json.Unmarshal([]byte(text), out); err != nil {
+		return fmt.Errorf("respuesta inválida de gemini: %w", err)
+	}
+	return nil
+}
+
+// generate llama a generateContent y devuelve el texto de la primera respuesta
+func (g *GeminiClient) generate(ctx context.Context, system, prompt, mimeType string) (string, error) {
+	req := geminiRequest{
+		Contents: []geminiContent{{Role: "user", Parts: []geminiPart{{Text: prompt}}}},
+	}
+	if system != "" {
+		req.SystemInstruction = &geminiContent{Parts: []geminiPart{{Text: system}}}
+	}
+	req.GenerationConfig.Temperature = 0.2
+	req.GenerationConfig.ResponseMimeType = mimeType
+
+	var resp geminiResponse
+	if err := g.call(ctx, http.MethodPost, ":generateContent", req, &resp); err != nil {
+		return "", err
+	}
+	if resp.PromptFeedback != nil && resp.PromptFeedback.BlockReason != "" {
+		return "", fmt.Errorf("gemini bloqueó el mensaje: %s", resp.PromptFeedback.BlockReason)
+	}
+	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
+		return "", fmt.Errorf("gemini no devolvió respuesta")
+	}
+
+	var text strings.Builder
+	for _, part := range resp.Candidates[0].Content.Parts {
+		text.WriteString(part.Text)
+	}
+	return strings.TrimSpace(text.String()), nil
+}
+
+// call ejecuta una petición sobre el modelo (models/<modelo><action>)
+func (g *GeminiClient) call(ctx context.Context, method, action string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	endpoint := g.baseURL + "/models/" + url.PathEscape(g.model) + action
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("x-goog-api-key", g.apiKey)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := g.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("no se pudo conectar con gemini: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, geminiMaxBody))
+	if err != nil {
+		return fmt.Errorf("no se pudo leer la respuesta de gemini: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error.Message != "" {
+			return fmt.Errorf("gemini respondió %d: %s", resp.StatusCode, apiErr.Error.Message)
+		}
+		return fmt.Errorf("gemini respondió %d", resp.StatusCode)
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("respuesta inválida de gemini: %w", err)
+	}
+	return nil
+}
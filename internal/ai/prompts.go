@@ -1 +1,39 @@
 package ai
+
+// classifySystemPrompt instrucciones para clasificar los mensajes de los clientes
+const classifySystemPrompt = `Usted clasifica mensajes que los clientes envían al equipo de soporte por WhatsApp o Discord.
+Responda solo con un objeto JSON con estos campos:
+- "category": "bug" (algo no funciona), "request" (pide algo nuevo o un cambio), "question" (consulta) u "other" (saludos, agradecimientos, charla).
+- "confidence": número entre 0 y 1 con su seguridad.
+- "summary": resumen de una línea en español, apto como título de un ticket (máximo 80 caracteres).
+- "priority": "Highest", "High", "Medium" o "Low" según la urgencia y el impacto; vacío si no aplica.
+- "issue_type": "Bug" para category bug, "Task" para request; vacío en otro caso.
+- "suggestion": respuesta breve y cordial en español para el cliente, tratándolo de usted; vacío si no hace falta.
+No invente datos que no estén en el mensaje.`
+
+// classifyPrompt mensaje a clasificar. Se completa con el remitente, el chat y el texto.
+const classifyPrompt = "Remitente: %s\nChat: %s\n\nMensaje:\n%s"
+
+// summarizeSystemPrompt instrucciones para resumir una conversación de soporte
+const summarizeSystemPrompt = `Usted resume conversaciones entre clientes y el equipo de soporte, de WhatsApp o Discord.
+Escriba en español un resumen breve (máximo 10 líneas) con:
+- el problema o pedido principal y quién lo planteó,
+- lo que ya se respondió o se acordó,
+- lo que queda pendiente.
+Use texto plano: guiones para las listas y *asteriscos* solo para resaltar. No use títulos ni tablas.
+No invente datos que no estén en la conversación.`
+
+// summarizePrompt conversación a resumir. Se completa con la transcripción.
+const summarizePrompt = "Conversación:\n%s"
+
+// draftSystemPrompt instrucciones para redactar un ticket de Jira desde una conversación
+const draftSystemPrompt = `Usted redacta tickets de Jira a partir de conversaciones entre clientes y el equipo de soporte, de WhatsApp o Discord.
+Responda solo con un objeto JSON con estos campos:
+- "summary": título del ticket en español, de una línea (máximo 120 caracteres).
+- "description": descripción en español con el problema o pedido, los pasos o datos que dio el cliente y lo que ya se le respondió.
+- "issue_type": "Bug" si algo no funciona, "Task" si pide algo nuevo o un cambio, "Story" si es una mejora mayor.
+- "priority": "Highest", "High", "Medium" o "Low" según la urgencia y el impacto.
+No invente datos que no estén en la conversación.`
+
+// draftPrompt conversación de la que se redacta el ticket. Se completa con la transcripción.
+const draftPrompt = "Conversación:\n%s"
@@ -1 +1,55 @@
 package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"Lisa/pkg/types"
+)
+
+// draftSummaryMaxLength largo máximo del título del borrador
+const draftSummaryMaxLength = 120
+
+// validPriorities prioridades de Jira que se aceptan en el borrador
+var validPriorities = map[string]bool{"Highest": true, "High": true, "Medium": true, "Low": true, "Lowest": true}
+
+// TicketDrafter redacta borradores de tickets con Gemini para /ticket y el
+// menú contextual "Crear ticket de Jira"
+type TicketDrafter struct {
+	gemini *GeminiClient
+}
+
+// NewTicketDrafter crea el redactor de borradores
+func NewTicketDrafter(gemini *GeminiClient) *TicketDrafter {
+	return &TicketDrafter{gemini: gemini}
+}
+
+// DraftTicket redacta el borrador a partir de los mensajes, del más antiguo al más nuevo
+func (d *TicketDrafter) DraftTicket(ctx context.Context, messages []types.MessageInfo) (*types.TicketDraft, error) {
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("no hay mensajes para redactar el ticket")
+	}
+
+	var draft types.TicketDraft
+	if err := d.gemini.GenerateJSON(ctx, draftSystemPrompt, fmt.Sprintf(draftPrompt, transcript(messages)), &draft); err != nil {
+		return nil, err
+	}
+
+	draft.Summary = strings.Join(strings.Fields(draft.Summary), " ")
+	if draft.Summary == "" {
+		return nil, fmt.Errorf("gemini devolvió un borrador sin resumen")
+	}
+	if r := []rune(draft.Summary); len(r) > draftSummaryMaxLength {
+		draft.Summary = strings.TrimSpace(string(r[:draftSummaryMaxLength-1])) + "…"
+	}
+	draft.Description = strings.TrimSpace(draft.Description)
+	draft.IssueType = strings.TrimSpace(draft.IssueType)
+	if !validPriorities[draft.Priority] {
+		draft.Priority = ""
+	}
+
+	// El proyecto, el informante y el origen los completa quien crea el ticket
+	draft.ProjectKey, draft.Reporter, draft.SourceChat = "", "", ""
+	return &draft, nil
+}
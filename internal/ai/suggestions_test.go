@@ -0,0 +1,58 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"Lisa/pkg/types"
+)
+
+func TestDraftTicket(t *testing.T) {
+	messages := []types.MessageInfo{{PushName: "Ana", Text: "No puedo entrar al sistema desde ayer"}}
+
+	tests := []struct {
+		name     string
+		status   int
+		text     string
+		messages []types.MessageInfo
+		want     types.TicketDraft
+		wantErr  bool
+	}{
+		{
+			name:     "borrador",
+			status:   http.StatusOK,
+			text:     `{"summary": " No puede  ingresar al sistema ", "description": "La cliente no puede ingresar desde ayer.", "issue_type": "Bug", "priority": "High", "reporter": "gemini"}`,
+			messages: messages,
+			want:     types.TicketDraft{Summary: "No puede ingresar al sistema", Description: "La cliente no puede ingresar desde ayer.", IssueType: "Bug", Priority: "High"},
+		},
+		{
+			name:     "prioridad desconocida",
+			status:   http.StatusOK,
+			text:     `{"summary": "Alta de usuario", "issue_type": "Task", "priority": "Urgente"}`,
+			messages: messages,
+			want:     types.TicketDraft{Summary: "Alta de usuario", IssueType: "Task"},
+		},
+		{name: "sin resumen", status: http.StatusOK, text: `{"summary": "  ", "description": "algo"}`, messages: messages, wantErr: true},
+		{name: "respuesta inválida", status: http.StatusOK, text: "no es JSON", messages: messages, wantErr: true},
+		{name: "error de gemini", status: http.StatusTooManyRequests, messages: messages, wantErr: true},
+		{name: "sin mensajes", status: http.StatusOK, text: `{"summary": "x"}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewTicketDrafter(fakeGemini(t, tt.status, tt.text))
+			got, err := d.DraftTicket(context.Background(), tt.messages)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DraftTicket() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Summary != tt.want.Summary || got.Description != tt.want.Description ||
+				got.IssueType != tt.want.IssueType || got.Priority != tt.want.Priority || got.Reporter != "" {
+				t.Errorf("DraftTicket() = %+v, se esperaba %+v", *got, tt.want)
+			}
+		})
+	}
+}
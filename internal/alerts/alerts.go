@@ -0,0 +1,120 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Level gravedad de una alerta
+type Level string
+
+const (
+	LevelInfo     Level = "INFO"
+	LevelWarning  Level = "WARN"
+	LevelCritical Level = "CRITICAL"
+)
+
+// Alert aviso para los administradores de Lisa
+type Alert struct {
+	Level   Level
+	Title   string
+	Message string
+	Time    time.Time
+}
+
+// Text devuelve la alerta formateada como texto plano
+func (a Alert) Text() string {
+	return fmt.Sprintf("[%s] %s\n%s", a.Level, a.Title, a.Message)
+}
+
+// Channel destino al que se envían las alertas
+type Channel interface {
+	Name() string
+	Send(ctx context.Context, alert Alert) error
+}
+
+// Notifier envía cada alerta al log y a todos los canales configurados
+type Notifier struct {
+	mu       sync.RWMutex
+	channels []Channel
+}
+
+func NewNotifier(channels ...Channel) *Notifier {
+	return &Notifier{channels: channels}
+}
+
+// AddChannel agrega un canal de alertas (por ejemplo, cuando Discord ya está conectado)
+func (n *Notifier) AddChannel(ch Channel) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.channels = append(n.channels, ch)
+}
+
+// Notify registra la alerta y la envía a los canales. Un canal que falla no
+// impide el envío a los demás.
+func (n *Notifier) Notify(ctx context.Context, alert Alert) {
+	if alert.Time.IsZero() {
+		alert.Time = time.Now()
+	}
+	log.Printf("ALERTA [%s]: %s - %s", alert.Level, alert.Title, alert.Message)
+
+	n.mu.RLock()
+	channels := append([]Channel(nil), n.channels...)
+	n.mu.RUnlock()
+
+	for _, ch := range channels {
+		sendCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		if err := ch.Send(sendCtx, alert); err != nil {
+			log.Printf("ALERTA: No se pudo enviar por %s: %v", ch.Name(), err)
+		}
+		cancel()
+	}
+}
+
+// WebhookChannel envía alertas por HTTP POST en JSON. El cuerpo incluye "text"
+// y "content", por lo que sirve tanto para webhooks de Slack como de Discord.
+type WebhookChannel struct {
+	URL        string
+	HTTPClient *http.Client
+}
+
+func NewWebhookChannel(url string) *WebhookChannel {
+	return &WebhookChannel{URL: url, HTTPClient: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (w *WebhookChannel) Name() string {
+	return "webhook"
+}
+
+func (w *WebhookChannel) Send(ctx context.Context, alert Alert) error {
+	body, err := json.Marshal(map[string]string{
+		"text":    alert.Text(),
+		"content": alert.Text(),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook respondió %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+
+	"Lisa/internal/alerts"
+)
+
+var alertColors = map[alerts.Level]int{
+	alerts.LevelInfo:     0x2ecc71,
+	alerts.LevelWarning:  0xf1c40f,
+	alerts.LevelCritical: 0xe74c3c,
+}
+
+// AlertChannel publica las alertas de Lisa en un canal de Discord
+type AlertChannel struct {
+	bot       *Bot
+	channelID string
+}
+
+func NewAlertChannel(bot *Bot, channelID string) *AlertChannel {
+	return &AlertChannel{bot: bot, channelID: channelID}
+}
+
+func (a *AlertChannel) Name() string {
+	return "discord"
+}
+
+func (a *AlertChannel) Send(ctx context.Context, alert alerts.Alert) error {
+	if !a.bot.IsReady() {
+		return fmt.Errorf("el bot de Discord no está conectado")
+	}
+
+	_, err := a.bot.session.ChannelMessageSendEmbed(a.channelID, &discordgo.MessageEmbed{
+		Title:       alert.Title,
+		Description: alert.Message,
+		Color:       alertColors[alert.Level],
+		Timestamp:   alert.Time.Format("2006-01-02T15:04:05Z07:00"),
+		Footer:      &discordgo.MessageEmbedFooter{Text: string(alert.Level)},
+	}, discordgo.WithContext(ctx))
+	return err
+}
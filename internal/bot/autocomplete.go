@@ -0,0 +1,247 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"Lisa/pkg/types"
+)
+
+const (
+	// autocompleteDeadline margen para responder antes de los 3 segundos de Discord
+	autocompleteDeadline = 2500 * time.Millisecond
+
+	// autocompleteTTL tiempo que se reutilizan las respuestas de Jira
+	autocompleteTTL = time.Minute
+
+	// autocompleteRefresh espera máxima de una actualización en segundo plano
+	autocompleteRefresh = 10 * time.Second
+
+	// maxChoices sugerencias que acepta Discord por respuesta
+	maxChoices = 25
+
+	// recentTicketsLimit tickets recientes que se ofrecen como sugerencia
+	recentTicketsLimit = 50
+)
+
+// TicketDirectory consultas de Jira que alimentan el autocompletado
+type TicketDirectory interface {
+	Projects(ctx context.Context) ([]types.ProjectInfo, error)
+	RecentTickets(ctx context.Context, limit int) ([]types.TicketInfo, error)
+	Transitions(ctx context.Context, key string) ([]types.Transition, error)
+	AssignableUsers(ctx context.Context, key, query string) ([]types.JiraUser, error)
+}
+
+// cacheEntry respuesta guardada y su vencimiento
+type cacheEntry struct {
+	value   interface{}
+	expires time.Time
+	loading bool
+}
+
+// lookupCache guarda por poco tiempo las respuestas de Jira. Una entrada
+// vencida se sigue sirviendo mientras se actualiza en segundo plano, así el
+// autocompletado solo espera a Jira la primera vez.
+type lookupCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+func newLookupCache(ttl time.Duration) *lookupCache {
+	return &lookupCache{ttl: ttl, entries: make(map[string]*cacheEntry)}
+}
+
+// get devuelve el valor de key, cargándolo con load si no está
+func (c *lookupCache) get(ctx context.Context, key string, load func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if ok && time.Now().Before(entry.expires) {
+		c.mu.Unlock()
+		return entry.value, nil
+	}
+	if ok {
+		if !entry.loading {
+			entry.loading = true
+			go c.refresh(key, load)
+		}
+		c.mu.Unlock()
+		return entry.value, nil
+	}
+	c.mu.Unlock()
+
+	value, err := load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.set(key, value)
+	return value, nil
+}
+
+func (c *lookupCache) refresh(key string, load func(ctx context.Context) (interface{}, error)) {
+	ctx, cancel := context.WithTimeout(context.Background(), autocompleteRefresh)
+	defer cancel()
+
+	value, err := load(ctx)
+	if err != nil {
+		log.Printf("DC: No se pudo actualizar %s: %v", key, err)
+		c.mu.Lock()
+		if entry, ok := c.entries[key]; ok {
+			entry.loading = false
+		}
+		c.mu.Unlock()
+		return
+	}
+	c.set(key, value)
+}
+
+func (c *lookupCache) set(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for k, e := range c.entries {
+		if !e.loading && now.Sub(e.expires) > c.ttl {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = &cacheEntry{value: value, expires: now.Add(c.ttl)}
+}
+
+// autocompleter sugiere proyectos, tickets, transiciones y usuarios de Jira
+type autocompleter struct {
+	directory TicketDirectory
+	cache     *lookupCache
+}
+
+func newAutocompleter(directory TicketDirectory) *autocompleter {
+	return &autocompleter{directory: directory, cache: newLookupCache(autocompleteTTL)}
+}
+
+// complete atiende las opciones "proyecto", "issue", "transicion" y "usuario"
+// de cualquier comando
+func (a *autocompleter) complete(ic *InteractionContext, focused *discordgo.ApplicationCommandInteractionDataOption) ([]*discordgo.ApplicationCommandOptionChoice, error) {
+	if a == nil || a.directory == nil {
+		return nil, nil
+	}
+	query := strings.ToLower(strings.TrimSpace(focused.StringValue()))
+
+	switch focused.Name {
+	case "proyecto":
+		return a.projects(ic.Ctx, query)
+	case "issue":
+		return a.tickets(ic.Ctx, query)
+	case "transicion":
+		key, _ := ic.Options.String("issue")
+		return a.transitions(ic.Ctx, strings.ToUpper(key), query)
+	case "usuario":
+		key, _ := ic.Options.String("issue")
+		return a.users(ic.Ctx, strings.ToUpper(key), query)
+	}
+	return nil, fmt.Errorf("opción sin autocompletado: %s", focused.Name)
+}
+
+func (a *autocompleter) projects(ctx context.Context, query string) ([]*discordgo.ApplicationCommandOptionChoice, error) {
+	value, err := a.cache.get(ctx, "projects", func(ctx context.Context) (interface{}, error) {
+		return a.directory.Projects(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for _, p := range value.([]types.ProjectInfo) {
+		if matches(query, p.Key, p.Name) {
+			choices = append(choices, choice(fmt.Sprintf("%s · %s", p.Key, p.Name), p.Key))
+		}
+	}
+	return choices, nil
+}
+
+func (a *autocompleter) tickets(ctx context.Context, query string) ([]*discordgo.ApplicationCommandOptionChoice, error) {
+	value, err := a.cache.get(ctx, "tickets", func(ctx context.Context) (interface{}, error) {
+		return a.directory.RecentTickets(ctx, recentTicketsLimit)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for _, t := range value.([]types.TicketInfo) {
+		if matches(query, t.Key, t.Summary) {
+			choices = append(choices, choice(fmt.Sprintf("%s · %s", t.Key, t.Summary), t.Key))
+		}
+	}
+	// Una clave completa que no está entre los recientes se ofrece igual
+	if key := strings.ToUpper(query); len(choices) == 0 && issueKeyPattern.MatchString(key) {
+		choices = append(choices, choice(key, key))
+	}
+	return choices, nil
+}
+
+func (a *autocompleter) transitions(ctx context.Context, key, query string) ([]*discordgo.ApplicationCommandOptionChoice, error) {
+	if !issueKeyPattern.MatchString(key) {
+		return nil, nil
+	}
+	value, err := a.cache.get(ctx, "transitions:"+key, func(ctx context.Context) (interface{}, error) {
+		return a.directory.Transitions(ctx, key)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for _, t := range value.([]types.Transition) {
+		if matches(query, t.Name, t.ToStatus) {
+			choices = append(choices, choice(fmt.Sprintf("%s → %s", t.Name, t.ToStatus), t.ID))
+		}
+	}
+	return choices, nil
+}
+
+func (a *autocompleter) users(ctx context.Context, key, query string) ([]*discordgo.ApplicationCommandOptionChoice, error) {
+	if !issueKeyPattern.MatchString(key) {
+		return nil, nil
+	}
+	// Jira filtra por el texto, así que cada búsqueda se guarda por separado
+	value, err := a.cache.get(ctx, "users:"+key+":"+query, func(ctx context.Context) (interface{}, error) {
+		return a.directory.AssignableUsers(ctx, key, query)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for _, u := range value.([]types.JiraUser) {
+		name := u.DisplayName
+		if u.Email != "" {
+			name += " · " + u.Email
+		}
+		choices = append(choices, choice(name, u.AccountID))
+	}
+	return choices, nil
+}
+
+// matches indica si alguno de los textos contiene la búsqueda
+func matches(query string, texts ...string) bool {
+	if query == "" {
+		return true
+	}
+	for _, t := range texts {
+		if strings.Contains(strings.ToLower(t), query) {
+			return true
+		}
+	}
+	return false
+}
+
+func choice(name, value string) *discordgo.ApplicationCommandOptionChoice {
+	return &discordgo.ApplicationCommandOptionChoice{Name: truncate(name, 100), Value: value}
+}
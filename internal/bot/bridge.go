@@ -0,0 +1,446 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	waTypes "go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+
+	"Lisa/internal/database"
+	"Lisa/internal/whatsapp"
+	"Lisa/pkg/types"
+)
+
+const (
+	// bridgeWebhookName nombre del webhook que Lisa crea en cada canal del puente
+	bridgeWebhookName = "Lisa Bridge"
+
+	// bridgeQueueSize mensajes pendientes por chat o canal antes de bloquear
+	bridgeQueueSize = 256
+
+	// discordMessageLimit largo máximo del contenido de un mensaje
+	discordMessageLimit = 2000
+
+	// discordUploadLimit tamaño máximo de archivo en servidores sin mejoras
+	discordUploadLimit = 10 << 20
+)
+
+// Discord no acepta nombres de webhook que contengan estas palabras
+var reservedUsernames = regexp.MustCompile(`(?i)discord|clyde`)
+
+// BridgeWhatsApp lo que el puente necesita del cliente de WhatsApp
+type BridgeWhatsApp interface {
+	DownloadMedia(ctx context.Context, msg *events.Message) (*types.MediaMessage, error)
+	AvatarURL(jid waTypes.JID) string
+	GetJID() string
+	SendText(ctx context.Context, chat, text string, quote *whatsapp.Quote) (string, error)
+	SendMedia(ctx context.Context, chat string, media *types.MediaMessage, quote *whatsapp.Quote) (string, error)
+}
+
+// BridgeStore guarda la relación entre los mensajes de ambos lados y los
+// hilos asignados a cada chat
+type BridgeStore interface {
+	ThreadStore
+	SaveBridgeMessage(ctx context.Context, msg *database.BridgeMessage) error
+	BridgeMessageByWhatsApp(ctx context.Context, chat, messageID string) (*database.BridgeMessage, error)
+	BridgeMessageByDiscord(ctx context.Context, discordMessageID string) (*database.BridgeMessage, error)
+}
+
+// Bridge replica los mensajes de chats de WhatsApp en canales de Discord usando
+// webhooks, para que cada mensaje aparezca con el nombre y la foto de quien lo
+// envió, y envía a WhatsApp lo que los agentes escriben en esos canales. Cada
+// chat y cada canal tiene su propia cola, así que el orden se mantiene sin que
+// un chat lento demore a los demás.
+type Bridge struct {
+	bot         *Bot
+	wa          BridgeWhatsApp
+	store       BridgeStore
+	channels    map[string]string // chat -> canal
+	chats       map[string]string // canal -> chat
+	replyPrefix string
+
+	// Hilos por chat para los chats sin canal propio
+	threadChannel string
+	archiveAfter  time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu       sync.Mutex
+	queues   map[string]chan func()
+	webhooks map[string]*discordgo.Webhook
+}
+
+// NewBridge crea el puente con el mapeo DISCORD_BRIDGE_CHANNELS. Las claves
+// pueden ser JIDs completos o números de teléfono. Con
+// DISCORD_BRIDGE_THREAD_CHANNEL_ID los demás chats se publican en un hilo
+// propio dentro de ese canal.
+func NewBridge(b *Bot, wa BridgeWhatsApp, store BridgeStore) (*Bridge, error) {
+	if b.cfg.ThreadChannelID != "" && store == nil {
+		return nil, fmt.Errorf("los hilos por chat necesitan la base de datos")
+	}
+
+	channels := make(map[string]string, len(b.cfg.BridgeChannels))
+	chats := make(map[string]string, len(b.cfg.BridgeChannels))
+	for chat, channelID := range b.cfg.BridgeChannels {
+		jid, err := whatsapp.ParseJID(chat)
+		if err != nil {
+			return nil, fmt.Errorf("chat del puente inválido %q: %w", chat, err)
+		}
+		if channelID == "" {
+			return nil, fmt.Errorf("falta el canal de Discord para %s", jid)
+		}
+		if other, dup := chats[channelID]; dup {
+			return nil, fmt.Errorf("el canal %s está asignado a %s y a %s", channelID, other, jid)
+		}
+		channels[jid.String()] = channelID
+		chats[channelID] = jid.String()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	br := &Bridge{
+		bot:         b,
+		wa:          wa,
+		store:       store,
+		channels:    channels,
+		chats:       chats,
+		replyPrefix: b.cfg.ReplyPrefix,
+
+		threadChannel: b.cfg.ThreadChannelID,
+		archiveAfter:  b.cfg.ThreadArchiveAfter,
+
+		ctx:      ctx,
+		cancel:   cancel,
+		queues:   make(map[string]chan func()),
+		webhooks: make(map[string]*discordgo.Webhook),
+	}
+	b.session.AddHandler(br.onDiscordMessage)
+
+	if br.threadChannel != "" {
+		b.session.AddHandler(br.onThreadUpdate)
+		if br.archiveAfter > 0 {
+			br.wg.Add(1)
+			go br.archiveIdleThreads()
+		}
+	}
+	return br, nil
+}
+
+// ChannelFor devuelve el canal de Discord de un chat de WhatsApp
+func (br *Bridge) ChannelFor(chat string) (string, bool) {
+	channelID, ok := br.channels[chat]
+	return channelID, ok
+}
+
+// HandleEvent recibe los eventos del cliente de WhatsApp. Se suscribe con
+// Events().Subscribe y encola los mensajes de los chats mapeados.
+func (br *Bridge) HandleEvent(evt whatsapp.Event) {
+	if evt.Type != whatsapp.EventMessage || evt.Message == nil {
+		return
+	}
+	if !br.routes(evt.Chat.String()) {
+		return
+	}
+
+	br.enqueue("wa:"+evt.Chat.String(), func() {
+		if err := br.forward(evt); err != nil {
+			log.Printf("DC: Puente: no se pudo reenviar %s de %s: %v", evt.Message.Info.ID, evt.Chat, err)
+		}
+	})
+}
+
+// Close detiene las colas y espera a que terminen los envíos en curso
+func (br *Bridge) Close() {
+	br.cancel()
+	br.wg.Wait()
+}
+
+// enqueue agrega un envío a la cola de su chat o canal. Las colas se procesan
+// en orden, una por clave.
+func (br *Bridge) enqueue(key string, job func()) {
+	br.mu.Lock()
+	q, ok := br.queues[key]
+	if !ok {
+		q = make(chan func(), bridgeQueueSize)
+		br.queues[key] = q
+		br.wg.Add(1)
+		go br.worker(q)
+	}
+	br.mu.Unlock()
+
+	select {
+	case q <- job:
+	case <-br.ctx.Done():
+	}
+}
+
+// QueueDepth cantidad de mensajes esperando en las colas del puente
+func (br *Bridge) QueueDepth() int {
+	br.mu.Lock()
+	defer br.mu.Unlock()
+	n := 0
+	for _, q := range br.queues {
+		n += len(q)
+	}
+	return n
+}
+
+func (br *Bridge) worker(q chan func()) {
+	defer br.wg.Done()
+	for {
+		select {
+		case job := <-q:
+			job()
+		case <-br.ctx.Done():
+			return
+		}
+	}
+}
+
+// forward publica un mensaje de WhatsApp en el canal o hilo del chat
+func (br *Bridge) forward(evt whatsapp.Event) error {
+	chat := evt.Chat.String()
+	msg := evt.Message
+	ctx := br.ctx
+
+	text := msg.Caption
+	if msg.Type == types.MessageTypeText {
+		text = msg.Info.Text
+	}
+	content := text
+
+	// Descargar el archivo si el mensaje lo tiene
+	var attachment *types.MediaMessage
+	if msg.Type != types.MessageTypeText && evt.Raw != nil {
+		media, err := br.wa.DownloadMedia(ctx, evt.Raw)
+		var tooLarge *whatsapp.MediaTooLargeError
+		switch {
+		case errors.As(err, &tooLarge):
+			content = joinLines(content, fmt.Sprintf("_[%s no reenviado: %v]_", msg.Type, err))
+		case err != nil:
+			log.Printf("DC: Puente: %v", err)
+			content = joinLines(content, fmt.Sprintf("_[%s no disponible]_", msg.Type))
+		case len(media.Data) > discordUploadLimit:
+			content = joinLines(content, fmt.Sprintf("_[%s de %d MB, supera el límite de Discord]_", msg.Type, len(media.Data)>>20))
+		case len(media.Data) > 0:
+			attachment = media
+		default:
+			content = joinLines(content, fmt.Sprintf("_[%s]_", msg.Type))
+		}
+	}
+
+	if quote := br.replyContext(ctx, msg.Info); quote != "" {
+		content = quote + "\n" + content
+	}
+	if content == "" && attachment == nil {
+		return nil
+	}
+
+	dest, err := br.destinationFor(ctx, evt)
+	if err != nil {
+		return err
+	}
+
+	params := &discordgo.WebhookParams{
+		Username:        webhookUsername(msg.Info.PushName, evt.Participant),
+		AvatarURL:       br.wa.AvatarURL(evt.Participant),
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	}
+
+	first, err := br.execute(dest, params, content, attachment)
+	if err != nil && isUnknownWebhook(err) {
+		// El webhook se borró en Discord: execute ya lo descartó y se crea otro
+		first, err = br.execute(dest, params, content, attachment)
+	}
+	if err != nil && dest.threadID != "" && isUnknownChannel(err) {
+		// El hilo se borró en Discord: se descarta y se publica en uno nuevo
+		if err := br.store.DeleteBridgeThread(ctx, chat); err != nil {
+			return err
+		}
+		if dest, err = br.destinationFor(ctx, evt); err != nil {
+			return err
+		}
+		first, err = br.execute(dest, params, content, attachment)
+	}
+	if err != nil {
+		return err
+	}
+
+	if br.store == nil {
+		return nil
+	}
+	if dest.threadID != "" {
+		if err := br.store.TouchBridgeThread(ctx, chat, false); err != nil {
+			log.Printf("DC: Puente: %v", err)
+		}
+	}
+	err = br.store.SaveBridgeMessage(ctx, &database.BridgeMessage{
+		WAChat:           chat,
+		WAMessageID:      msg.Info.ID,
+		WASender:         evt.Participant.ToNonAD().String(),
+		WAText:           truncate(text, 1000),
+		DiscordChannelID: dest.target(),
+		DiscordMessageID: first.ID,
+		Direction:        database.DirectionToDiscord,
+	})
+	if err != nil {
+		log.Printf("DC: Puente: %v", err)
+	}
+	return nil
+}
+
+// execute publica el contenido con el webhook del canal, dividido en partes
+// si es largo. Devuelve el primer mensaje, que es el que se relaciona con el
+// de WhatsApp; el archivo va en el último para que quede debajo del texto.
+func (br *Bridge) execute(dest destination, params *discordgo.WebhookParams, content string, attachment *types.MediaMessage) (*discordgo.Message, error) {
+	hook, err := br.webhook(dest.channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	var first *discordgo.Message
+	chunks := splitMessage(content, discordMessageLimit)
+	for i, chunk := range chunks {
+		params.Content = chunk
+		params.Files = nil
+		if i == len(chunks)-1 && attachment != nil {
+			params.Files = []*discordgo.File{{
+				Name:        attachment.Filename,
+				ContentType: attachment.MimeType,
+				Reader:      bytes.NewReader(attachment.Data),
+			}}
+		}
+
+		var sent *discordgo.Message
+		if dest.threadID != "" {
+			sent, err = br.bot.session.WebhookThreadExecute(hook.ID, hook.Token, true, dest.threadID, params)
+		} else {
+			sent, err = br.bot.session.WebhookExecute(hook.ID, hook.Token, true, params)
+		}
+		if err != nil {
+			// Si solo falta el hilo, el webhook del canal sigue sirviendo
+			if isUnknownWebhook(err) || dest.threadID == "" || !isUnknownChannel(err) {
+				br.forgetWebhook(dest.channelID)
+			}
+			return nil, fmt.Errorf("no se pudo publicar en %s: %w", dest.target(), err)
+		}
+		if first == nil {
+			first = sent
+		}
+	}
+	return first, nil
+}
+
+// replyContext arma la cita del mensaje respondido, con enlace al mensaje de
+// Discord si también pasó por el puente
+func (br *Bridge) replyContext(ctx context.Context, info types.MessageInfo) string {
+	if info.QuotedID == "" {
+		return ""
+	}
+
+	quoted := truncate(strings.ReplaceAll(info.QuotedText, "\n", " "), 100)
+	if quoted == "" {
+		quoted = "mensaje"
+	}
+	line := "> ↪ " + quoted
+
+	if br.store == nil {
+		return line
+	}
+	link, err := br.store.BridgeMessageByWhatsApp(ctx, info.From, info.QuotedID)
+	if err != nil {
+		log.Printf("DC: Puente: %v", err)
+		return line
+	}
+	if link != nil {
+		line += fmt.Sprintf(" ([ir al mensaje](https://discord.com/channels/%s/%s/%s))",
+			br.bot.GuildID(), link.DiscordChannelID, link.DiscordMessageID)
+	}
+	return line
+}
+
+// webhook devuelve el webhook del puente en el canal, reutilizando el que ya
+// exista o creándolo (requiere el permiso "Gestionar webhooks")
+func (br *Bridge) webhook(channelID string) (*discordgo.Webhook, error) {
+	br.mu.Lock()
+	hook, ok := br.webhooks[channelID]
+	br.mu.Unlock()
+	if ok {
+		return hook, nil
+	}
+
+	hooks, err := br.bot.session.ChannelWebhooks(channelID)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudieron listar los webhooks del canal %s: %w", channelID, err)
+	}
+	for _, h := range hooks {
+		if h.Name == bridgeWebhookName && h.Token != "" {
+			hook = h
+			break
+		}
+	}
+	if hook == nil {
+		hook, err = br.bot.session.WebhookCreate(channelID, bridgeWebhookName, "")
+		if err != nil {
+			return nil, fmt.Errorf("no se pudo crear el webhook en el canal %s: %w", channelID, err)
+		}
+		log.Printf("DC: Puente: webhook creado en el canal %s", channelID)
+	}
+
+	br.mu.Lock()
+	br.webhooks[channelID] = hook
+	br.mu.Unlock()
+	return hook, nil
+}
+
+// forgetWebhook descarta el webhook guardado por si fue eliminado desde Discord
+func (br *Bridge) forgetWebhook(channelID string) {
+	br.mu.Lock()
+	delete(br.webhooks, channelID)
+	br.mu.Unlock()
+}
+
+// webhookUsername nombre visible del remitente, dentro de las reglas de Discord
+func webhookUsername(pushName string, sender waTypes.JID) string {
+	name := strings.TrimSpace(reservedUsernames.ReplaceAllString(pushName, ""))
+	if name == "" {
+		name = "+" + sender.User
+	}
+	return truncate(name+" (WhatsApp)", 80)
+}
+
+// splitMessage divide el texto en partes que Discord acepte, cortando en
+// saltos de línea cuando es posible
+func splitMessage(text string, limit int) []string {
+	var chunks []string
+	runes := []rune(text)
+	for len(runes) > limit {
+		cut := limit
+		for i := limit; i > limit/2; i-- {
+			if runes[i] == '\n' {
+				cut = i
+				break
+			}
+		}
+		chunks = append(chunks, string(runes[:cut]))
+		runes = []rune(strings.TrimLeft(string(runes[cut:]), "\n"))
+	}
+	return append(chunks, string(runes))
+}
+
+func joinLines(a, b string) string {
+	if a == "" {
+		return b
+	}
+	return a + "\n" + b
+}
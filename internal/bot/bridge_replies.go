@@ -0,0 +1,256 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"Lisa/internal/database"
+	"Lisa/internal/whatsapp"
+	"Lisa/pkg/types"
+)
+
+const (
+	// attachmentLimit tamaño máximo de un adjunto que se reenvía a WhatsApp
+	attachmentLimit = 64 << 20
+
+	// noticeTTL tiempo que se muestra el aviso de error antes de borrarse
+	noticeTTL = 30 * time.Second
+)
+
+// onDiscordMessage encola los mensajes escritos por agentes en los canales del puente
+func (br *Bridge) onDiscordMessage(s *discordgo.Session, m *discordgo.MessageCreate) {
+	// Ignorar bots y webhooks, incluidos los mensajes que el propio puente publica
+	if m.Author == nil || m.Author.Bot || m.WebhookID != "" {
+		return
+	}
+	if m.Type != discordgo.MessageTypeDefault && m.Type != discordgo.MessageTypeReply {
+		return
+	}
+	chat, ok := br.chatFor(br.ctx, m.ChannelID)
+	if !ok {
+		return
+	}
+
+	br.enqueue("dc:"+m.ChannelID, func() {
+		if err := br.reply(chat, m.Message); err != nil {
+			log.Printf("DC: Puente: no se pudo enviar a %s el mensaje %s: %v", chat, m.ID, err)
+			br.notice(m.Message, fmt.Sprintf("No se pudo enviar a WhatsApp: %v", err))
+		}
+	})
+}
+
+// ChatFor devuelve el chat de WhatsApp de un canal o hilo del puente
+func (br *Bridge) ChatFor(ctx context.Context, channelID string) (string, bool) {
+	if br == nil {
+		return "", false
+	}
+	return br.chatFor(ctx, channelID)
+}
+
+// MessageFor devuelve el mensaje de WhatsApp que corresponde a un mensaje de
+// Discord, o nil si no pasó por el puente
+func (br *Bridge) MessageFor(ctx context.Context, discordMessageID string) (*database.BridgeMessage, error) {
+	if br == nil || br.store == nil {
+		return nil, nil
+	}
+	return br.store.BridgeMessageByDiscord(ctx, discordMessageID)
+}
+
+func (br *Bridge) chatFor(ctx context.Context, channelID string) (string, bool) {
+	if chat, ok := br.chats[channelID]; ok {
+		return chat, true
+	}
+	if br.threadChannel == "" {
+		return "", false
+	}
+
+	thread, err := br.store.BridgeThreadByID(ctx, channelID)
+	if err != nil {
+		log.Printf("DC: Puente: %v", err)
+		return "", false
+	}
+	if thread == nil {
+		return "", false
+	}
+	return thread.WAChat, true
+}
+
+// reply envía a WhatsApp el texto y los adjuntos de un mensaje de Discord. Si
+// el mensaje responde a otro que pasó por el puente, se envía como respuesta
+// citada en WhatsApp.
+func (br *Bridge) reply(chat string, m *discordgo.Message) error {
+	ctx := br.ctx
+
+	quote, err := br.quoteFor(ctx, m)
+	if err != nil {
+		return err
+	}
+
+	text := strings.TrimSpace(m.ContentWithMentionsReplaced())
+	if text != "" && br.replyPrefix != "" {
+		text = strings.ReplaceAll(br.replyPrefix, "{agente}", agentName(m)) + " " + text
+	}
+
+	var sent []string
+	if text != "" {
+		id, err := br.wa.SendText(ctx, chat, text, quote)
+		if err != nil {
+			return err
+		}
+		sent = append(sent, id)
+		quote = nil
+	}
+
+	var failed []string
+	for _, att := range m.Attachments {
+		id, err := br.sendAttachment(ctx, chat, att, quote)
+		if err != nil {
+			log.Printf("DC: Puente: adjunto %s: %v", att.Filename, err)
+			failed = append(failed, fmt.Sprintf("%s (%v)", att.Filename, err))
+			continue
+		}
+		sent = append(sent, id)
+		quote = nil
+	}
+
+	if br.store != nil {
+		if br.threadChannel != "" && m.ChannelID != br.channels[chat] {
+			if err := br.store.TouchBridgeThread(ctx, chat, false); err != nil {
+				log.Printf("DC: Puente: %v", err)
+			}
+		}
+		for _, id := range sent {
+			err := br.store.SaveBridgeMessage(ctx, &database.BridgeMessage{
+				WAChat:           chat,
+				WAMessageID:      id,
+				WAText:           truncate(text, 1000),
+				DiscordChannelID: m.ChannelID,
+				DiscordMessageID: m.ID,
+				Direction:        database.DirectionToWhatsApp,
+			})
+			if err != nil {
+				log.Printf("DC: Puente: %v", err)
+			}
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("adjuntos no enviados: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// quoteFor busca el mensaje de WhatsApp al que corresponde la respuesta de Discord
+func (br *Bridge) quoteFor(ctx context.Context, m *discordgo.Message) (*whatsapp.Quote, error) {
+	if m.MessageReference == nil || m.MessageReference.MessageID == "" || br.store == nil {
+		return nil, nil
+	}
+
+	link, err := br.store.BridgeMessageByDiscord(ctx, m.MessageReference.MessageID)
+	if err != nil {
+		return nil, err
+	}
+	if link == nil {
+		// Respuesta a un mensaje que no pasó por el puente: se envía sin cita
+		return nil, nil
+	}
+
+	quote := &whatsapp.Quote{ID: link.WAMessageID, Text: link.WAText}
+	if link.Direction == database.DirectionToDiscord {
+		quote.Sender = link.WASender
+	} else if own, err := whatsapp.ParseJID(br.wa.GetJID()); err == nil {
+		// Mensaje que Lisa envió desde otra respuesta de Discord
+		quote.Sender = own.ToNonAD().String()
+	}
+	return quote, nil
+}
+
+// sendAttachment descarga un adjunto de Discord y lo envía al chat
+func (br *Bridge) sendAttachment(ctx context.Context, chat string, att *discordgo.MessageAttachment, quote *whatsapp.Quote) (string, error) {
+	media, err := downloadAttachment(ctx, br.bot.session, att)
+	if err != nil {
+		return "", err
+	}
+	return br.wa.SendMedia(ctx, chat, media, quote)
+}
+
+// downloadAttachment descarga un adjunto de Discord de hasta attachmentLimit
+func downloadAttachment(ctx context.Context, s *discordgo.Session, att *discordgo.MessageAttachment) (*types.MediaMessage, error) {
+	if att.Size > attachmentLimit {
+		return nil, fmt.Errorf("pesa %d MB y el límite es %d MB", att.Size>>20, attachmentLimit>>20)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, att.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo descargar: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("no se pudo descargar: HTTP %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, attachmentLimit+1))
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo descargar: %w", err)
+	}
+	if len(data) > attachmentLimit {
+		return nil, fmt.Errorf("supera el límite de %d MB", attachmentLimit>>20)
+	}
+
+	contentType := att.ContentType
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+
+	return &types.MediaMessage{
+		MimeType: contentType,
+		Filename: att.Filename,
+		Data:     data,
+		Size:     int64(len(data)),
+	}, nil
+}
+
+// notice avisa al agente que su mensaje no llegó. Los mensajes normales no
+// pueden ser efímeros, así que se marca el mensaje con una reacción y se
+// responde con un aviso que se borra solo.
+func (br *Bridge) notice(m *discordgo.Message, text string) {
+	s := br.bot.session
+	if err := s.MessageReactionAdd(m.ChannelID, m.ID, "⚠️"); err != nil {
+		log.Printf("DC: Puente: no se pudo marcar el mensaje %s: %v", m.ID, err)
+	}
+
+	sent, err := s.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{
+		Content:         fmt.Sprintf("<@%s> %s", m.Author.ID, text),
+		Reference:       m.Reference(),
+		AllowedMentions: &discordgo.MessageAllowedMentions{Users: []string{m.Author.ID}},
+	})
+	if err != nil {
+		log.Printf("DC: Puente: no se pudo avisar el error: %v", err)
+		return
+	}
+	time.AfterFunc(noticeTTL, func() {
+		s.ChannelMessageDelete(sent.ChannelID, sent.ID)
+	})
+}
+
+// agentName nombre del agente en el servidor (apodo, nombre global o usuario)
+func agentName(m *discordgo.Message) string {
+	if m.Member != nil && m.Member.Nick != "" {
+		return m.Member.Nick
+	}
+	if m.Author.GlobalName != "" {
+		return m.Author.GlobalName
+	}
+	return m.Author.Username
+}
@@ -0,0 +1,53 @@
+package bot
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitMessage(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  []string
+	}{
+		{"vacío", "", 10, []string{""}},
+		{"entra completo", "hola", 10, []string{"hola"}},
+		{"justo en el límite", "abcdef", 6, []string{"abcdef"}},
+		{"sin saltos de línea", "abcdefghij", 4, []string{"abcd", "efgh", "ij"}},
+		{"corta en el salto de línea", "aaaa\nbbbbbb", 6, []string{"aaaa", "bbbbbb"}},
+		{"descarta los saltos al inicio de la parte siguiente", "aaa\n\n\nbbbb", 5, []string{"aaa\n\n", "bbbb"}},
+		{"ignora saltos en la primera mitad", "a\nbbbbbbbbb", 6, []string{"a\nbbbb", "bbbbb"}},
+		{"cuenta runas y no bytes", "ñññññ", 2, []string{"ññ", "ññ", "ñ"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitMessage(tt.text, tt.limit); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitMessage(%q, %d) = %q, se esperaba %q", tt.text, tt.limit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitMessageRespectsDiscordLimit(t *testing.T) {
+	const limit = 2000
+	text := strings.Repeat("Línea de la conversación con el cliente\n", 200)
+
+	chunks := splitMessage(text, limit)
+	if len(chunks) < 2 {
+		t.Fatalf("se esperaban varias partes, hubo %d", len(chunks))
+	}
+	for i, chunk := range chunks {
+		if n := len([]rune(chunk)); n > limit {
+			t.Errorf("la parte %d tiene %d caracteres", i, n)
+		}
+		if i < len(chunks)-1 && strings.HasSuffix(chunk, "\n") {
+			t.Errorf("la parte %d termina con un salto de línea", i)
+		}
+	}
+	if got := strings.Join(chunks, "\n"); got != text {
+		t.Errorf("al unir las partes se perdió texto")
+	}
+}
@@ -1 +1,510 @@
 package bot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// interactionTimeout duración de un token de interacción según Discord
+const interactionTimeout = 15 * time.Minute
+
+// SlashHandler ejecuta un comando de barra
+type SlashHandler func(ic *InteractionContext) error
+
+// ComponentHandler maneja botones y formularios (modales). Se registra por el
+// prefijo del custom ID: "triage:crear:42" lo atiende el handler de "triage".
+type ComponentHandler func(ic *InteractionContext) error
+
+// AutocompleteHandler sugiere valores para la opción que el usuario está escribiendo
+type AutocompleteHandler func(ic *InteractionContext, focused *discordgo.ApplicationCommandInteractionDataOption) ([]*discordgo.ApplicationCommandOptionChoice, error)
+
+// SlashCommand definición declarativa de un comando y su handler
+type SlashCommand struct {
+	Definition *discordgo.ApplicationCommand
+	Handler    SlashHandler
+
+	// Autocomplete atiende las opciones con Autocomplete: true
+	Autocomplete AutocompleteHandler
+
+	// Defer responde de inmediato con "pensando..." para operaciones que
+	// pueden superar los 3 segundos que Discord da para responder
+	Defer     bool
+	Ephemeral bool
+}
+
+// CommandRegistry registro de comandos de barra del servidor
+type CommandRegistry struct {
+	mu       sync.RWMutex
+	commands map[string]*SlashCommand
+}
+
+func NewCommandRegistry() *CommandRegistry {
+	return &CommandRegistry{commands: make(map[string]*SlashCommand)}
+}
+
+// Register agrega un comando. Falla si ya existe uno con el mismo nombre.
+func (r *CommandRegistry) Register(cmd SlashCommand) error {
+	if cmd.Definition == nil || cmd.Definition.Name == "" || cmd.Handler == nil {
+		return fmt.Errorf("comando inválido: definición, nombre y handler son obligatorios")
+	}
+	if cmd.Definition.Type == 0 {
+		cmd.Definition.Type = discordgo.ChatApplicationCommand
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.commands[cmd.Definition.Name]; exists {
+		return fmt.Errorf("comando duplicado: /%s", cmd.Definition.Name)
+	}
+	r.commands[cmd.Definition.Name] = &cmd
+	return nil
+}
+
+// Lookup busca un comando por nombre
+func (r *CommandRegistry) Lookup(name string) (*SlashCommand, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	cmd, ok := r.commands[name]
+	return cmd, ok
+}
+
+// Commands devuelve los comandos ordenados por nombre
+func (r *CommandRegistry) Commands() []*SlashCommand {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	cmds := make([]*SlashCommand, 0, len(r.commands))
+	for _, cmd := range r.commands {
+		cmds = append(cmds, cmd)
+	}
+	sort.Slice(cmds, func(i, j int) bool { return cmds[i].Definition.Name < cmds[j].Definition.Name })
+	return cmds
+}
+
+// Sync sincroniza las definiciones con Discord: crea los comandos nuevos,
+// actualiza los que cambiaron y elimina los que ya no están en el registro.
+func (r *CommandRegistry) Sync(s *discordgo.Session, appID, guildID string) error {
+	existing, err := s.ApplicationCommands(appID, guildID)
+	if err != nil {
+		return fmt.Errorf("no se pudieron listar los comandos registrados: %w", err)
+	}
+
+	byName := make(map[string]*discordgo.ApplicationCommand, len(existing))
+	for _, cmd := range existing {
+		byName[cmd.Name] = cmd
+	}
+
+	var created, updated, deleted int
+	for _, cmd := range r.Commands() {
+		def := cmd.Definition
+		current, ok := byName[def.Name]
+		delete(byName, def.Name)
+
+		switch {
+		case !ok:
+			if _, err := s.ApplicationCommandCreate(appID, guildID, def); err != nil {
+				return fmt.Errorf("no se pudo crear /%s: %w", def.Name, err)
+			}
+			created++
+		case commandSignature(current) != commandSignature(def):
+			if _, err := s.ApplicationCommandEdit(appID, guildID, current.ID, def); err != nil {
+				return fmt.Errorf("no se pudo actualizar /%s: %w", def.Name, err)
+			}
+			updated++
+		}
+	}
+
+	// Lo que queda en byName son comandos obsoletos
+	for _, stale := range byName {
+		if err := s.ApplicationCommandDelete(appID, guildID, stale.ID); err != nil {
+			return fmt.Errorf("no se pudo eliminar /%s: %w", stale.Name, err)
+		}
+		deleted++
+	}
+
+	log.Printf("DC: Comandos sincronizados (%d nuevos, %d actualizados, %d eliminados)", created, updated, deleted)
+	return nil
+}
+
+// commandSignature representación comparable de los campos que definimos nosotros
+func commandSignature(cmd *discordgo.ApplicationCommand) string {
+	data, _ := json.Marshal(struct {
+		Type        discordgo.ApplicationCommandType
+		Name        string
+		Description string
+		Options     []*discordgo.ApplicationCommandOption
+		Permissions *int64
+	}{cmd.Type, cmd.Name, cmd.Description, cmd.Options, cmd.DefaultMemberPermissions})
+	return string(data)
+}
+
+// handleInteraction despacha las interacciones recibidas por el gateway
+func (b *Bot) handleInteraction(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand:
+		b.dispatchCommand(i)
+	case discordgo.InteractionApplicationCommandAutocomplete:
+		b.dispatchAutocomplete(i)
+	case discordgo.InteractionMessageComponent:
+		b.dispatchComponent(i, i.MessageComponentData().CustomID)
+	case discordgo.InteractionModalSubmit:
+		b.dispatchComponent(i, i.ModalSubmitData().CustomID)
+	}
+}
+
+// HandleComponent registra el handler de los botones y formularios cuyo
+// custom ID empieza con "prefijo:"
+func (b *Bot) HandleComponent(prefix string, handler ComponentHandler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.components[prefix] = handler
+}
+
+func (b *Bot) dispatchComponent(i *discordgo.InteractionCreate, customID string) {
+	prefix, _, _ := strings.Cut(customID, ":")
+
+	b.mu.RLock()
+	handler, ok := b.components[prefix]
+	b.mu.RUnlock()
+	if !ok {
+		log.Printf("DC: Componente sin handler: %s", customID)
+		return
+	}
+
+	ic := newInteractionContext(b, i, nil)
+	ic.customID = customID
+	go func() {
+		defer ic.cancel()
+		if err := handler(ic); err != nil {
+			log.Printf("DC: Error en %s: %v", customID, err)
+			ic.RespondError(err)
+		}
+	}()
+}
+
+func (b *Bot) dispatchCommand(i *discordgo.InteractionCreate) {
+	data := i.ApplicationCommandData()
+	cmd, ok := b.commands.Lookup(data.Name)
+	if !ok {
+		log.Printf("DC: Comando desconocido /%s", data.Name)
+		return
+	}
+
+	ic := newInteractionContext(b, i, data.Options)
+	ic.ephemeral = cmd.Ephemeral
+	log.Printf("DC: Comando /%s de %s", data.Name, ic.User())
+
+	if cmd.Defer {
+		if err := ic.Defer(); err != nil {
+			log.Printf("DC: No se pudo diferir /%s: %v", data.Name, err)
+			return
+		}
+	}
+
+	go func() {
+		defer ic.cancel()
+		if err := cmd.Handler(ic); err != nil {
+			log.Printf("DC: Error en /%s: %v", data.Name, err)
+			ic.RespondError(err)
+		}
+	}()
+}
+
+// dispatchAutocomplete responde las sugerencias dentro del plazo de 3 segundos
+// de Discord. Si el handler falla o no llega a tiempo, no se sugiere nada.
+func (b *Bot) dispatchAutocomplete(i *discordgo.InteractionCreate) {
+	data := i.ApplicationCommandData()
+	cmd, ok := b.commands.Lookup(data.Name)
+	if !ok || cmd.Autocomplete == nil {
+		return
+	}
+
+	var focused *discordgo.ApplicationCommandInteractionDataOption
+	for _, opt := range data.Options {
+		if opt.Focused {
+			focused = opt
+		}
+	}
+	if focused == nil {
+		return
+	}
+
+	go func() {
+		ic := newInteractionContext(b, i, data.Options)
+		defer ic.cancel()
+		ctx, cancel := context.WithTimeout(ic.Ctx, autocompleteDeadline)
+		defer cancel()
+		ic.Ctx = ctx
+
+		choices, err := cmd.Autocomplete(ic, focused)
+		if err != nil {
+			log.Printf("DC: Autocompletado de /%s %s: %v", data.Name, focused.Name, err)
+			choices = nil
+		}
+		if len(choices) > maxChoices {
+			choices = choices[:maxChoices]
+		}
+		if choices == nil {
+			choices = []*discordgo.ApplicationCommandOptionChoice{}
+		}
+
+		err = b.session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+			Data: &discordgo.InteractionResponseData{Choices: choices},
+		})
+		if err != nil {
+			log.Printf("DC: No se pudo responder el autocompletado de /%s: %v", data.Name, err)
+		}
+	}()
+}
+
+// InteractionContext datos y respuestas de una interacción
+type InteractionContext struct {
+	Ctx         context.Context
+	Bot         *Bot
+	Interaction *discordgo.InteractionCreate
+	Options     Options
+
+	cancel    context.CancelFunc
+	customID  string
+	deferred  bool
+	responded bool
+	ephemeral bool
+}
+
+func newInteractionContext(b *Bot, i *discordgo.InteractionCreate, opts []*discordgo.ApplicationCommandInteractionDataOption) *InteractionContext {
+	ctx, cancel := context.WithTimeout(context.Background(), interactionTimeout)
+	return &InteractionContext{
+		Ctx:         ctx,
+		Bot:         b,
+		Interaction: i,
+		Options:     NewOptions(opts),
+		cancel:      cancel,
+	}
+}
+
+// User devuelve quién ejecutó la interacción (miembro del servidor o usuario en DM)
+func (ic *InteractionContext) User() *discordgo.User {
+	if ic.Interaction.Member != nil {
+		return ic.Interaction.Member.User
+	}
+	return ic.Interaction.User
+}
+
+func (ic *InteractionContext) flags() discordgo.MessageFlags {
+	if ic.ephemeral {
+		return discordgo.MessageFlagsEphemeral
+	}
+	return 0
+}
+
+// Defer confirma la interacción y muestra "pensando..." hasta la respuesta final
+func (ic *InteractionContext) Defer() error {
+	err := ic.Bot.session.InteractionRespond(ic.Interaction.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: ic.flags()},
+	})
+	if err == nil {
+		ic.deferred = true
+	}
+	return err
+}
+
+// Respond envía la respuesta (o edita la respuesta diferida)
+func (ic *InteractionContext) Respond(content string, embeds ...*discordgo.MessageEmbed) error {
+	if ic.deferred || ic.responded {
+		_, err := ic.Bot.session.InteractionResponseEdit(ic.Interaction.Interaction, &discordgo.WebhookEdit{
+			Content: &content,
+			Embeds:  &embeds,
+		})
+		return err
+	}
+
+	ic.responded = true
+	return ic.Bot.session.InteractionRespond(ic.Interaction.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Embeds:  embeds,
+			Flags:   ic.flags(),
+		},
+	})
+}
+
+// RespondError informa un error solo a quien ejecutó la interacción
+func (ic *InteractionContext) RespondError(err error) {
+	content := fmt.Sprintf("ERROR: %v", err)
+
+	var sendErr error
+	if ic.deferred || ic.responded {
+		_, sendErr = ic.Bot.session.FollowupMessageCreate(ic.Interaction.Interaction, false, &discordgo.WebhookParams{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		})
+	} else {
+		ic.ephemeral = true
+		sendErr = ic.Respond(content)
+	}
+	if sendErr != nil {
+		log.Printf("DC: No se pudo informar el error: %v", sendErr)
+	}
+}
+
+// CustomID devuelve el custom ID del botón o formulario
+func (ic *InteractionContext) CustomID() string {
+	return ic.customID
+}
+
+// DeferUpdate confirma un botón sin mostrar nada; el mensaje se edita luego con Update
+func (ic *InteractionContext) DeferUpdate() error {
+	err := ic.Bot.session.InteractionRespond(ic.Interaction.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredMessageUpdate,
+	})
+	if err == nil {
+		ic.deferred = true
+	}
+	return err
+}
+
+// Update reemplaza los embeds y componentes del mensaje que contiene el botón
+func (ic *InteractionContext) Update(embeds []*discordgo.MessageEmbed, components []discordgo.MessageComponent) error {
+	if components == nil {
+		components = []discordgo.MessageComponent{}
+	}
+	if ic.deferred || ic.responded {
+		_, err := ic.Bot.session.InteractionResponseEdit(ic.Interaction.Interaction, &discordgo.WebhookEdit{
+			Embeds:     &embeds,
+			Components: &components,
+		})
+		return err
+	}
+
+	ic.responded = true
+	return ic.Bot.session.InteractionRespond(ic.Interaction.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     embeds,
+			Components: components,
+		},
+	})
+}
+
+// OpenModal responde mostrando un formulario. Cada campo va en su propia fila.
+func (ic *InteractionContext) OpenModal(customID, title string, inputs ...discordgo.TextInput) error {
+	rows := make([]discordgo.MessageComponent, 0, len(inputs))
+	for i := range inputs {
+		rows = append(rows, discordgo.ActionsRow{Components: []discordgo.MessageComponent{inputs[i]}})
+	}
+
+	ic.responded = true
+	return ic.Bot.session.InteractionRespond(ic.Interaction.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID:   customID,
+			Title:      truncate(title, 45),
+			Components: rows,
+		},
+	})
+}
+
+// TargetMessage devuelve el mensaje sobre el que se usó un comando de menú contextual
+func (ic *InteractionContext) TargetMessage() *discordgo.Message {
+	if ic.Interaction.Type != discordgo.InteractionApplicationCommand {
+		return nil
+	}
+	data := ic.Interaction.ApplicationCommandData()
+	if data.Resolved == nil {
+		return nil
+	}
+	return data.Resolved.Messages[data.TargetID]
+}
+
+// ModalValues devuelve los valores de un formulario enviado, por custom ID del campo
+func (ic *InteractionContext) ModalValues() map[string]string {
+	values := make(map[string]string)
+	if ic.Interaction.Type != discordgo.InteractionModalSubmit {
+		return values
+	}
+	for _, row := range ic.Interaction.ModalSubmitData().Components {
+		actions, ok := row.(*discordgo.ActionsRow)
+		if !ok {
+			continue
+		}
+		for _, c := range actions.Components {
+			if input, ok := c.(*discordgo.TextInput); ok {
+				values[input.CustomID] = strings.TrimSpace(input.Value)
+			}
+		}
+	}
+	return values
+}
+
+// Options acceso tipado a las opciones de un comando
+type Options map[string]*discordgo.ApplicationCommandInteractionDataOption
+
+func NewOptions(opts []*discordgo.ApplicationCommandInteractionDataOption) Options {
+	o := make(Options, len(opts))
+	for _, opt := range opts {
+		o[opt.Name] = opt
+	}
+	return o
+}
+
+func (o Options) String(name string) (string, bool) {
+	opt, ok := o[name]
+	if !ok || opt.Type != discordgo.ApplicationCommandOptionString {
+		return "", false
+	}
+	return opt.StringValue(), true
+}
+
+// StringOr devuelve la opción o el valor por defecto si no se indicó
+func (o Options) StringOr(name, def string) string {
+	if v, ok := o.String(name); ok && v != "" {
+		return v
+	}
+	return def
+}
+
+func (o Options) Int(name string) (int64, bool) {
+	opt, ok := o[name]
+	if !ok || opt.Type != discordgo.ApplicationCommandOptionInteger {
+		return 0, false
+	}
+	return opt.IntValue(), true
+}
+
+// IntOr devuelve la opción entera o el valor por defecto
+func (o Options) IntOr(name string, def int64) int64 {
+	if v, ok := o.Int(name); ok {
+		return v
+	}
+	return def
+}
+
+func (o Options) Bool(name string) (bool, bool) {
+	opt, ok := o[name]
+	if !ok || opt.Type != discordgo.ApplicationCommandOptionBoolean {
+		return false, false
+	}
+	return opt.BoolValue(), true
+}
+
+// UserID devuelve el ID del usuario elegido en una opción de tipo usuario
+func (o Options) UserID(name string) (string, bool) {
+	opt, ok := o[name]
+	if !ok || opt.Type != discordgo.ApplicationCommandOptionUser {
+		return "", false
+	}
+	id, _ := opt.Value.(string)
+	return id, id != ""
+}
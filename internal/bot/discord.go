@@ -1 +1,270 @@
 package bot
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"Lisa/internal/config"
+)
+
+// readyTimeout tiempo máximo de espera del evento READY al conectar
+const readyTimeout = 30 * time.Second
+
+// intentsByName intents que se pueden habilitar desde DISCORD_INTENTS
+var intentsByName = map[string]discordgo.Intent{
+	"guilds":                   discordgo.IntentsGuilds,
+	"guild_members":            discordgo.IntentsGuildMembers,
+	"guild_messages":           discordgo.IntentsGuildMessages,
+	"guild_message_reactions":  discordgo.IntentsGuildMessageReactions,
+	"guild_presences":          discordgo.IntentsGuildPresences,
+	"guild_webhooks":           discordgo.IntentsGuildWebhooks,
+	"direct_messages":          discordgo.IntentsDirectMessages,
+	"direct_message_reactions": discordgo.IntentsDirectMessageReactions,
+	"message_content":          discordgo.IntentsMessageContent,
+}
+
+// Status estado de la conexión con el gateway de Discord
+type Status struct {
+	Ready      bool
+	User       string
+	Since      time.Time
+	Reconnects int
+	Latency    time.Duration
+}
+
+// Bot cliente del gateway y la API REST de Discord
+type Bot struct {
+	session  *discordgo.Session
+	cfg      config.DiscordConfig
+	commands *CommandRegistry
+
+	// Handlers de botones y formularios por prefijo del custom ID
+	components map[string]ComponentHandler
+
+	mu         sync.RWMutex
+	ready      bool
+	user       *discordgo.User
+	since      time.Time
+	reconnects int
+	readyCh    chan struct{}
+}
+
+// New crea el bot con los intents configurados. Si DiscordConfig.APIURL está
+// definido, las llamadas REST (y por lo tanto la URL del gateway) se dirigen a
+// ese servidor, lo que permite probar contra un Discord falso local.
+func New(cfg config.DiscordConfig) (*Bot, error) {
+	if cfg.Token == "" {
+		return nil, fmt.Errorf("falta el token de Discord")
+	}
+
+	intents, err := parseIntents(cfg.Intents)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := discordgo.New("Bot " + cfg.Token)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo crear la sesion de Discord: %w", err)
+	}
+	session.Identify.Intents = intents
+	session.ShouldReconnectOnError = true
+	session.LogLevel = discordgo.LogWarning
+
+	if cfg.APIURL != "" {
+		base, err := url.Parse(strings.TrimSuffix(cfg.APIURL, "/") + "/")
+		if err != nil {
+			return nil, fmt.Errorf("DISCORD_API_URL inválido: %w", err)
+		}
+		session.Client.Transport = &rewriteTransport{base: base, next: http.DefaultTransport}
+	}
+
+	b := &Bot{
+		session:    session,
+		cfg:        cfg,
+		commands:   NewCommandRegistry(),
+		components: make(map[string]ComponentHandler),
+		readyCh:    make(chan struct{}, 1),
+	}
+
+	session.AddHandler(b.onReady)
+	session.AddHandler(b.onResumed)
+	session.AddHandler(b.onDisconnect)
+	session.AddHandler(b.handleInteraction)
+
+	return b, nil
+}
+
+// Open conecta al gateway, espera el evento READY y sincroniza los comandos de
+// barra. Sin DISCORD_GUILD_ID los comandos se registran globalmente, y Discord
+// puede tardar hasta una hora en mostrarlos.
+func (b *Bot) Open() error {
+	if err := b.session.Open(); err != nil {
+		return fmt.Errorf("no se pudo conectar al gateway de Discord: %w", err)
+	}
+
+	select {
+	case <-b.readyCh:
+	case <-time.After(readyTimeout):
+		b.session.Close()
+		return fmt.Errorf("Discord no envió READY en %s", readyTimeout)
+	}
+
+	if b.cfg.GuildID == "" {
+		log.Println("DC: WARN: DISCORD_GUILD_ID no configurado, los comandos se registran globalmente")
+	}
+
+	b.mu.RLock()
+	appID := b.user.ID
+	b.mu.RUnlock()
+
+	if err := b.commands.Sync(b.session, appID, b.cfg.GuildID); err != nil {
+		b.session.Close()
+		return err
+	}
+	return nil
+}
+
+// Commands devuelve el registro de comandos de barra. Los comandos deben
+// registrarse antes de Open para que se sincronicen con Discord.
+func (b *Bot) Commands() *CommandRegistry {
+	return b.commands
+}
+
+// Close cierra la conexión con el gateway
+func (b *Bot) Close() error {
+	b.mu.Lock()
+	b.ready = false
+	b.mu.Unlock()
+
+	log.Println("DC: Bot desconectado")
+	return b.session.Close()
+}
+
+// Session devuelve la sesión de discordgo para registrar handlers o llamar a la API
+func (b *Bot) Session() *discordgo.Session {
+	return b.session
+}
+
+// GuildID devuelve el servidor configurado
+func (b *Bot) GuildID() string {
+	return b.cfg.GuildID
+}
+
+// IsReady indica si el bot está conectado y recibió READY
+func (b *Bot) IsReady() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.ready
+}
+
+// Status devuelve el estado de la conexión
+func (b *Bot) Status() Status {
+	// discordgo actualiza el último ACK del heartbeat con el lock de la sesión
+	b.session.RLock()
+	latency := b.session.HeartbeatLatency()
+	b.session.RUnlock()
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	status := Status{
+		Ready:      b.ready,
+		Since:      b.since,
+		Reconnects: b.reconnects,
+		Latency:    latency,
+	}
+	if b.user != nil {
+		status.User = b.user.String()
+	}
+	return status
+}
+
+func (b *Bot) onReady(s *discordgo.Session, r *discordgo.Ready) {
+	b.mu.Lock()
+	reconnected := !b.since.IsZero()
+	b.ready = true
+	b.user = r.User
+	b.since = time.Now()
+	b.mu.Unlock()
+
+	if reconnected {
+		log.Printf("DC: Reconectado como %s", r.User)
+	} else {
+		log.Printf("DC: Bot listo como %s (%d servidores)", r.User, len(r.Guilds))
+	}
+
+	if b.cfg.GuildID != "" {
+		found := false
+		for _, g := range r.Guilds {
+			if g.ID == b.cfg.GuildID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			log.Printf("DC: WARN: El bot no pertenece al servidor configurado (%s)", b.cfg.GuildID)
+		}
+	}
+
+	select {
+	case b.readyCh <- struct{}{}:
+	default:
+	}
+}
+
+func (b *Bot) onResumed(s *discordgo.Session, r *discordgo.Resumed) {
+	b.mu.Lock()
+	b.ready = true
+	b.mu.Unlock()
+	log.Println("DC: Sesion reanudada")
+}
+
+func (b *Bot) onDisconnect(s *discordgo.Session, d *discordgo.Disconnect) {
+	b.mu.Lock()
+	wasReady := b.ready
+	b.ready = false
+	if wasReady {
+		b.reconnects++
+	}
+	b.mu.Unlock()
+
+	if wasReady {
+		log.Println("DC: Desconectado del gateway, reconectando...")
+	}
+}
+
+func parseIntents(names []string) (discordgo.Intent, error) {
+	var intents discordgo.Intent
+	for _, name := range names {
+		intent, ok := intentsByName[strings.ToLower(name)]
+		if !ok {
+			return 0, fmt.Errorf("intent de Discord desconocido: %q", name)
+		}
+		intents |= intent
+	}
+	return intents, nil
+}
+
+// rewriteTransport redirige las llamadas a discord.com hacia otra URL base
+type rewriteTransport struct {
+	base *url.URL
+	next http.RoundTripper
+}
+
+func (t *rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host == "discord.com" || req.URL.Host == "discordapp.com" {
+		req = req.Clone(req.Context())
+		req.URL.Scheme = t.base.Scheme
+		req.URL.Host = t.base.Host
+		req.URL.Path = t.base.Path + strings.TrimPrefix(req.URL.Path, "/")
+		req.Host = t.base.Host
+	}
+	return t.next.RoundTrip(req)
+}
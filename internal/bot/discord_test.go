@@ -0,0 +1,219 @@
+package bot
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gorilla/websocket"
+
+	"Lisa/internal/config"
+)
+
+// fakeAPI ruta de la API REST en el servidor falso (DISCORD_API_URL termina en /fake)
+var fakeAPI = "/fake/api/v" + discordgo.APIVersion
+
+// fakeDiscord gateway y API REST de Discord mínimos: la primera conexión al
+// gateway se corta después de READY para forzar una reconexión
+type fakeDiscord struct {
+	t      *testing.T
+	server *httptest.Server
+
+	mu          sync.Mutex
+	paths       []string
+	connections int
+}
+
+func newFakeDiscord(t *testing.T) *fakeDiscord {
+	f := &fakeDiscord{t: t}
+	mux := http.NewServeMux()
+	gateway := func(w http.ResponseWriter, r *http.Request) {
+		f.record(r)
+		json.NewEncoder(w).Encode(map[string]interface{}{"url": "ws" + strings.TrimPrefix(f.server.URL, "http") + "/ws", "shards": 1})
+	}
+	mux.HandleFunc(fakeAPI+"/gateway", gateway)
+	mux.HandleFunc(fakeAPI+"/gateway/bot", gateway)
+	mux.HandleFunc("/ws/", f.gateway)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		f.record(r)
+		if strings.HasSuffix(r.URL.Path, "/users/@me") {
+			json.NewEncoder(w).Encode(map[string]string{"id": "42", "username": "lisa"})
+			return
+		}
+		w.Write([]byte("[]"))
+	})
+	f.server = httptest.NewServer(mux)
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func (f *fakeDiscord) record(r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.paths = append(f.paths, r.Method+" "+r.URL.Path)
+}
+
+func (f *fakeDiscord) sawPath(path string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, p := range f.paths {
+		if p == path {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *fakeDiscord) gateway(w http.ResponseWriter, r *http.Request) {
+	conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+	if err != nil {
+		f.t.Errorf("upgrade: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	f.mu.Lock()
+	f.connections++
+	first := f.connections == 1
+	f.mu.Unlock()
+
+	send := func(v interface{}) bool {
+		if err := conn.WriteJSON(v); err != nil {
+			f.t.Logf("gateway: %v", err)
+			return false
+		}
+		return true
+	}
+
+	if !send(map[string]interface{}{"op": 10, "d": map[string]int{"heartbeat_interval": 45000}}) {
+		return
+	}
+	// IDENTIFY u op 6 RESUME
+	var hello struct {
+		Op int `json:"op"`
+	}
+	if err := conn.ReadJSON(&hello); err != nil {
+		return
+	}
+
+	ready := map[string]interface{}{
+		"op": 0, "s": 1, "t": "READY",
+		"d": map[string]interface{}{
+			"v":          10,
+			"session_id": "sesion",
+			"user":       map[string]string{"id": "42", "username": "lisa"},
+			"guilds":     []map[string]string{{"id": "g1"}},
+		},
+	}
+	if hello.Op == 6 {
+		ready = map[string]interface{}{"op": 0, "s": 2, "t": "RESUMED", "d": map[string]interface{}{}}
+	}
+	if !send(ready) {
+		return
+	}
+
+	if first {
+		// Se espera el primer heartbeat y se corta la conexión sin cerrar el
+		// websocket, como una caída de red. Si se corta antes, el heartbeat
+		// fallido de discordgo provoca una segunda reconexión.
+		conn.ReadMessage()
+		return
+	}
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("tiempo agotado esperando %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestBotReconnectsAgainstFakeDiscord(t *testing.T) {
+	fake := newFakeDiscord(t)
+
+	b, err := New(config.DiscordConfig{
+		Token:   "token",
+		GuildID: "g1",
+		Intents: []string{"guilds", "guild_messages"},
+		APIURL:  fake.server.URL + "/fake",
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err := b.Open(); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() {
+		// Sin esto discordgo vuelve a conectarse al servidor falso tras Close
+		b.Session().ShouldReconnectOnError = false
+		b.Close()
+	}()
+
+	if !fake.sawPath("GET " + fakeAPI + "/gateway") {
+		t.Errorf("la URL del gateway no se pidió al servidor falso: %v", fake.paths)
+	}
+	if !fake.sawPath("GET " + fakeAPI + "/applications/42/guilds/g1/commands") {
+		t.Errorf("los comandos no se sincronizaron con el servidor falso: %v", fake.paths)
+	}
+
+	// El gateway corta la primera conexión: el bot se reconecta y reanuda
+	waitFor(t, "la reconexión", func() bool {
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		return fake.connections >= 2
+	})
+	waitFor(t, "el bot listo", b.IsReady)
+
+	status := b.Status()
+	if status.Reconnects != 1 {
+		t.Errorf("Reconnects = %d, se esperaba 1", status.Reconnects)
+	}
+	if !strings.HasPrefix(status.User, "lisa") {
+		t.Errorf("User = %q", status.User)
+	}
+
+	user, err := b.Session().User("@me")
+	if err != nil {
+		t.Fatalf("User(@me): %v", err)
+	}
+	if user.ID != "42" || !fake.sawPath("GET "+fakeAPI+"/users/@me") {
+		t.Errorf("la llamada REST no se redirigió al servidor falso: %v", fake.paths)
+	}
+}
+
+func TestNewRequiresToken(t *testing.T) {
+	if _, err := New(config.DiscordConfig{}); err == nil {
+		t.Fatal("se esperaba error sin token")
+	}
+}
+
+func TestParseIntents(t *testing.T) {
+	tests := []struct {
+		names   []string
+		wantErr bool
+	}{
+		{names: nil},
+		{names: []string{"guilds", "MESSAGE_CONTENT"}},
+		{names: []string{"guilds", "voz"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		_, err := parseIntents(tt.names)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseIntents(%v) error = %v, wantErr %v", tt.names, err, tt.wantErr)
+		}
+	}
+}
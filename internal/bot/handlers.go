@@ -1 +1,393 @@
 package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+
+	"Lisa/internal/whatsapp"
+	"Lisa/pkg/types"
+)
+
+// Colores de los embeds
+const (
+	colorInfo    = 0x3498db
+	colorSuccess = 0x2ecc71
+	colorWarning = 0xf1c40f
+	colorError   = 0xe74c3c
+	colorMuted   = 0x95a5a6
+)
+
+// searchLimit cantidad máxima de resultados de /search
+const searchLimit = 10
+
+// TicketService operaciones de tickets que usan los comandos de Discord
+type TicketService interface {
+	CreateTicket(ctx context.Context, draft types.TicketDraft) (*types.TicketInfo, error)
+	GetTicket(ctx context.Context, key string) (*types.TicketInfo, error)
+	SearchTickets(ctx context.Context, query types.TicketQuery, limit int) ([]types.TicketInfo, error)
+	AddAttachment(ctx context.Context, key string, media *types.MediaMessage) error
+}
+
+// WorkflowService cambios de estado, asignación y prioridad de tickets. La
+// transición puede indicarse por ID, por nombre o por el estado de destino.
+// Un accountID vacío deja el ticket sin asignar.
+type WorkflowService interface {
+	TransitionTicket(ctx context.Context, key, transition string) (*types.TicketInfo, error)
+	AssignTicket(ctx context.Context, key, accountID string) (*types.TicketInfo, error)
+	SetPriority(ctx context.Context, key, priority string) (*types.TicketInfo, error)
+}
+
+// WhatsAppService lo que el bot necesita del cliente de WhatsApp
+type WhatsAppService interface {
+	IsConnected() bool
+	State() whatsapp.ConnectionState
+	StateReason() string
+	GetJID() string
+	History() *whatsapp.History
+	SendTextMessage(jid, text string) error
+	SendText(ctx context.Context, chat, text string, quote *whatsapp.Quote) (string, error)
+	ConversationMedia(ctx context.Context, chat string, messageIDs []string) []*types.MediaMessage
+}
+
+// Services dependencias de los comandos. Los servicios nil se reportan como
+// no configurados al usar el comando.
+type Services struct {
+	Tickets    TicketService
+	Workflow   WorkflowService
+	Directory  TicketDirectory
+	WhatsApp   WhatsAppService
+	Summarizer whatsapp.Summarizer
+	Classifier Classifier
+	Drafter    Drafter
+	Links      LinkStore
+	Bridge     *Bridge
+	Database   Pinger
+}
+
+// RegisterDefaultCommands registra /ticket, /transition, /assign, /wa-status,
+// /summary, /search, /help y el menú contextual "Crear ticket de Jira" en el
+// bot, junto con el formulario de /ticket
+func RegisterDefaultCommands(b *Bot, services Services) error {
+	r := b.Commands()
+	tickets := newTicketForm(b, services)
+	lookup := newAutocompleter(services.Directory)
+	minCount := 1.0
+	commands := []SlashCommand{
+		{
+			Definition: &discordgo.ApplicationCommand{
+				Name:        "ticket",
+				Description: "Crea un ticket en Jira desde un formulario con la conversación de WhatsApp",
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionString, Name: "chat", Description: "JID del chat de WhatsApp (por defecto, el del canal o hilo del puente)"},
+					{Type: discordgo.ApplicationCommandOptionString, Name: "proyecto", Description: "Proyecto de Jira (por defecto, el configurado)", Autocomplete: true},
+				},
+			},
+			Handler:      tickets.open,
+			Autocomplete: lookup.complete,
+		},
+		{
+			Definition: &discordgo.ApplicationCommand{
+				Name:        "transition",
+				Description: "Cambia el estado de un ticket de Jira",
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionString, Name: "issue", Description: "Clave del ticket", Required: true, Autocomplete: true},
+					{Type: discordgo.ApplicationCommandOptionString, Name: "transicion", Description: "Transición a aplicar", Required: true, Autocomplete: true},
+				},
+			},
+			Handler:      services.handleTransition,
+			Autocomplete: lookup.complete,
+			Defer:        true,
+		},
+		{
+			Definition: &discordgo.ApplicationCommand{
+				Name:        "assign",
+				Description: "Asigna un ticket de Jira",
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionString, Name: "issue", Description: "Clave del ticket", Required: true, Autocomplete: true},
+					{Type: discordgo.ApplicationCommandOptionString, Name: "usuario", Description: "Usuario de Jira", Required: true, Autocomplete: true},
+				},
+			},
+			Handler:      services.handleAssign,
+			Autocomplete: lookup.complete,
+			Defer:        true,
+		},
+		{
+			Definition: &discordgo.ApplicationCommand{
+				Name: messageTicketCommand,
+				Type: discordgo.MessageApplicationCommand,
+			},
+			Handler: tickets.fromMessage,
+			Defer:   true,
+		},
+		{
+			Definition: &discordgo.ApplicationCommand{
+				Name:        "wa-status",
+				Description: "Estado de la conexión con WhatsApp",
+			},
+			Handler:   services.handleWAStatus,
+			Ephemeral: true,
+		},
+		{
+			Definition: &discordgo.ApplicationCommand{
+				Name:        "summary",
+				Description: "Resume los mensajes recientes de un chat de WhatsApp",
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionString, Name: "chat", Description: "JID del chat de WhatsApp", Required: true},
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "cantidad", Description: "Cantidad de mensajes (por defecto 30)", MinValue: &minCount, MaxValue: 100},
+				},
+			},
+			Handler: services.handleSummary,
+			Defer:   true,
+		},
+		{
+			Definition: &discordgo.ApplicationCommand{
+				Name:        "search",
+				Description: "Busca tickets en Jira (sin criterios, los del chat del canal o hilo del puente)",
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionString, Name: "texto", Description: "Texto mencionado en el ticket"},
+					{Type: discordgo.ApplicationCommandOptionString, Name: "cliente", Description: "Nombre o teléfono del cliente"},
+					{Type: discordgo.ApplicationCommandOptionString, Name: "chat", Description: "JID del chat de WhatsApp desde el que se creó"},
+					{Type: discordgo.ApplicationCommandOptionString, Name: "proyecto", Description: "Proyecto de Jira", Autocomplete: true},
+					{Type: discordgo.ApplicationCommandOptionBoolean, Name: "abiertos", Description: "Solo tickets sin terminar"},
+				},
+			},
+			Handler:      services.handleSearch,
+			Autocomplete: lookup.complete,
+			Defer:        true,
+		},
+	}
+
+	for _, cmd := range commands {
+		if err := r.Register(cmd); err != nil {
+			return err
+		}
+	}
+
+	return r.Register(SlashCommand{
+		Definition: &discordgo.ApplicationCommand{
+			Name:        "help",
+			Description: "Muestra los comandos de Lisa",
+		},
+		Handler:   func(ic *InteractionContext) error { return ic.Respond("", helpEmbed(r)) },
+		Ephemeral: true,
+	})
+}
+
+func (s Services) handleTransition(ic *InteractionContext) error {
+	if s.Workflow == nil {
+		return fmt.Errorf("Jira no está configurado")
+	}
+
+	key, _ := ic.Options.String("issue")
+	key = strings.ToUpper(key)
+	if !issueKeyPattern.MatchString(key) {
+		return fmt.Errorf("%q no es una clave de ticket válida (ejemplo: PROJ-123)", key)
+	}
+	transition, _ := ic.Options.String("transicion")
+
+	ticket, err := s.Workflow.TransitionTicket(ic.Ctx, key, transition)
+	if err != nil {
+		return fmt.Errorf("no se pudo cambiar el estado de %s: %w", key, err)
+	}
+	log.Printf("DC: %s pasó a %s por %s", ticket.Key, ticket.Status, ic.User())
+	return ic.Respond("", ticketEmbed(ticket, colorSuccess))
+}
+
+func (s Services) handleAssign(ic *InteractionContext) error {
+	if s.Workflow == nil {
+		return fmt.Errorf("Jira no está configurado")
+	}
+
+	key, _ := ic.Options.String("issue")
+	key = strings.ToUpper(key)
+	if !issueKeyPattern.MatchString(key) {
+		return fmt.Errorf("%q no es una clave de ticket válida (ejemplo: PROJ-123)", key)
+	}
+	accountID, _ := ic.Options.String("usuario")
+
+	ticket, err := s.Workflow.AssignTicket(ic.Ctx, key, accountID)
+	if err != nil {
+		return fmt.Errorf("no se pudo asignar %s: %w", key, err)
+	}
+	log.Printf("DC: %s asignado a %s por %s", ticket.Key, ticket.Assignee, ic.User())
+	return ic.Respond("", ticketEmbed(ticket, colorSuccess))
+}
+
+func (s Services) handleWAStatus(ic *InteractionContext) error {
+	if s.WhatsApp == nil {
+		return fmt.Errorf("WhatsApp no está configurado")
+	}
+
+	state := s.WhatsApp.State()
+	color := colorSuccess
+	if !s.WhatsApp.IsConnected() {
+		color = colorError
+	}
+
+	fields := []*discordgo.MessageEmbedField{
+		{Name: "Estado", Value: string(state), Inline: true},
+	}
+	if jid := s.WhatsApp.GetJID(); jid != "" {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Cuenta", Value: jid, Inline: true})
+	}
+	if reason := s.WhatsApp.StateReason(); reason != "" && state != whatsapp.StateConnected {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Motivo", Value: reason})
+	}
+
+	return ic.Respond("", &discordgo.MessageEmbed{
+		Title:  "WhatsApp",
+		Color:  color,
+		Fields: fields,
+	})
+}
+
+func (s Services) handleSummary(ic *InteractionContext) error {
+	if s.WhatsApp == nil {
+		return fmt.Errorf("WhatsApp no está configurado")
+	}
+
+	chat, _ := ic.Options.String("chat")
+	messages := s.WhatsApp.History().Recent(chat, int(ic.Options.IntOr("cantidad", 30)))
+	if len(messages) == 0 {
+		return ic.Respond("No hay mensajes recientes en ese chat.")
+	}
+
+	summary := whatsapp.BasicSummary(messages)
+	if s.Summarizer != nil {
+		var err error
+		summary, err = s.Summarizer.Summarize(ic.Ctx, messages)
+		if err != nil {
+			return fmt.Errorf("no se pudo generar el resumen: %w", err)
+		}
+	}
+
+	return ic.Respond("", &discordgo.MessageEmbed{
+		Title:       "Resumen de " + chat,
+		Description: truncate(summary, 4000),
+		Color:       colorInfo,
+	})
+}
+
+func (s Services) handleSearch(ic *InteractionContext) error {
+	if s.Tickets == nil {
+		return fmt.Errorf("Jira no está configurado")
+	}
+
+	query := types.TicketQuery{
+		Text:       ic.Options.StringOr("texto", ""),
+		Customer:   ic.Options.StringOr("cliente", ""),
+		Chat:       ic.Options.StringOr("chat", ""),
+		ProjectKey: ic.Options.StringOr("proyecto", ""),
+	}
+	query.OpenOnly, _ = ic.Options.Bool("abiertos")
+	if query.Text == "" && query.Customer == "" && query.Chat == "" {
+		query.Chat, _ = s.Bridge.ChatFor(ic.Ctx, ic.Interaction.ChannelID)
+		if query.Chat == "" && query.ProjectKey == "" {
+			return fmt.Errorf("indique texto, cliente, chat o proyecto, o use /search en un canal del puente")
+		}
+	}
+
+	tickets, err := s.Tickets.SearchTickets(ic.Ctx, query, searchLimit)
+	if err != nil {
+		return fmt.Errorf("no se pudo buscar: %w", err)
+	}
+	title := searchTitle(query)
+	if len(tickets) == 0 {
+		return ic.Respond(fmt.Sprintf("No hay tickets para %s.", title))
+	}
+
+	var sb strings.Builder
+	for _, t := range tickets {
+		sb.WriteString(fmt.Sprintf("[%s](%s) · %s · %s\n", t.Key, t.URL, t.Status, truncate(t.Summary, 80)))
+	}
+
+	return ic.Respond("", &discordgo.MessageEmbed{
+		Title:       "Resultados para " + title,
+		Description: sb.String(),
+		Color:       colorInfo,
+	})
+}
+
+// searchTitle describe los criterios de la búsqueda
+func searchTitle(q types.TicketQuery) string {
+	var parts []string
+	if q.Text != "" {
+		parts = append(parts, fmt.Sprintf("%q", q.Text))
+	}
+	if q.Customer != "" {
+		parts = append(parts, "cliente "+q.Customer)
+	}
+	if q.Chat != "" {
+		parts = append(parts, "chat "+q.Chat)
+	}
+	if q.ProjectKey != "" {
+		parts = append(parts, "proyecto "+strings.ToUpper(q.ProjectKey))
+	}
+	title := strings.Join(parts, ", ")
+	if q.OpenOnly {
+		title += " (abiertos)"
+	}
+	return title
+}
+
+// helpEmbed genera la ayuda a partir del registro de comandos
+func helpEmbed(r *CommandRegistry) *discordgo.MessageEmbed {
+	var sb strings.Builder
+	for _, cmd := range r.Commands() {
+		def := cmd.Definition
+		if def.Type != discordgo.ChatApplicationCommand {
+			continue
+		}
+
+		usage := "/" + def.Name
+		for _, opt := range def.Options {
+			if opt.Required {
+				usage += fmt.Sprintf(" <%s>", opt.Name)
+			} else {
+				usage += fmt.Sprintf(" [%s]", opt.Name)
+			}
+		}
+		sb.WriteString(fmt.Sprintf("`%s`\n%s\n\n", usage, def.Description))
+	}
+
+	return &discordgo.MessageEmbed{
+		Title:       "Comandos de Lisa",
+		Description: sb.String(),
+		Color:       colorInfo,
+	}
+}
+
+// ticketEmbed muestra los datos principales de un ticket
+func ticketEmbed(t *types.TicketInfo, color int) *discordgo.MessageEmbed {
+	assignee := t.Assignee
+	if assignee == "" {
+		assignee = "Sin asignar"
+	}
+
+	fields := []*discordgo.MessageEmbedField{
+		{Name: "Estado", Value: t.Status, Inline: true},
+		{Name: "Asignado", Value: assignee, Inline: true},
+	}
+	if t.Priority != "" {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Prioridad", Value: t.Priority, Inline: true})
+	}
+
+	return &discordgo.MessageEmbed{
+		Title:  fmt.Sprintf("%s: %s", t.Key, truncate(t.Summary, 200)),
+		URL:    t.URL,
+		Color:  color,
+		Fields: fields,
+	}
+}
+
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max-1]) + "…"
+}
@@ -0,0 +1,328 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// healthInterval cada cuánto se revisan las integraciones para la presencia
+	healthInterval = 30 * time.Second
+
+	// pingTimeout espera máxima de cada verificación
+	pingTimeout = 5 * time.Second
+)
+
+// Pinger servicio que puede comprobar su conexión (Jira, IA, base de datos)
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// HealthState estado de una integración
+type HealthState string
+
+const (
+	HealthOK       HealthState = "ok"
+	HealthDown     HealthState = "down"
+	HealthDisabled HealthState = "disabled"
+)
+
+// ComponentHealth resultado de la verificación de una integración
+type ComponentHealth struct {
+	Name    string
+	State   HealthState
+	Detail  string
+	Latency time.Duration
+
+	// Critical indica que sin esta integración Lisa no funciona
+	Critical bool
+}
+
+// HealthReport estado de todas las integraciones y colas
+type HealthReport struct {
+	Components []ComponentHealth
+	Queues     map[string]int
+	Uptime     time.Duration
+}
+
+// Down devuelve la primera integración crítica caída, o la primera caída si
+// ninguna crítica lo está
+func (r HealthReport) Down() (*ComponentHealth, bool) {
+	var first *ComponentHealth
+	for i := range r.Components {
+		c := &r.Components[i]
+		if c.State != HealthDown {
+			continue
+		}
+		if c.Critical {
+			return c, true
+		}
+		if first == nil {
+			first = c
+		}
+	}
+	return first, first != nil
+}
+
+// queueDepth cola cuya cantidad de trabajos pendientes se informa en /health
+type queueDepth struct {
+	name  string
+	depth func() int
+}
+
+// Health verifica las integraciones, refleja el estado general en la presencia
+// del bot y responde /health
+type Health struct {
+	bot      *Bot
+	services Services
+	started  time.Time
+
+	mu       sync.Mutex
+	queues   []queueDepth
+	presence string
+	since    time.Time
+}
+
+// NewHealth crea el monitor. Services.Database se usa para verificar la base de datos.
+func NewHealth(b *Bot, services Services) *Health {
+	return &Health{
+		bot:      b,
+		services: services,
+		started:  time.Now(),
+	}
+}
+
+// AddQueue agrega una cola a los reportes de /health
+func (h *Health) AddQueue(name string, depth func() int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.queues = append(h.queues, queueDepth{name: name, depth: depth})
+}
+
+// Register registra /health, visible solo para quienes administran el servidor
+func (h *Health) Register(r *CommandRegistry) error {
+	perm := int64(discordgo.PermissionManageServer)
+	return r.Register(SlashCommand{
+		Definition: &discordgo.ApplicationCommand{
+			Name:                     "health",
+			Description:              "Estado de WhatsApp, Jira, la IA y la base de datos",
+			DefaultMemberPermissions: &perm,
+		},
+		Handler:   h.handleHealth,
+		Defer:     true,
+		Ephemeral: true,
+	})
+}
+
+// Run actualiza la presencia del bot hasta que se cancele el contexto
+func (h *Health) Run(ctx context.Context) {
+	ticker := time.NewTicker(healthInterval)
+	defer ticker.Stop()
+
+	for {
+		h.updatePresence(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check verifica todas las integraciones
+func (h *Health) Check(ctx context.Context) HealthReport {
+	s := h.services
+	report := HealthReport{
+		Queues: make(map[string]int),
+		Uptime: time.Since(h.started),
+	}
+
+	wa := ComponentHealth{Name: "WhatsApp", State: HealthDisabled, Detail: "No configurado", Critical: true}
+	if s.WhatsApp != nil {
+		switch {
+		case s.WhatsApp.IsConnected():
+			wa.State, wa.Detail = HealthOK, s.WhatsApp.GetJID()
+		default:
+			wa.State, wa.Detail = HealthDown, string(s.WhatsApp.State())
+			if reason := s.WhatsApp.StateReason(); reason != "" {
+				wa.Detail += ": " + reason
+			}
+		}
+	}
+
+	dc := ComponentHealth{Name: "Discord", State: HealthOK, Critical: true}
+	status := h.bot.Status()
+	dc.Latency = status.Latency
+	dc.Detail = fmt.Sprintf("%s, %d reconexiones", status.User, status.Reconnects)
+	if !status.Ready {
+		dc.State, dc.Detail = HealthDown, "Desconectado del gateway"
+	}
+
+	var ai interface{}
+	switch {
+	case s.Classifier != nil:
+		ai = s.Classifier
+	case s.Drafter != nil:
+		ai = s.Drafter
+	case s.Summarizer != nil:
+		ai = s.Summarizer
+	}
+
+	report.Components = []ComponentHealth{
+		wa,
+		dc,
+		ping(ctx, "Jira", s.Tickets, false),
+		ping(ctx, "IA", ai, false),
+		ping(ctx, "Base de datos", s.Database, true),
+	}
+
+	h.mu.Lock()
+	for _, q := range h.queues {
+		report.Queues[q.name] = q.depth()
+	}
+	h.mu.Unlock()
+
+	return report
+}
+
+// ping verifica un servicio. Los servicios que no implementan Pinger se dan
+// por operativos si están configurados.
+func ping(ctx context.Context, name string, service interface{}, critical bool) ComponentHealth {
+	c := ComponentHealth{Name: name, Critical: critical}
+	if service == nil {
+		c.State, c.Detail = HealthDisabled, "No configurado"
+		return c
+	}
+	p, ok := service.(Pinger)
+	if !ok {
+		c.State, c.Detail = HealthOK, "Configurado"
+		return c
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := p.Ping(ctx)
+	c.Latency = time.Since(start)
+	if err != nil {
+		c.State, c.Detail = HealthDown, err.Error()
+		return c
+	}
+	c.State = HealthOK
+	return c
+}
+
+// updatePresence cambia la presencia según el estado general: en línea si
+// todo funciona, ausente si falla una integración secundaria y no molestar si
+// falla WhatsApp, Discord o la base de datos
+func (h *Health) updatePresence(ctx context.Context) {
+	if !h.bot.IsReady() {
+		return
+	}
+	report := h.Check(ctx)
+
+	status, text := string(discordgo.StatusOnline), "Todo operativo"
+	if c, down := report.Down(); down {
+		status = string(discordgo.StatusIdle)
+		if c.Critical {
+			status = string(discordgo.StatusDoNotDisturb)
+		}
+		text = c.Name + " desconectado"
+	}
+
+	// Discord borra la presencia al reconectar: se vuelve a enviar
+	since := h.bot.Status().Since
+	key := status + "|" + text
+
+	h.mu.Lock()
+	changed := key != h.presence || !since.Equal(h.since)
+	h.mu.Unlock()
+	if !changed {
+		return
+	}
+
+	err := h.bot.session.UpdateStatusComplex(discordgo.UpdateStatusData{
+		Status: status,
+		Activities: []*discordgo.Activity{{
+			Name:  "Estado",
+			Type:  discordgo.ActivityTypeCustom,
+			State: text,
+		}},
+	})
+	if err != nil {
+		log.Printf("DC: No se pudo actualizar la presencia: %v", err)
+		return
+	}
+
+	h.mu.Lock()
+	previous := h.presence
+	h.presence, h.since = key, since
+	h.mu.Unlock()
+	if previous != "" && previous != key {
+		log.Printf("DC: Presencia: %s", text)
+	}
+}
+
+func (h *Health) handleHealth(ic *InteractionContext) error {
+	report := h.Check(ic.Ctx)
+
+	color := colorSuccess
+	if c, down := report.Down(); down {
+		color = colorWarning
+		if c.Critical {
+			color = colorError
+		}
+	}
+
+	fields := make([]*discordgo.MessageEmbedField, 0, len(report.Components)+2)
+	for _, c := range report.Components {
+		value := healthIcon(c.State)
+		if c.Detail != "" {
+			value += " " + truncate(c.Detail, 200)
+		}
+		if c.Latency > 0 {
+			value += fmt.Sprintf("\n%d ms", c.Latency.Milliseconds())
+		}
+		fields = append(fields, &discordgo.MessageEmbedField{Name: c.Name, Value: value, Inline: true})
+	}
+
+	if len(report.Queues) > 0 {
+		var sb strings.Builder
+		h.mu.Lock()
+		for _, q := range h.queues {
+			sb.WriteString(fmt.Sprintf("%s: %d\n", q.name, report.Queues[q.name]))
+		}
+		h.mu.Unlock()
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Colas", Value: sb.String(), Inline: true})
+	}
+	fields = append(fields, &discordgo.MessageEmbedField{
+		Name:   "Activo desde hace",
+		Value:  report.Uptime.Truncate(time.Second).String(),
+		Inline: true,
+	})
+
+	return ic.Respond("", &discordgo.MessageEmbed{
+		Title:     "Estado de Lisa",
+		Color:     color,
+		Fields:    fields,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+func healthIcon(state HealthState) string {
+	switch state {
+	case HealthOK:
+		return "🟢"
+	case HealthDown:
+		return "🔴"
+	default:
+		return "⚪"
+	}
+}
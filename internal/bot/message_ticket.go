@@ -0,0 +1,169 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+
+	"Lisa/internal/whatsapp"
+	"Lisa/pkg/types"
+)
+
+const (
+	// messageTicketCommand nombre del comando de menú contextual de mensajes
+	messageTicketCommand = "Crear ticket de Jira"
+
+	// messageContextSize mensajes alrededor del elegido que se incluyen en el ticket
+	messageContextSize = 15
+)
+
+// fromMessage crea un ticket desde el menú contextual de un mensaje, con la
+// conversación que lo rodea y sus adjuntos. Sirve para mensajes del puente y
+// para mensajes escritos directamente en Discord.
+func (f *ticketForm) fromMessage(ic *InteractionContext) error {
+	s := f.services
+	if s.Tickets == nil {
+		return fmt.Errorf("Jira no está configurado")
+	}
+
+	target := ic.TargetMessage()
+	if target == nil {
+		return fmt.Errorf("no se encontró el mensaje elegido")
+	}
+
+	around, err := ic.Bot.session.ChannelMessages(target.ChannelID, messageContextSize, "", "", target.ID)
+	if err != nil {
+		log.Printf("DC: No se pudo leer la conversación de %s: %v", target.ID, err)
+		around = []*discordgo.Message{target}
+	}
+	sort.Slice(around, func(i, j int) bool { return around[i].Timestamp.Before(around[j].Timestamp) })
+
+	messages := make([]types.MessageInfo, 0, len(around))
+	for _, m := range around {
+		messages = append(messages, discordMessageInfo(m))
+	}
+
+	link := fmt.Sprintf("https://discord.com/channels/%s/%s/%s", ic.Interaction.GuildID, target.ChannelID, target.ID)
+	draft := f.messageDraft(ic.Ctx, target, messages, link)
+	draft.Reporter = ic.User().Username
+
+	chat, bridged := s.Bridge.ChatFor(ic.Ctx, target.ChannelID)
+	if bridged {
+		draft.SourceChat = chat
+	}
+
+	ticket, err := s.Tickets.CreateTicket(ic.Ctx, draft)
+	if err != nil {
+		return fmt.Errorf("no se pudo crear el ticket: %w", err)
+	}
+	log.Printf("DC: Ticket %s creado por %s desde el mensaje %s", ticket.Key, ic.User(), target.ID)
+
+	var notes []string
+	for _, att := range target.Attachments {
+		media, err := downloadAttachment(ic.Ctx, ic.Bot.session, att)
+		if err == nil {
+			err = s.Tickets.AddAttachment(ic.Ctx, ticket.Key, media)
+		}
+		if err != nil {
+			log.Printf("DC: Adjunto %s de %s: %v", att.Filename, ticket.Key, err)
+			notes = append(notes, fmt.Sprintf("No se pudo adjuntar %s: %v", att.Filename, err))
+		}
+	}
+
+	if bridged {
+		f.linkBridged(ic.Ctx, ticket, chat, around)
+	}
+
+	return ic.Respond(strings.Join(notes, "\n"), ticketEmbed(ticket, colorSuccess))
+}
+
+// messageDraft arma el ticket: el borrador de la IA si hay, o el mensaje
+// elegido como resumen. La transcripción y el enlace al mensaje van siempre.
+func (f *ticketForm) messageDraft(ctx context.Context, target *discordgo.Message, messages []types.MessageInfo, link string) types.TicketDraft {
+	var draft types.TicketDraft
+	if f.services.Drafter != nil {
+		d, err := f.services.Drafter.DraftTicket(ctx, messages)
+		if err != nil {
+			log.Printf("DC: Borrador de IA no disponible, se usa el mensaje: %v", err)
+		} else if d != nil {
+			draft = *d
+		}
+	}
+
+	if draft.Summary == "" {
+		draft.Summary = truncate(strings.Join(strings.Fields(target.Content), " "), 120)
+	}
+	if draft.Summary == "" {
+		draft.Summary = "Mensaje de " + messageAuthor(target)
+	}
+
+	var sb strings.Builder
+	if draft.Description != "" {
+		sb.WriteString(draft.Description + "\n\n")
+	}
+	sb.WriteString(fmt.Sprintf("Mensaje de %s:\n%s\n\n", messageAuthor(target), target.Content))
+	sb.WriteString("Conversación:\n" + whatsapp.Transcript(messages))
+	sb.WriteString("\n\nMensaje en Discord: " + link)
+	draft.Description = sb.String()
+
+	return draft
+}
+
+// linkBridged vincula el ticket con los mensajes de WhatsApp de la
+// conversación y con el hilo del puente
+func (f *ticketForm) linkBridged(ctx context.Context, ticket *types.TicketInfo, chat string, around []*discordgo.Message) {
+	if f.services.Links != nil {
+		var ids []string
+		for _, m := range around {
+			bm, err := f.services.Bridge.MessageFor(ctx, m.ID)
+			if err != nil {
+				log.Printf("DC: Puente: %v", err)
+				continue
+			}
+			if bm != nil {
+				ids = append(ids, bm.WAMessageID)
+			}
+		}
+		if len(ids) > 0 {
+			if err := f.services.Links.SaveTicketLinks(ctx, ticket.Key, chat, ids); err != nil {
+				log.Printf("DC: %v", err)
+			}
+		}
+	}
+
+	if err := f.services.Bridge.LinkTicket(ctx, chat, ticket.Key); err != nil {
+		log.Printf("DC: Puente: %v", err)
+	}
+}
+
+// discordMessageInfo adapta un mensaje de Discord para transcripciones y la IA.
+// En los mensajes del puente el autor es el webhook con el nombre del contacto.
+func discordMessageInfo(m *discordgo.Message) types.MessageInfo {
+	text := m.Content
+	for _, att := range m.Attachments {
+		text = joinLines(text, fmt.Sprintf("[adjunto: %s]", att.Filename))
+	}
+	info := types.MessageInfo{
+		ID:        m.ID,
+		From:      m.ChannelID,
+		PushName:  messageAuthor(m),
+		Text:      text,
+		Timestamp: m.Timestamp,
+		Source:    types.SourceDiscord,
+	}
+	if m.Author != nil {
+		info.Sender = m.Author.ID
+	}
+	return info
+}
+
+func messageAuthor(m *discordgo.Message) string {
+	if m.Author == nil {
+		return "desconocido"
+	}
+	return agentName(m)
+}
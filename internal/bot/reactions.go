@@ -0,0 +1,399 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"Lisa/internal/database"
+	"Lisa/pkg/types"
+)
+
+// Acciones de triaje por reacción
+const (
+	ReactionBug      = "bug"
+	ReactionPriority = "priority"
+	ReactionAssign   = "assign"
+	ReactionResolve  = "resolve"
+)
+
+const (
+	// reactionTimeout espera máxima de una acción sobre Jira
+	reactionTimeout = 30 * time.Second
+
+	// reactionUndoTTL tiempo durante el que quitar la reacción deshace la acción
+	reactionUndoTTL = 24 * time.Hour
+
+	// resolveTransition transición que aplica ✅; Jira la resuelve por alias
+	resolveTransition = "resolve"
+)
+
+// priorityLadder prioridades de Jira de menor a mayor
+var priorityLadder = []string{"Lowest", "Low", "Medium", "High", "Highest"}
+
+// ReactionStore vínculos entre tickets y mensajes de WhatsApp
+type ReactionStore interface {
+	SaveTicketLinks(ctx context.Context, ticketKey, chat string, messageIDs []string) error
+	TicketKeyForMessage(ctx context.Context, chat, messageID string) (string, error)
+	TicketLinksForChat(ctx context.Context, chat string) ([]database.TicketLink, error)
+}
+
+// reactionRecord acción aplicada por una reacción, para poder deshacerla
+type reactionRecord struct {
+	ticketKey string
+	undo      func(ctx context.Context) error
+	channelID string
+	confirmID string
+	expires   time.Time
+}
+
+// Reactions aplica en Jira las reacciones de los agentes sobre los mensajes
+// del puente: 🐛 crea un bug, ⚡ sube la prioridad del ticket vinculado, 👀 lo
+// asigna a quien reacciona y ✅ lo resuelve. Quitar la reacción deshace la
+// acción cuando Jira lo permite.
+type Reactions struct {
+	bot       *Bot
+	services  Services
+	store     ReactionStore
+	actions   map[string]string
+	roles     map[string]bool
+	jiraUsers map[string]string
+
+	mu   sync.Mutex
+	done map[string]*reactionRecord
+}
+
+// NewReactions crea el triaje por reacciones con DISCORD_REACTION_ACTIONS,
+// DISCORD_TRIAGE_ROLES y DISCORD_JIRA_USERS
+func NewReactions(b *Bot, services Services, store ReactionStore) (*Reactions, error) {
+	if services.Bridge == nil {
+		return nil, fmt.Errorf("el triaje por reacciones necesita el puente")
+	}
+	if services.Tickets == nil || services.Workflow == nil {
+		return nil, fmt.Errorf("el triaje por reacciones necesita Jira")
+	}
+
+	actions := make(map[string]string, len(b.cfg.ReactionActions))
+	for emoji, action := range b.cfg.ReactionActions {
+		switch action {
+		case ReactionBug, ReactionPriority, ReactionAssign, ReactionResolve:
+			actions[normalizeEmoji(emoji)] = action
+		default:
+			return nil, fmt.Errorf("acción de reacción desconocida para %s: %q", emoji, action)
+		}
+	}
+
+	roles := make(map[string]bool, len(b.cfg.TriageRoles))
+	for _, role := range b.cfg.TriageRoles {
+		roles[role] = true
+	}
+
+	rt := &Reactions{
+		bot:       b,
+		services:  services,
+		store:     store,
+		actions:   actions,
+		roles:     roles,
+		jiraUsers: b.cfg.JiraUsers,
+		done:      make(map[string]*reactionRecord),
+	}
+	b.session.AddHandler(rt.onReactionAdd)
+	b.session.AddHandler(rt.onReactionRemove)
+	return rt, nil
+}
+
+func (rt *Reactions) onReactionAdd(s *discordgo.Session, r *discordgo.MessageReactionAdd) {
+	action, ok := rt.actions[normalizeEmoji(r.Emoji.Name)]
+	if !ok || r.UserID == s.State.User.ID {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), reactionTimeout)
+		defer cancel()
+
+		bm, err := rt.services.Bridge.MessageFor(ctx, r.MessageID)
+		if err != nil {
+			log.Printf("DC: Triaje: %v", err)
+			return
+		}
+		if bm == nil {
+			// No es un mensaje del puente
+			return
+		}
+		if !rt.allowed(r.MessageReaction, r.Member) {
+			rt.reply(r.ChannelID, r.MessageID, r.UserID, "No tiene permiso para triar con reacciones.", true)
+			return
+		}
+
+		record, text, err := rt.apply(ctx, action, r, bm)
+		if err != nil {
+			log.Printf("DC: Triaje: %s sobre %s: %v", action, r.MessageID, err)
+			rt.reply(r.ChannelID, r.MessageID, r.UserID, fmt.Sprintf("No se pudo aplicar %s: %v", r.Emoji.Name, err), true)
+			return
+		}
+		log.Printf("DC: Triaje: %s aplicado a %s por %s", action, record.ticketKey, r.UserID)
+
+		if confirm := rt.reply(r.ChannelID, r.MessageID, r.UserID, text, false); confirm != nil {
+			record.channelID, record.confirmID = confirm.ChannelID, confirm.ID
+		}
+		record.expires = time.Now().Add(reactionUndoTTL)
+
+		rt.mu.Lock()
+		now := time.Now()
+		for k, rec := range rt.done {
+			if now.After(rec.expires) {
+				delete(rt.done, k)
+			}
+		}
+		rt.done[reactionKey(r.MessageID, r.UserID, action)] = record
+		rt.mu.Unlock()
+	}()
+}
+
+func (rt *Reactions) onReactionRemove(s *discordgo.Session, r *discordgo.MessageReactionRemove) {
+	action, ok := rt.actions[normalizeEmoji(r.Emoji.Name)]
+	if !ok {
+		return
+	}
+
+	key := reactionKey(r.MessageID, r.UserID, action)
+	rt.mu.Lock()
+	record, ok := rt.done[key]
+	delete(rt.done, key)
+	rt.mu.Unlock()
+	if !ok || time.Now().After(record.expires) {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), reactionTimeout)
+		defer cancel()
+
+		result := "deshecho"
+		if record.undo == nil {
+			result = "no se puede deshacer desde Discord"
+		} else if err := record.undo(ctx); err != nil {
+			log.Printf("DC: Triaje: no se pudo deshacer %s sobre %s: %v", action, record.ticketKey, err)
+			result = fmt.Sprintf("no se pudo deshacer: %v", err)
+		} else {
+			log.Printf("DC: Triaje: %s deshecho en %s por %s", action, record.ticketKey, r.UserID)
+		}
+
+		if record.confirmID == "" {
+			return
+		}
+		msg, err := s.ChannelMessage(record.channelID, record.confirmID)
+		if err != nil {
+			return
+		}
+		content := fmt.Sprintf("~~%s~~\n_%s (<@%s> quitó la reacción)_", msg.Content, result, r.UserID)
+		if _, err := s.ChannelMessageEdit(record.channelID, record.confirmID, truncate(content, discordMessageLimit)); err != nil {
+			log.Printf("DC: Triaje: no se pudo actualizar la confirmación: %v", err)
+		}
+	}()
+}
+
+// apply ejecuta la acción y devuelve cómo deshacerla y el texto de confirmación
+func (rt *Reactions) apply(ctx context.Context, action string, r *discordgo.MessageReactionAdd, bm *database.BridgeMessage) (*reactionRecord, string, error) {
+	tickets, workflow := rt.services.Tickets, rt.services.Workflow
+
+	if action == ReactionBug {
+		return rt.createBug(ctx, r, bm)
+	}
+
+	key, err := rt.linkedTicket(ctx, bm)
+	if err != nil {
+		return nil, "", err
+	}
+	if key == "" {
+		return nil, "", fmt.Errorf("el mensaje no tiene un ticket vinculado (reaccione con 🐛 para crearlo)")
+	}
+	before, err := tickets.GetTicket(ctx, key)
+	if err != nil {
+		return nil, "", err
+	}
+
+	record := &reactionRecord{ticketKey: key}
+	switch action {
+	case ReactionPriority:
+		next, ok := higherPriority(before.Priority)
+		if !ok {
+			return nil, "", fmt.Errorf("%s ya tiene la prioridad máxima (%s)", key, before.Priority)
+		}
+		ticket, err := workflow.SetPriority(ctx, key, next)
+		if err != nil {
+			return nil, "", err
+		}
+		record.undo = func(ctx context.Context) error {
+			_, err := workflow.SetPriority(ctx, key, before.Priority)
+			return err
+		}
+		return record, fmt.Sprintf("⚡ Prioridad de [%s](<%s>): %s → %s (<@%s>)",
+			key, ticket.URL, before.Priority, ticket.Priority, r.UserID), nil
+
+	case ReactionAssign:
+		accountID, ok := rt.jiraUsers[r.UserID]
+		if !ok {
+			return nil, "", fmt.Errorf("su usuario de Discord no tiene cuenta de Jira asociada en DISCORD_JIRA_USERS")
+		}
+		ticket, err := workflow.AssignTicket(ctx, key, accountID)
+		if err != nil {
+			return nil, "", err
+		}
+		record.undo = func(ctx context.Context) error {
+			_, err := workflow.AssignTicket(ctx, key, before.AssigneeID)
+			return err
+		}
+		return record, fmt.Sprintf("👀 [%s](<%s>) asignado a <@%s>", key, ticket.URL, r.UserID), nil
+
+	case ReactionResolve:
+		ticket, err := workflow.TransitionTicket(ctx, key, resolveTransition)
+		if err != nil {
+			return nil, "", err
+		}
+		record.undo = func(ctx context.Context) error {
+			_, err := workflow.TransitionTicket(ctx, key, before.Status)
+			return err
+		}
+		return record, fmt.Sprintf("✅ [%s](<%s>): %s → %s (<@%s>)",
+			key, ticket.URL, before.Status, ticket.Status, r.UserID), nil
+	}
+	return nil, "", fmt.Errorf("acción desconocida: %s", action)
+}
+
+// createBug crea un bug con el mensaje y lo vincula con el chat. Jira no
+// permite deshacerlo desde Discord: el ticket se cierra a mano.
+func (rt *Reactions) createBug(ctx context.Context, r *discordgo.MessageReactionAdd, bm *database.BridgeMessage) (*reactionRecord, string, error) {
+	link := fmt.Sprintf("https://discord.com/channels/%s/%s/%s", r.GuildID, r.ChannelID, r.MessageID)
+	summary := truncate(strings.Join(strings.Fields(bm.WAText), " "), 120)
+	if summary == "" {
+		summary = "Bug reportado por WhatsApp"
+	}
+
+	reporter := r.UserID
+	if r.Member != nil && r.Member.User != nil {
+		reporter = r.Member.User.Username
+	}
+
+	ticket, err := rt.services.Tickets.CreateTicket(ctx, types.TicketDraft{
+		Summary:     summary,
+		Description: fmt.Sprintf("Mensaje de WhatsApp (%s):\n%s\n\nMensaje en Discord: %s", bm.WAChat, bm.WAText, link),
+		IssueType:   "Bug",
+		Reporter:    reporter,
+		SourceChat:  bm.WAChat,
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("no se pudo crear el ticket: %w", err)
+	}
+
+	if rt.store != nil {
+		if err := rt.store.SaveTicketLinks(ctx, ticket.Key, bm.WAChat, []string{bm.WAMessageID}); err != nil {
+			log.Printf("DC: Triaje: %v", err)
+		}
+	}
+	if err := rt.services.Bridge.LinkTicket(ctx, bm.WAChat, ticket.Key); err != nil {
+		log.Printf("DC: Puente: %v", err)
+	}
+	attachConversationMedia(ctx, rt.services, ticket.Key, bm.WAChat, []string{bm.WAMessageID})
+
+	return &reactionRecord{ticketKey: ticket.Key},
+		fmt.Sprintf("🐛 Bug [%s](<%s>) creado por <@%s>", ticket.Key, ticket.URL, r.UserID), nil
+}
+
+// linkedTicket ticket del mensaje: el vinculado al propio mensaje, el del
+// hilo del chat o el último creado desde ese chat
+func (rt *Reactions) linkedTicket(ctx context.Context, bm *database.BridgeMessage) (string, error) {
+	if rt.store != nil {
+		key, err := rt.store.TicketKeyForMessage(ctx, bm.WAChat, bm.WAMessageID)
+		if err != nil || key != "" {
+			return key, err
+		}
+	}
+	if key := rt.services.Bridge.TicketFor(ctx, bm.WAChat); key != "" {
+		return key, nil
+	}
+	if rt.store != nil {
+		links, err := rt.store.TicketLinksForChat(ctx, bm.WAChat)
+		if err != nil || len(links) == 0 {
+			return "", err
+		}
+		return links[0].TicketKey, nil
+	}
+	return "", nil
+}
+
+// allowed verifica que quien reacciona tenga uno de los roles de triaje o,
+// si no hay roles configurados, el permiso de gestionar mensajes en el canal
+func (rt *Reactions) allowed(r *discordgo.MessageReaction, member *discordgo.Member) bool {
+	if len(rt.roles) > 0 {
+		if member == nil {
+			return false
+		}
+		for _, role := range member.Roles {
+			if rt.roles[role] {
+				return true
+			}
+		}
+		return false
+	}
+
+	perms, err := rt.bot.session.UserChannelPermissions(r.UserID, r.ChannelID)
+	if err != nil {
+		log.Printf("DC: Triaje: no se pudieron leer los permisos de %s: %v", r.UserID, err)
+		return false
+	}
+	return perms&discordgo.PermissionManageMessages != 0
+}
+
+// reply responde al mensaje del puente. Los errores se borran solos pasado noticeTTL.
+func (rt *Reactions) reply(channelID, messageID, userID, text string, ephemeral bool) *discordgo.Message {
+	s := rt.bot.session
+	if ephemeral {
+		text = fmt.Sprintf("<@%s> %s", userID, text)
+	}
+	sent, err := s.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Content:         text,
+		Reference:       &discordgo.MessageReference{MessageID: messageID, ChannelID: channelID},
+		AllowedMentions: &discordgo.MessageAllowedMentions{Users: []string{userID}},
+	})
+	if err != nil {
+		log.Printf("DC: Triaje: no se pudo responder en %s: %v", channelID, err)
+		return nil
+	}
+	if ephemeral {
+		time.AfterFunc(noticeTTL, func() {
+			s.ChannelMessageDelete(sent.ChannelID, sent.ID)
+		})
+	}
+	return sent
+}
+
+// higherPriority prioridad siguiente en la escala de Jira
+func higherPriority(current string) (string, bool) {
+	for i, p := range priorityLadder {
+		if strings.EqualFold(p, current) {
+			if i == len(priorityLadder)-1 {
+				return "", false
+			}
+			return priorityLadder[i+1], true
+		}
+	}
+	// Prioridad desconocida o vacía: se sube a la alta
+	return "High", true
+}
+
+func reactionKey(messageID, userID, action string) string {
+	return messageID + ":" + userID + ":" + action
+}
+
+// normalizeEmoji quita el selector de variación, que algunos clientes agregan
+// (✅ y ✅️ son la misma reacción)
+func normalizeEmoji(emoji string) string {
+	return strings.TrimSuffix(emoji, "️")
+}
@@ -0,0 +1,196 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"Lisa/internal/database"
+	"Lisa/internal/whatsapp"
+	"Lisa/pkg/types"
+)
+
+const (
+	// archiveQueueSize mensajes que esperan ser archivados como máximo
+	archiveQueueSize = 256
+
+	archiveTimeout = 10 * time.Second
+)
+
+// Ingestor recibe los mensajes normalizados de una fuente (el archivo y el triaje)
+type Ingestor interface {
+	Ingest(msg types.MessageInfo, projectKey string)
+}
+
+// ArchiveStore persistencia del archivo de mensajes de las fuentes
+type ArchiveStore interface {
+	SaveSourceMessage(ctx context.Context, msg *database.SourceMessage) error
+}
+
+// MessageArchive guarda los mensajes de las fuentes en el historial del
+// cliente de WhatsApp, para que /summary y los tickets tengan la conversación
+// como en un chat, y en la base de datos. El guardado en la base no bloquea
+// al handler de Discord.
+type MessageArchive struct {
+	history *whatsapp.History
+	store   ArchiveStore
+	queue   chan *database.SourceMessage
+	pending atomic.Int64
+}
+
+// NewMessageArchive crea el archivo y arranca el guardado en la base. history
+// puede ser nil.
+func NewMessageArchive(history *whatsapp.History, store ArchiveStore) *MessageArchive {
+	a := &MessageArchive{
+		history: history,
+		store:   store,
+		queue:   make(chan *database.SourceMessage, archiveQueueSize),
+	}
+	go a.run()
+	return a
+}
+
+// Ingest archiva el mensaje. Si la cola está llena se descarta el guardado en
+// la base, pero el mensaje queda en el historial.
+func (a *MessageArchive) Ingest(msg types.MessageInfo, projectKey string) {
+	if a.history != nil {
+		a.history.Add(msg)
+	}
+
+	select {
+	case a.queue <- &database.SourceMessage{
+		Source:     msg.Source,
+		Chat:       msg.From,
+		MessageID:  msg.ID,
+		Sender:     msg.Sender,
+		SenderName: msg.PushName,
+		Text:       msg.Text,
+		ProjectKey: projectKey,
+		SentAt:     msg.Timestamp,
+	}:
+		a.pending.Add(1)
+	default:
+		log.Printf("DB: Cola del archivo llena, no se guardó el mensaje %s", msg.ID)
+	}
+}
+
+// Pending cantidad de mensajes esperando ser guardados
+func (a *MessageArchive) Pending() int {
+	return int(a.pending.Load())
+}
+
+func (a *MessageArchive) run() {
+	for msg := range a.queue {
+		ctx, cancel := context.WithTimeout(context.Background(), archiveTimeout)
+		if err := a.store.SaveSourceMessage(ctx, msg); err != nil {
+			log.Printf("DB: %v", err)
+		}
+		cancel()
+		a.pending.Add(-1)
+	}
+}
+
+// DiscordSource monitorea canales y foros de Discord donde reportan clientes
+// y pasa sus mensajes al archivo y al mismo triaje que los de WhatsApp, con
+// el proyecto de Jira de cada canal
+type DiscordSource struct {
+	bot      *Bot
+	projects map[string]string // canal o foro -> proyecto
+	sinks    []Ingestor
+
+	mu      sync.Mutex
+	parents map[string]string // hilo -> canal padre
+}
+
+// NewDiscordSource crea la fuente con DISCORD_SOURCE_CHANNELS
+func NewDiscordSource(b *Bot, sinks ...Ingestor) (*DiscordSource, error) {
+	if len(b.cfg.SourceChannels) == 0 {
+		return nil, fmt.Errorf("no hay canales de Discord para monitorear")
+	}
+	// BridgeChannels va de chat de WhatsApp a canal: se comparan los canales
+	bridged := make(map[string]bool, len(b.cfg.BridgeChannels)+1)
+	for _, channelID := range b.cfg.BridgeChannels {
+		bridged[channelID] = true
+	}
+	if b.cfg.ThreadChannelID != "" {
+		bridged[b.cfg.ThreadChannelID] = true
+	}
+	for channelID := range b.cfg.SourceChannels {
+		if bridged[channelID] {
+			return nil, fmt.Errorf("el canal %s es del puente y no puede monitorearse como fuente", channelID)
+		}
+	}
+
+	src := &DiscordSource{
+		bot:      b,
+		projects: b.cfg.SourceChannels,
+		sinks:    sinks,
+		parents:  make(map[string]string),
+	}
+	b.session.AddHandler(src.onMessage)
+	return src, nil
+}
+
+func (src *DiscordSource) onMessage(s *discordgo.Session, m *discordgo.MessageCreate) {
+	if m.Author == nil || m.Author.Bot || m.WebhookID != "" {
+		return
+	}
+	if m.Type != discordgo.MessageTypeDefault && m.Type != discordgo.MessageTypeReply {
+		return
+	}
+
+	channelID, ok := src.monitored(m.ChannelID)
+	if !ok {
+		return
+	}
+
+	msg := discordMessageInfo(m.Message)
+	msg.IsGroup = true
+	msg.GroupName = "<#" + m.ChannelID + ">"
+	if m.MessageReference != nil && m.ReferencedMessage != nil {
+		msg.QuotedID = m.ReferencedMessage.ID
+		msg.QuotedText = m.ReferencedMessage.Content
+	}
+
+	for _, sink := range src.sinks {
+		sink.Ingest(msg, src.projects[channelID])
+	}
+}
+
+// monitored devuelve el canal configurado del mensaje: el propio canal o, en
+// hilos y publicaciones de foro, el canal padre
+func (src *DiscordSource) monitored(channelID string) (string, bool) {
+	if _, ok := src.projects[channelID]; ok {
+		return channelID, true
+	}
+
+	src.mu.Lock()
+	parent, cached := src.parents[channelID]
+	src.mu.Unlock()
+
+	if !cached {
+		ch, err := src.bot.session.State.Channel(channelID)
+		if err != nil {
+			if ch, err = src.bot.session.Channel(channelID); err != nil {
+				log.Printf("DC: Fuente: no se pudo leer el canal %s: %v", channelID, err)
+				return "", false
+			}
+		}
+		if ch.IsThread() {
+			parent = ch.ParentID
+		}
+		src.mu.Lock()
+		src.parents[channelID] = parent
+		src.mu.Unlock()
+	}
+
+	if _, ok := src.projects[parent]; ok && parent != "" {
+		return parent, true
+	}
+	return "", false
+}
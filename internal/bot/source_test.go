@@ -0,0 +1,109 @@
+package bot
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"Lisa/internal/config"
+	"Lisa/internal/database"
+	"Lisa/internal/whatsapp"
+	"Lisa/pkg/types"
+)
+
+// memoryArchive ArchiveStore en memoria
+type memoryArchive struct {
+	mu       sync.Mutex
+	messages []database.SourceMessage
+}
+
+func (m *memoryArchive) SaveSourceMessage(ctx context.Context, msg *database.SourceMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, *msg)
+	return nil
+}
+
+func (m *memoryArchive) saved() []database.SourceMessage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]database.SourceMessage(nil), m.messages...)
+}
+
+func TestMessageArchive(t *testing.T) {
+	history := whatsapp.NewHistory(10)
+	store := &memoryArchive{}
+	archive := NewMessageArchive(history, store)
+
+	msg := types.MessageInfo{
+		ID:        "m1",
+		From:      "c1",
+		Sender:    "u1",
+		PushName:  "Ana",
+		Text:      "No carga el panel",
+		Timestamp: time.Now(),
+		Source:    types.SourceDiscord,
+	}
+	archive.Ingest(msg, "WEB")
+
+	if got := history.Recent("c1", 10); len(got) != 1 || got[0].ID != "m1" {
+		t.Errorf("historial = %+v, se esperaba el mensaje m1", got)
+	}
+	waitFor(t, "el guardado en la base", func() bool { return archive.Pending() == 0 && len(store.saved()) == 1 })
+
+	saved := store.saved()[0]
+	if saved.Chat != "c1" || saved.MessageID != "m1" || saved.ProjectKey != "WEB" || saved.Source != types.SourceDiscord {
+		t.Errorf("mensaje archivado = %+v", saved)
+	}
+}
+
+func TestNewDiscordSourceRejectsBridgeChannels(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.DiscordConfig
+		wantErr bool
+	}{
+		{
+			name:    "sin canales",
+			cfg:     config.DiscordConfig{},
+			wantErr: true,
+		},
+		{
+			name: "canal del puente",
+			cfg: config.DiscordConfig{
+				BridgeChannels: map[string]string{"5491100000000@s.whatsapp.net": "c1"},
+				SourceChannels: map[string]string{"c1": "WEB"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "canal de hilos del puente",
+			cfg: config.DiscordConfig{
+				ThreadChannelID: "c1",
+				SourceChannels:  map[string]string{"c1": ""},
+			},
+			wantErr: true,
+		},
+		{
+			name: "canal propio",
+			cfg: config.DiscordConfig{
+				BridgeChannels: map[string]string{"c1": "c2"},
+				SourceChannels: map[string]string{"c1": "WEB"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Token = "token"
+			b, err := New(tt.cfg)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			_, err = NewDiscordSource(b)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewDiscordSource() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
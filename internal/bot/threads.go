@@ -0,0 +1,266 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"Lisa/internal/database"
+	"Lisa/internal/whatsapp"
+)
+
+// threadJanitorInterval cada cuánto se buscan hilos inactivos para archivar
+const threadJanitorInterval = 5 * time.Minute
+
+// archiveDurations duraciones de archivo automático que acepta Discord, en minutos
+var archiveDurations = []int{60, 1440, 4320, 10080}
+
+// ThreadStore persistencia de la asignación chat -> hilo
+type ThreadStore interface {
+	SaveBridgeThread(ctx context.Context, thread *database.BridgeThread) error
+	BridgeThread(ctx context.Context, chat string) (*database.BridgeThread, error)
+	BridgeThreadByID(ctx context.Context, threadID string) (*database.BridgeThread, error)
+	IdleBridgeThreads(ctx context.Context, before time.Time) ([]database.BridgeThread, error)
+	TouchBridgeThread(ctx context.Context, chat string, archived bool) error
+	SetBridgeThreadArchived(ctx context.Context, chat string, archived bool) error
+	DeleteBridgeThread(ctx context.Context, chat string) error
+}
+
+// destination canal (y, si corresponde, hilo) donde se publica un chat
+type destination struct {
+	channelID string
+	threadID  string
+}
+
+// target es el ID que Discord usa para el mensaje: el hilo si hay uno
+func (d destination) target() string {
+	if d.threadID != "" {
+		return d.threadID
+	}
+	return d.channelID
+}
+
+// routes indica si los mensajes del chat pasan por el puente
+func (br *Bridge) routes(chat string) bool {
+	_, mapped := br.channels[chat]
+	return mapped || br.threadChannel != ""
+}
+
+// destinationFor resuelve dónde publicar un chat: su canal mapeado o su hilo
+// en DISCORD_BRIDGE_THREAD_CHANNEL_ID, creándolo o reabriéndolo si hace falta
+func (br *Bridge) destinationFor(ctx context.Context, evt whatsapp.Event) (destination, error) {
+	chat := evt.Chat.String()
+	if channelID, ok := br.channels[chat]; ok {
+		return destination{channelID: channelID}, nil
+	}
+
+	thread, err := br.store.BridgeThread(ctx, chat)
+	if err != nil {
+		return destination{}, err
+	}
+	if thread != nil && thread.Archived {
+		if err := br.setArchived(thread.ThreadID, false); err != nil {
+			if !isUnknownChannel(err) {
+				return destination{}, fmt.Errorf("no se pudo reabrir el hilo de %s: %w", chat, err)
+			}
+			// El hilo se borró en Discord: se crea uno nuevo
+			thread = nil
+		} else {
+			log.Printf("DC: Puente: hilo de %s reabierto", chat)
+		}
+	}
+	if thread == nil {
+		if thread, err = br.createThread(ctx, chat, threadName(evt)); err != nil {
+			return destination{}, err
+		}
+	}
+
+	return destination{channelID: thread.ChannelID, threadID: thread.ThreadID}, nil
+}
+
+// createThread abre el hilo del chat en el canal de hilos. En canales de foro
+// la publicación necesita un mensaje inicial.
+func (br *Bridge) createThread(ctx context.Context, chat, name string) (*database.BridgeThread, error) {
+	s := br.bot.session
+
+	parent, err := s.Channel(br.threadChannel)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo leer el canal de hilos %s: %w", br.threadChannel, err)
+	}
+
+	start := &discordgo.ThreadStart{
+		Name:                name,
+		AutoArchiveDuration: autoArchiveMinutes(br.archiveAfter),
+		Type:                discordgo.ChannelTypeGuildPublicThread,
+	}
+
+	var thread *discordgo.Channel
+	if parent.Type == discordgo.ChannelTypeGuildForum {
+		thread, err = s.ForumThreadStartComplex(br.threadChannel, start, &discordgo.MessageSend{
+			Content: fmt.Sprintf("Conversación de WhatsApp con **%s** (`%s`)", name, chat),
+		})
+	} else {
+		thread, err = s.ThreadStartComplex(br.threadChannel, start)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo crear el hilo de %s: %w", chat, err)
+	}
+
+	record := &database.BridgeThread{
+		WAChat:    chat,
+		ChannelID: br.threadChannel,
+		ThreadID:  thread.ID,
+		Name:      name,
+	}
+	if err := br.store.SaveBridgeThread(ctx, record); err != nil {
+		return nil, err
+	}
+
+	log.Printf("DC: Puente: hilo %q creado para %s", name, chat)
+	return record, nil
+}
+
+// LinkTicket asocia un ticket al hilo del chat y lo agrega al nombre del hilo
+func (br *Bridge) LinkTicket(ctx context.Context, chat, ticketKey string) error {
+	if br == nil || br.store == nil {
+		return nil
+	}
+	thread, err := br.store.BridgeThread(ctx, chat)
+	if err != nil || thread == nil {
+		return err
+	}
+
+	thread.TicketKey = ticketKey
+	name := truncate(fmt.Sprintf("[%s] %s", ticketKey, thread.Name), 100)
+	if _, err := br.bot.session.ChannelEditComplex(thread.ThreadID, &discordgo.ChannelEdit{Name: name}); err != nil {
+		log.Printf("DC: Puente: no se pudo renombrar el hilo %s: %v", thread.ThreadID, err)
+	}
+	return br.store.SaveBridgeThread(ctx, thread)
+}
+
+// ThreadFor devuelve el hilo de Discord de un chat de WhatsApp, si tiene
+func (br *Bridge) ThreadFor(ctx context.Context, chat string) (string, bool) {
+	if br == nil || br.store == nil {
+		return "", false
+	}
+	thread, err := br.store.BridgeThread(ctx, chat)
+	if err != nil || thread == nil {
+		return "", false
+	}
+	return thread.ThreadID, true
+}
+
+// TicketFor devuelve el ticket vinculado al hilo de un chat, o "" si no tiene
+func (br *Bridge) TicketFor(ctx context.Context, chat string) string {
+	if br == nil || br.store == nil {
+		return ""
+	}
+	thread, err := br.store.BridgeThread(ctx, chat)
+	if err != nil || thread == nil {
+		return ""
+	}
+	return thread.TicketKey
+}
+
+// archiveIdleThreads archiva periódicamente los hilos sin actividad
+func (br *Bridge) archiveIdleThreads() {
+	defer br.wg.Done()
+
+	ticker := time.NewTicker(threadJanitorInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-br.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		idle, err := br.store.IdleBridgeThreads(br.ctx, time.Now().Add(-br.archiveAfter))
+		if err != nil {
+			log.Printf("DC: Puente: %v", err)
+			continue
+		}
+		for _, thread := range idle {
+			err := br.setArchived(thread.ThreadID, true)
+			if err != nil && !isUnknownChannel(err) {
+				log.Printf("DC: Puente: no se pudo archivar el hilo %s: %v", thread.ThreadID, err)
+				continue
+			}
+			if err := br.store.SetBridgeThreadArchived(br.ctx, thread.WAChat, true); err != nil {
+				log.Printf("DC: Puente: %v", err)
+			}
+		}
+		if len(idle) > 0 {
+			log.Printf("DC: Puente: %d hilos inactivos archivados", len(idle))
+		}
+	}
+}
+
+// onThreadUpdate mantiene el estado de archivo cuando un agente archiva o
+// reabre un hilo del puente a mano
+func (br *Bridge) onThreadUpdate(s *discordgo.Session, t *discordgo.ThreadUpdate) {
+	if t.ThreadMetadata == nil || t.ParentID != br.threadChannel {
+		return
+	}
+	thread, err := br.store.BridgeThreadByID(br.ctx, t.ID)
+	if err != nil || thread == nil || thread.Archived == t.ThreadMetadata.Archived {
+		return
+	}
+	if err := br.store.SetBridgeThreadArchived(br.ctx, thread.WAChat, t.ThreadMetadata.Archived); err != nil {
+		log.Printf("DC: Puente: %v", err)
+	}
+}
+
+func (br *Bridge) setArchived(threadID string, archived bool) error {
+	_, err := br.bot.session.ChannelEditComplex(threadID, &discordgo.ChannelEdit{Archived: &archived})
+	return err
+}
+
+// threadName nombre del hilo: el grupo o el contacto con su número
+func threadName(evt whatsapp.Event) string {
+	name := evt.ChatName
+	if name == "" {
+		name = fmt.Sprintf("+%s", evt.Chat.User)
+		if evt.Message != nil && evt.Message.Info.PushName != "" {
+			name = fmt.Sprintf("%s (+%s)", evt.Message.Info.PushName, evt.Chat.User)
+		}
+	}
+	return truncate(name, 100)
+}
+
+// autoArchiveMinutes duración de archivo automático de Discord más cercana por
+// encima del período configurado. Es un respaldo: el archivo se hace antes
+// desde archiveIdleThreads. Un período 0 desactiva el archivo por inactividad.
+func autoArchiveMinutes(after time.Duration) int {
+	minutes := int(after.Minutes())
+	if after <= 0 {
+		minutes = archiveDurations[len(archiveDurations)-1]
+	}
+	for _, d := range archiveDurations {
+		if minutes <= d {
+			return d
+		}
+	}
+	return archiveDurations[len(archiveDurations)-1]
+}
+
+// isUnknownChannel indica si Discord respondió que el canal o hilo no existe.
+// Solo se mira el código de error: un 404 también puede ser un webhook borrado.
+func isUnknownChannel(err error) bool {
+	return isDiscordError(err, discordgo.ErrCodeUnknownChannel)
+}
+
+// isUnknownWebhook indica si Discord respondió que el webhook no existe
+func isUnknownWebhook(err error) bool {
+	return isDiscordError(err, discordgo.ErrCodeUnknownWebhook)
+}
+
+func isDiscordError(err error, code int) bool {
+	var restErr *discordgo.RESTError
+	return errors.As(err, &restErr) && restErr.Message != nil && restErr.Message.Code == code
+}
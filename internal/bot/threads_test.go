@@ -0,0 +1,44 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// restError error REST de Discord con el estado HTTP y el código indicados
+func restError(status, code int) error {
+	err := &discordgo.RESTError{Response: &http.Response{StatusCode: status}}
+	if code != 0 {
+		err.Message = &discordgo.APIErrorMessage{Code: code}
+	}
+	return fmt.Errorf("no se pudo publicar: %w", err)
+}
+
+func TestDiscordNotFoundErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		channel bool
+		webhook bool
+	}{
+		{"canal desconocido", restError(http.StatusNotFound, discordgo.ErrCodeUnknownChannel), true, false},
+		{"webhook desconocido", restError(http.StatusNotFound, discordgo.ErrCodeUnknownWebhook), false, true},
+		{"404 sin código", restError(http.StatusNotFound, 0), false, false},
+		{"sin permisos", restError(http.StatusForbidden, discordgo.ErrCodeMissingPermissions), false, false},
+		{"otro error", errors.New("timeout"), false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isUnknownChannel(tt.err); got != tt.channel {
+				t.Errorf("isUnknownChannel() = %v, se esperaba %v", got, tt.channel)
+			}
+			if got := isUnknownWebhook(tt.err); got != tt.webhook {
+				t.Errorf("isUnknownWebhook() = %v, se esperaba %v", got, tt.webhook)
+			}
+		})
+	}
+}
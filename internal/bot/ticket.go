@@ -0,0 +1,290 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"Lisa/internal/whatsapp"
+	"Lisa/pkg/types"
+)
+
+const (
+	// ticketPrefix prefijo del custom ID del formulario de /ticket
+	ticketPrefix = "ticket"
+
+	// ticketFormTTL tiempo que se guarda el borrador mientras el agente completa el formulario
+	ticketFormTTL = 15 * time.Minute
+
+	// ticketTranscriptSize mensajes del chat que se incluyen en el ticket
+	ticketTranscriptSize = 15
+
+	// draftTimeout espera máxima del borrador de la IA. El formulario debe
+	// abrirse dentro de los 3 segundos de la interacción y no se puede diferir.
+	draftTimeout = 2 * time.Second
+)
+
+// Drafter redacta un borrador de ticket a partir de una conversación (normalmente con IA)
+type Drafter interface {
+	DraftTicket(ctx context.Context, messages []types.MessageInfo) (*types.TicketDraft, error)
+}
+
+// LinkStore persistencia de los vínculos entre tickets y mensajes de WhatsApp
+type LinkStore interface {
+	SaveTicketLinks(ctx context.Context, ticketKey, chat string, messageIDs []string) error
+}
+
+// pendingTicket conversación de un formulario de /ticket abierto
+type pendingTicket struct {
+	chat     string
+	project  string
+	messages []types.MessageInfo
+	expires  time.Time
+}
+
+// ticketForm abre el formulario de /ticket y crea el ticket al enviarlo
+type ticketForm struct {
+	services Services
+
+	mu      sync.Mutex
+	pending map[string]pendingTicket
+}
+
+func newTicketForm(b *Bot, services Services) *ticketForm {
+	f := &ticketForm{
+		services: services,
+		pending:  make(map[string]pendingTicket),
+	}
+	b.HandleComponent(ticketPrefix, f.submit)
+	return f
+}
+
+// open muestra el formulario con el borrador del ticket. El chat se toma de la
+// opción o, si se usa dentro de un canal o hilo del puente, de ese chat.
+func (f *ticketForm) open(ic *InteractionContext) error {
+	s := f.services
+	if s.Tickets == nil {
+		return fmt.Errorf("Jira no está configurado")
+	}
+
+	chat := ic.Options.StringOr("chat", "")
+	if chat == "" {
+		chat, _ = s.Bridge.ChatFor(ic.Ctx, ic.Interaction.ChannelID)
+	}
+
+	var messages []types.MessageInfo
+	if chat != "" && s.WhatsApp != nil {
+		messages = s.WhatsApp.History().Recent(chat, ticketTranscriptSize)
+	}
+	draft := f.draft(ic.Ctx, messages)
+
+	f.mu.Lock()
+	now := time.Now()
+	for id, p := range f.pending {
+		if now.After(p.expires) {
+			delete(f.pending, id)
+		}
+	}
+	f.pending[ic.Interaction.ID] = pendingTicket{
+		chat:     chat,
+		project:  strings.ToUpper(ic.Options.StringOr("proyecto", draft.ProjectKey)),
+		messages: messages,
+		expires:  now.Add(ticketFormTTL),
+	}
+	f.mu.Unlock()
+
+	component := ""
+	if len(draft.Components) > 0 {
+		component = draft.Components[0]
+	}
+
+	title := "Nuevo ticket"
+	if chat != "" {
+		title = "Nuevo ticket desde WhatsApp"
+	}
+	return ic.OpenModal(ticketPrefix+":"+ic.Interaction.ID, title,
+		discordgo.TextInput{
+			CustomID: "summary", Label: "Resumen", Style: discordgo.TextInputShort,
+			Value: truncate(draft.Summary, 255), Required: true, MaxLength: 255,
+		},
+		discordgo.TextInput{
+			CustomID: "description", Label: "Descripción", Style: discordgo.TextInputParagraph,
+			Value: truncate(draft.Description, 4000), MaxLength: 4000,
+		},
+		discordgo.TextInput{
+			CustomID: "priority", Label: "Prioridad", Style: discordgo.TextInputShort,
+			Value: draft.Priority, Placeholder: "Highest, High, Medium, Low, Lowest", MaxLength: 50,
+		},
+		discordgo.TextInput{
+			CustomID: "component", Label: "Componente", Style: discordgo.TextInputShort,
+			Value: component, Placeholder: "Vacío si el proyecto no usa componentes", MaxLength: 100,
+		},
+		discordgo.TextInput{
+			CustomID: "type", Label: "Tipo de issue", Style: discordgo.TextInputShort,
+			Value: draft.IssueType, Placeholder: "Bug, Task, Story", MaxLength: 50,
+		},
+	)
+}
+
+// draft arma el borrador del formulario: el de la IA si responde a tiempo, o
+// el último mensaje del cliente con la transcripción de la conversación
+func (f *ticketForm) draft(ctx context.Context, messages []types.MessageInfo) types.TicketDraft {
+	if len(messages) == 0 {
+		return types.TicketDraft{}
+	}
+
+	if f.services.Drafter != nil {
+		ctx, cancel := context.WithTimeout(ctx, draftTimeout)
+		defer cancel()
+		draft, err := f.services.Drafter.DraftTicket(ctx, messages)
+		if err == nil && draft != nil {
+			return *draft
+		}
+		log.Printf("DC: Borrador de IA no disponible, se usa la conversación: %v", err)
+	}
+
+	var summary string
+	if last, ok := lastCustomerMessage(messages); ok {
+		summary = truncate(strings.Join(strings.Fields(last.Text), " "), 120)
+	}
+	return types.TicketDraft{
+		Summary:     summary,
+		Description: "Conversación de WhatsApp:\n" + whatsapp.Transcript(messages),
+	}
+}
+
+// submit crea el ticket con los valores del formulario, lo vincula con los
+// mensajes de WhatsApp y lo confirma en Discord y en el chat
+func (f *ticketForm) submit(ic *InteractionContext) error {
+	s := f.services
+	if s.Tickets == nil {
+		return fmt.Errorf("Jira no está configurado")
+	}
+
+	id := strings.TrimPrefix(ic.CustomID(), ticketPrefix+":")
+	f.mu.Lock()
+	pending, ok := f.pending[id]
+	delete(f.pending, id)
+	f.mu.Unlock()
+	if !ok || time.Now().After(pending.expires) {
+		return fmt.Errorf("el formulario expiró, vuelva a ejecutar /ticket")
+	}
+
+	if err := ic.Defer(); err != nil {
+		return err
+	}
+
+	values := ic.ModalValues()
+	draft := types.TicketDraft{
+		ProjectKey:  pending.project,
+		Summary:     values["summary"],
+		Description: values["description"],
+		Priority:    values["priority"],
+		IssueType:   values["type"],
+		Reporter:    ic.User().Username,
+		SourceChat:  pending.chat,
+	}
+	if c := values["component"]; c != "" {
+		draft.Components = []string{c}
+	}
+
+	ticket, err := s.Tickets.CreateTicket(ic.Ctx, draft)
+	if err != nil {
+		return fmt.Errorf("no se pudo crear el ticket: %w", err)
+	}
+	log.Printf("DC: Ticket %s creado por %s desde el formulario", ticket.Key, ic.User())
+
+	if pending.chat == "" {
+		return ic.Respond("", ticketEmbed(ticket, colorSuccess))
+	}
+
+	f.link(ic.Ctx, ticket, pending)
+
+	var notes []string
+	if n := attachConversationMedia(ic.Ctx, s, ticket.Key, pending.chat, customerMessageIDs(pending.messages)); n > 0 {
+		notes = append(notes, fmt.Sprintf("Se adjuntaron %d archivos de la conversación.", n))
+	}
+	if err := f.confirm(ic.Ctx, ticket, pending); err != nil {
+		log.Printf("DC: No se pudo confirmar %s en WhatsApp: %v", ticket.Key, err)
+		notes = append(notes, fmt.Sprintf("No se pudo avisar en WhatsApp: %v", err))
+	}
+	return ic.Respond(strings.Join(notes, "\n"), ticketEmbed(ticket, colorSuccess))
+}
+
+// link vincula el ticket con los mensajes del cliente y con el hilo del puente
+func (f *ticketForm) link(ctx context.Context, ticket *types.TicketInfo, pending pendingTicket) {
+	if f.services.Links != nil {
+		if ids := customerMessageIDs(pending.messages); len(ids) > 0 {
+			if err := f.services.Links.SaveTicketLinks(ctx, ticket.Key, pending.chat, ids); err != nil {
+				log.Printf("DC: %v", err)
+			}
+		}
+	}
+
+	if err := f.services.Bridge.LinkTicket(ctx, pending.chat, ticket.Key); err != nil {
+		log.Printf("DC: Puente: %v", err)
+	}
+}
+
+// confirm avisa en el chat de WhatsApp, citando el último mensaje del cliente
+func (f *ticketForm) confirm(ctx context.Context, ticket *types.TicketInfo, pending pendingTicket) error {
+	if f.services.WhatsApp == nil {
+		return fmt.Errorf("WhatsApp no está configurado")
+	}
+
+	var quote *whatsapp.Quote
+	if last, ok := lastCustomerMessage(pending.messages); ok {
+		quote = &whatsapp.Quote{ID: last.ID, Sender: last.Sender, Text: last.Text}
+	}
+
+	text := fmt.Sprintf("Registramos su caso como *%s*: %s", ticket.Key, ticket.Summary)
+	if ticket.URL != "" {
+		text += "\n" + ticket.URL
+	}
+	_, err := f.services.WhatsApp.SendText(ctx, pending.chat, text, quote)
+	return err
+}
+
+// attachConversationMedia adjunta al ticket los archivos de los mensajes de
+// WhatsApp indicados y devuelve cuántos se subieron
+func attachConversationMedia(ctx context.Context, s Services, key, chat string, messageIDs []string) int {
+	if s.Tickets == nil || s.WhatsApp == nil || chat == "" || len(messageIDs) == 0 {
+		return 0
+	}
+
+	attached := 0
+	for _, media := range s.WhatsApp.ConversationMedia(ctx, chat, messageIDs) {
+		if err := s.Tickets.AddAttachment(ctx, key, media); err != nil {
+			log.Printf("DC: No se pudo adjuntar %s a %s: %v", media.Filename, key, err)
+			continue
+		}
+		attached++
+	}
+	return attached
+}
+
+// customerMessageIDs IDs de los mensajes que no envió Lisa
+func customerMessageIDs(messages []types.MessageInfo) []string {
+	var ids []string
+	for _, msg := range messages {
+		if !msg.IsFromMe {
+			ids = append(ids, msg.ID)
+		}
+	}
+	return ids
+}
+
+// lastCustomerMessage último mensaje del chat que no envió Lisa
+func lastCustomerMessage(messages []types.MessageInfo) (types.MessageInfo, bool) {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if !messages[i].IsFromMe && messages[i].Text != "" {
+			return messages[i], true
+		}
+	}
+	return types.MessageInfo{}, false
+}
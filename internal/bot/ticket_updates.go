@@ -0,0 +1,145 @@
+package bot
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"Lisa/internal/config"
+	"Lisa/internal/database"
+	"Lisa/pkg/types"
+)
+
+// TicketUpdateStore vínculos de los tickets con chats de WhatsApp e hilos del puente
+type TicketUpdateStore interface {
+	TicketLinks(ctx context.Context, ticketKey string) ([]database.TicketLink, error)
+	BridgeThreadByTicket(ctx context.Context, ticketKey string) (*database.BridgeThread, error)
+}
+
+// TicketUpdates avisa las novedades de los tickets (webhook de Jira) en los
+// chats de WhatsApp vinculados y en su hilo o canal del puente
+type TicketUpdates struct {
+	bot       *Bot
+	services  Services
+	store     TicketUpdateStore
+	templates config.TicketUpdateTemplates
+}
+
+// NewTicketUpdates crea el aviso de novedades con JIRA_UPDATE_TEMPLATES
+func NewTicketUpdates(b *Bot, services Services, store TicketUpdateStore, templates config.TicketUpdateTemplates) *TicketUpdates {
+	return &TicketUpdates{
+		bot:       b,
+		services:  services,
+		store:     store,
+		templates: templates,
+	}
+}
+
+// Handle avisa las novedades. Las de un mismo ticket se juntan en un mensaje.
+func (u *TicketUpdates) Handle(ctx context.Context, events []types.TicketEvent) {
+	var keys []string
+	lines := make(map[string][]string)
+	for _, evt := range events {
+		text := u.render(evt)
+		if text == "" {
+			continue
+		}
+		if _, ok := lines[evt.Key]; !ok {
+			keys = append(keys, evt.Key)
+		}
+		lines[evt.Key] = append(lines[evt.Key], text)
+	}
+
+	for _, key := range keys {
+		u.notify(ctx, key, strings.Join(lines[key], "\n"))
+	}
+}
+
+// render aplica la plantilla del tipo de novedad, o "" si está desactivada
+func (u *TicketUpdates) render(evt types.TicketEvent) string {
+	var template string
+	switch evt.Type {
+	case types.TicketEventStatus:
+		template = u.templates.Status
+	case types.TicketEventComment:
+		template = u.templates.Comment
+	case types.TicketEventAssignee:
+		template = u.templates.Assignee
+	case types.TicketEventResolution:
+		template = u.templates.Resolution
+	}
+	if template == "" {
+		return ""
+	}
+
+	assignee := evt.To
+	if evt.Type == types.TicketEventAssignee && assignee == "" {
+		assignee = "nadie por ahora"
+	}
+	return strings.NewReplacer(
+		"{clave}", evt.Key,
+		"{resumen}", evt.Summary,
+		"{enlace}", evt.URL,
+		"{autor}", evt.Author,
+		"{anterior}", evt.From,
+		"{estado}", evt.To,
+		"{asignado}", assignee,
+		"{resolucion}", evt.To,
+		"{comentario}", truncate(evt.Comment, 1500),
+	).Replace(template)
+}
+
+// notify envía el aviso a cada chat vinculado al ticket y a su hilo o canal
+// del puente
+func (u *TicketUpdates) notify(ctx context.Context, key, text string) {
+	chats := make(map[string]bool)
+	var order []string
+	addChat := func(chat string) {
+		if chat != "" && !chats[chat] {
+			chats[chat] = true
+			order = append(order, chat)
+		}
+	}
+
+	links, err := u.store.TicketLinks(ctx, key)
+	if err != nil {
+		log.Printf("DC: Novedades: %v", err)
+	}
+	for _, link := range links {
+		addChat(link.WAChat)
+	}
+
+	var discordChannels []string
+	thread, err := u.store.BridgeThreadByTicket(ctx, key)
+	if err != nil {
+		log.Printf("DC: Novedades: %v", err)
+	}
+	if thread != nil {
+		addChat(thread.WAChat)
+		discordChannels = append(discordChannels, thread.ThreadID)
+	} else if u.services.Bridge != nil {
+		for _, chat := range order {
+			if channelID, ok := u.services.Bridge.ChannelFor(chat); ok {
+				discordChannels = append(discordChannels, channelID)
+			}
+		}
+	}
+
+	if len(order) == 0 && len(discordChannels) == 0 {
+		return
+	}
+
+	if u.services.WhatsApp != nil {
+		for _, chat := range order {
+			if _, err := u.services.WhatsApp.SendText(ctx, chat, text, nil); err != nil {
+				log.Printf("DC: Novedades: no se pudo avisar %s en %s: %v", key, chat, err)
+			}
+		}
+	}
+	for _, channelID := range discordChannels {
+		if _, err := u.bot.session.ChannelMessageSend(channelID, text); err != nil {
+			log.Printf("DC: Novedades: no se pudo avisar %s en %s: %v", key, channelID, err)
+		}
+	}
+	log.Printf("DC: Novedades de %s avisadas en %d chats y %d canales", key, len(order), len(discordChannels))
+}
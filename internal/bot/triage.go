@@ -0,0 +1,407 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"Lisa/internal/database"
+	"Lisa/internal/whatsapp"
+	"Lisa/pkg/types"
+)
+
+const (
+	// triagePrefix prefijo de los custom ID de los botones y formularios de triaje
+	triagePrefix = "triage"
+
+	// triageMinConfidence confianza mínima de la IA para publicar un caso
+	triageMinConfidence = 0.6
+
+	// triageMinLength mensajes más cortos no se clasifican ("ok", "gracias")
+	triageMinLength = 15
+
+	// triageWorkers clasificaciones simultáneas como máximo
+	triageWorkers = 4
+
+	classifyTimeout = 30 * time.Second
+)
+
+// issueKeyPattern formato de una clave de Jira (PROJ-123)
+var issueKeyPattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]+-\d+$`)
+
+// Classifier decide si un mensaje es un bug, un pedido, una consulta u otra cosa
+type Classifier interface {
+	Classify(ctx context.Context, msg types.MessageInfo) (*types.Classification, error)
+}
+
+// TriageStore persistencia de los casos de triaje
+type TriageStore interface {
+	SaveTriageItem(ctx context.Context, item *database.TriageItem) error
+	TriageItem(ctx context.Context, id int64) (*database.TriageItem, error)
+	SetTriageMessage(ctx context.Context, id int64, channelID, messageID string) error
+	ClaimTriageItem(ctx context.Context, id int64, status, actedBy string) (bool, error)
+	FinishTriageItem(ctx context.Context, id int64, outcome string) error
+	ReleaseTriageItem(ctx context.Context, id int64) error
+}
+
+// Triage publica en Discord los mensajes que la IA detecta como problemas o
+// pedidos, con botones para crear el ticket, marcarlo como duplicado,
+// responder con la sugerencia de la IA o descartarlo
+type Triage struct {
+	bot       *Bot
+	channelID string
+	services  Services
+	store     TriageStore
+	sem       chan struct{}
+
+	// pending mensajes en clasificación o esperando turno
+	pending atomic.Int64
+}
+
+// NewTriage crea el triaje y registra los handlers de sus botones
+func NewTriage(b *Bot, channelID string, services Services, store TriageStore) (*Triage, error) {
+	if services.Classifier == nil {
+		return nil, fmt.Errorf("el triaje necesita un clasificador de IA")
+	}
+	if channelID == "" {
+		return nil, fmt.Errorf("falta el canal de triaje")
+	}
+
+	t := &Triage{
+		bot:       b,
+		channelID: channelID,
+		services:  services,
+		store:     store,
+		sem:       make(chan struct{}, triageWorkers),
+	}
+	b.HandleComponent(triagePrefix, t.handleComponent)
+	return t, nil
+}
+
+// HandleEvent clasifica los mensajes de texto recibidos por WhatsApp. Se
+// suscribe con Events().Subscribe; la clasificación no bloquea al cliente.
+// Los comandos del chat (!ticket ...) no se clasifican: ya los atendió el router.
+func (t *Triage) HandleEvent(evt whatsapp.Event) {
+	if evt.Type != whatsapp.EventMessage || evt.Message == nil || evt.Message.Type != types.MessageTypeText || evt.Command {
+		return
+	}
+	t.Ingest(evt.Message.Info, "")
+}
+
+// Ingest clasifica un mensaje normalizado de cualquier fuente. projectKey es
+// el proyecto de Jira de los tickets que se creen desde el caso (vacío = el
+// configurado por defecto).
+func (t *Triage) Ingest(msg types.MessageInfo, projectKey string) {
+	if len([]rune(strings.TrimSpace(msg.Text))) < triageMinLength {
+		return
+	}
+	t.pending.Add(1)
+	go t.classify(msg, projectKey)
+}
+
+// Pending cantidad de mensajes en clasificación o esperando turno
+func (t *Triage) Pending() int {
+	return int(t.pending.Load())
+}
+
+func (t *Triage) classify(msg types.MessageInfo, projectKey string) {
+	defer t.pending.Add(-1)
+	t.sem <- struct{}{}
+	defer func() { <-t.sem }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), classifyTimeout)
+	defer cancel()
+
+	c, err := t.services.Classifier.Classify(ctx, msg)
+	if err != nil {
+		log.Printf("DC: Triaje: no se pudo clasificar %s: %v", msg.ID, err)
+		return
+	}
+	if !c.Actionable() || c.Confidence < triageMinConfidence {
+		return
+	}
+
+	item := &database.TriageItem{
+		Source:      msg.Source,
+		ProjectKey:  projectKey,
+		WAChat:      msg.From,
+		WAMessageID: msg.ID,
+		WASender:    msg.Sender,
+		SenderName:  msg.PushName,
+		Text:        msg.Text,
+		Category:    string(c.Category),
+		Confidence:  c.Confidence,
+		Summary:     c.Summary,
+		Priority:    c.Priority,
+		IssueType:   c.IssueType,
+		Suggestion:  c.Suggestion,
+	}
+	if msg.GroupName != "" {
+		item.SenderName = fmt.Sprintf("%s en %s", msg.PushName, msg.GroupName)
+	}
+	if err := t.store.SaveTriageItem(ctx, item); err != nil {
+		log.Printf("DC: Triaje: %v", err)
+		return
+	}
+
+	sent, err := t.bot.session.ChannelMessageSendComplex(t.channelID, &discordgo.MessageSend{
+		Embeds:     []*discordgo.MessageEmbed{triageEmbed(item)},
+		Components: triageButtons(item),
+	})
+	if err != nil {
+		log.Printf("DC: Triaje: no se pudo publicar el caso #%d: %v", item.ID, err)
+		return
+	}
+	if err := t.store.SetTriageMessage(ctx, item.ID, sent.ChannelID, sent.ID); err != nil {
+		log.Printf("DC: Triaje: %v", err)
+	}
+	log.Printf("DC: Triaje: caso #%d (%s) publicado", item.ID, item.Category)
+}
+
+// handleComponent atiende los botones "triage:<acción>:<id>" y sus formularios
+func (t *Triage) handleComponent(ic *InteractionContext) error {
+	parts := strings.Split(ic.CustomID(), ":")
+	if len(parts) != 3 {
+		return fmt.Errorf("botón de triaje inválido: %s", ic.CustomID())
+	}
+	id, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return fmt.Errorf("botón de triaje inválido: %s", ic.CustomID())
+	}
+
+	item, err := t.store.TriageItem(ic.Ctx, id)
+	if err != nil {
+		return err
+	}
+	if item == nil {
+		return fmt.Errorf("el caso #%d ya no existe", id)
+	}
+	if item.Status != database.TriagePending {
+		// Otro agente ya actuó: mostrar el resultado
+		return ic.Update([]*discordgo.MessageEmbed{triageEmbed(item)}, nil)
+	}
+
+	switch parts[1] {
+	case "create":
+		return t.createTicket(ic, item)
+	case "dup":
+		return ic.OpenModal(fmt.Sprintf("%s:dupmodal:%d", triagePrefix, id), "Marcar como duplicado",
+			discordgo.TextInput{
+				CustomID:    "key",
+				Label:       "Clave del ticket original",
+				Style:       discordgo.TextInputShort,
+				Placeholder: "PROJ-123",
+				Required:    true,
+				MaxLength:   32,
+			})
+	case "dupmodal":
+		return t.markDuplicate(ic, item, strings.ToUpper(ic.ModalValues()["key"]))
+	case "reply":
+		return ic.OpenModal(fmt.Sprintf("%s:replymodal:%d", triagePrefix, id), "Responder al cliente",
+			discordgo.TextInput{
+				CustomID:  "text",
+				Label:     "Respuesta",
+				Style:     discordgo.TextInputParagraph,
+				Value:     item.Suggestion,
+				Required:  true,
+				MaxLength: 4000,
+			})
+	case "replymodal":
+		return t.reply(ic, item, ic.ModalValues()["text"])
+	case "dismiss":
+		return t.resolve(ic, item, database.TriageDismissed, func() (string, error) { return "", nil })
+	default:
+		return fmt.Errorf("acción de triaje desconocida: %s", parts[1])
+	}
+}
+
+func (t *Triage) createTicket(ic *InteractionContext, item *database.TriageItem) error {
+	if t.services.Tickets == nil {
+		return fmt.Errorf("Jira no está configurado")
+	}
+
+	return t.resolve(ic, item, database.TriageTicket, func() (string, error) {
+		summary := item.Summary
+		if summary == "" {
+			summary = truncate(item.Text, 120)
+		}
+		description := fmt.Sprintf("%s\n\nMensaje original de %s:\n%s", item.Summary, item.SenderName, item.Text)
+		if item.Source == types.SourceDiscord && t.services.WhatsApp != nil {
+			// Los canales de Discord se archivan en el historial como un chat
+			if messages := t.services.WhatsApp.History().Recent(item.WAChat, ticketTranscriptSize); len(messages) > 1 {
+				description += "\n\nConversación de Discord:\n" + whatsapp.Transcript(messages)
+			}
+		}
+		ticket, err := t.services.Tickets.CreateTicket(ic.Ctx, types.TicketDraft{
+			ProjectKey:  item.ProjectKey,
+			Summary:     summary,
+			Description: description,
+			IssueType:   item.IssueType,
+			Priority:    item.Priority,
+			Reporter:    ic.User().Username,
+			SourceChat:  item.WAChat,
+		})
+		if err != nil {
+			return "", fmt.Errorf("no se pudo crear el ticket: %w", err)
+		}
+		if item.Source != types.SourceDiscord {
+			attachConversationMedia(ic.Ctx, t.services, ticket.Key, item.WAChat, []string{item.WAMessageID})
+		}
+		return fmt.Sprintf("[%s](%s)", ticket.Key, ticket.URL), nil
+	})
+}
+
+func (t *Triage) markDuplicate(ic *InteractionContext, item *database.TriageItem, key string) error {
+	if !issueKeyPattern.MatchString(key) {
+		return fmt.Errorf("%q no es una clave de ticket válida (ejemplo: PROJ-123)", key)
+	}
+
+	return t.resolve(ic, item, database.TriageDuplicate, func() (string, error) {
+		if t.services.Tickets == nil {
+			return key, nil
+		}
+		ticket, err := t.services.Tickets.GetTicket(ic.Ctx, key)
+		if err != nil {
+			return "", fmt.Errorf("no se encontró el ticket %s: %w", key, err)
+		}
+		return fmt.Sprintf("[%s](%s)", ticket.Key, ticket.URL), nil
+	})
+}
+
+func (t *Triage) reply(ic *InteractionContext, item *database.TriageItem, text string) error {
+	if text == "" {
+		return fmt.Errorf("la respuesta está vacía")
+	}
+	if item.Source == types.SourceDiscord {
+		// El caso vino de un canal de Discord: se responde en el mismo canal
+		return t.resolve(ic, item, database.TriageReplied, func() (string, error) {
+			_, err := t.bot.session.ChannelMessageSendReply(item.WAChat, text, &discordgo.MessageReference{
+				MessageID: item.WAMessageID,
+				ChannelID: item.WAChat,
+			})
+			if err != nil {
+				return "", err
+			}
+			return truncate(text, 500), nil
+		})
+	}
+	if t.services.WhatsApp == nil {
+		return fmt.Errorf("WhatsApp no está configurado")
+	}
+
+	return t.resolve(ic, item, database.TriageReplied, func() (string, error) {
+		_, err := t.services.WhatsApp.SendText(ic.Ctx, item.WAChat, text, &whatsapp.Quote{
+			ID:     item.WAMessageID,
+			Sender: item.WASender,
+			Text:   item.Text,
+		})
+		if err != nil {
+			return "", err
+		}
+		return truncate(text, 500), nil
+	})
+}
+
+// resolve reserva el caso para el agente, ejecuta la acción y actualiza el
+// embed con el resultado. Si la acción falla, el caso vuelve a quedar pendiente.
+func (t *Triage) resolve(ic *InteractionContext, item *database.TriageItem, status string, action func() (string, error)) error {
+	claimed, err := t.store.ClaimTriageItem(ic.Ctx, item.ID, status, ic.User().ID)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		if current, err := t.store.TriageItem(ic.Ctx, item.ID); err == nil && current != nil {
+			return ic.Update([]*discordgo.MessageEmbed{triageEmbed(current)}, nil)
+		}
+		return fmt.Errorf("otro agente ya tomó el caso #%d", item.ID)
+	}
+
+	if err := ic.DeferUpdate(); err != nil {
+		t.store.ReleaseTriageItem(ic.Ctx, item.ID)
+		return err
+	}
+
+	outcome, err := action()
+	if err != nil {
+		if releaseErr := t.store.ReleaseTriageItem(ic.Ctx, item.ID); releaseErr != nil {
+			log.Printf("DC: Triaje: %v", releaseErr)
+		}
+		return err
+	}
+	if err := t.store.FinishTriageItem(ic.Ctx, item.ID, outcome); err != nil {
+		log.Printf("DC: Triaje: %v", err)
+	}
+
+	item.Status, item.Outcome, item.ActedBy = status, outcome, ic.User().ID
+	log.Printf("DC: Triaje: caso #%d -> %s por %s", item.ID, status, ic.User())
+	return ic.Update([]*discordgo.MessageEmbed{triageEmbed(item)}, nil)
+}
+
+// triageEmbed muestra el caso y, si ya se resolvió, el resultado y quién actuó
+func triageEmbed(item *database.TriageItem) *discordgo.MessageEmbed {
+	title := "🐛 Posible bug"
+	if item.Category == string(types.CategoryRequest) {
+		title = "📝 Pedido de cliente"
+	}
+
+	from := item.SenderName
+	if item.Source == types.SourceDiscord {
+		from += " (Discord)"
+	}
+
+	fields := []*discordgo.MessageEmbedField{
+		{Name: "Mensaje", Value: truncate(item.Text, 1024)},
+		{Name: "De", Value: from, Inline: true},
+		{Name: "Confianza", Value: fmt.Sprintf("%.0f%%", item.Confidence*100), Inline: true},
+	}
+	if item.Priority != "" {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Prioridad sugerida", Value: item.Priority, Inline: true})
+	}
+	if item.ProjectKey != "" {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Proyecto", Value: item.ProjectKey, Inline: true})
+	}
+
+	color := colorWarning
+	if item.Status != database.TriagePending {
+		var result string
+		switch item.Status {
+		case database.TriageTicket:
+			color, result = colorSuccess, fmt.Sprintf("Ticket %s creado por <@%s>", item.Outcome, item.ActedBy)
+		case database.TriageDuplicate:
+			color, result = colorInfo, fmt.Sprintf("Duplicado de %s, marcado por <@%s>", item.Outcome, item.ActedBy)
+		case database.TriageReplied:
+			color, result = colorInfo, fmt.Sprintf("<@%s> respondió:\n> %s", item.ActedBy, strings.ReplaceAll(item.Outcome, "\n", "\n> "))
+		case database.TriageDismissed:
+			color, result = colorMuted, fmt.Sprintf("Descartado por <@%s>", item.ActedBy)
+		}
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Resultado", Value: truncate(result, 1024)})
+	}
+
+	return &discordgo.MessageEmbed{
+		Title:       title,
+		Description: item.Summary,
+		Color:       color,
+		Fields:      fields,
+		Footer:      &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("Caso #%d", item.ID)},
+		Timestamp:   item.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+func triageButtons(item *database.TriageItem) []discordgo.MessageComponent {
+	id := func(action string) string { return fmt.Sprintf("%s:%s:%d", triagePrefix, action, item.ID) }
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.Button{Label: "Crear ticket en Jira", Style: discordgo.PrimaryButton, CustomID: id("create")},
+			discordgo.Button{Label: "Marcar duplicado de…", Style: discordgo.SecondaryButton, CustomID: id("dup")},
+			discordgo.Button{Label: "Responder con sugerencia", Style: discordgo.SuccessButton, CustomID: id("reply"), Disabled: item.Suggestion == ""},
+			discordgo.Button{Label: "Descartar", Style: discordgo.DangerButton, CustomID: id("dismiss")},
+		}},
+	}
+}
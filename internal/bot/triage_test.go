@@ -0,0 +1,51 @@
+package bot
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"Lisa/internal/whatsapp"
+	"Lisa/pkg/types"
+)
+
+// countingClassifier cuenta las clasificaciones y responde "otro" para que
+// el caso no se publique
+type countingClassifier struct {
+	calls atomic.Int32
+}
+
+func (c *countingClassifier) Classify(ctx context.Context, msg types.MessageInfo) (*types.Classification, error) {
+	c.calls.Add(1)
+	return &types.Classification{Category: types.CategoryOther}, nil
+}
+
+func TestTriageHandleEvent(t *testing.T) {
+	message := func(text string) *types.MediaMessage {
+		return &types.MediaMessage{Type: types.MessageTypeText, Info: types.MessageInfo{ID: "m1", Text: text}}
+	}
+	tests := []struct {
+		name     string
+		evt      whatsapp.Event
+		classify bool
+	}{
+		{"mensaje del cliente", whatsapp.Event{Type: whatsapp.EventMessage, Message: message("la app no carga desde ayer")}, true},
+		{"comando del chat", whatsapp.Event{Type: whatsapp.EventMessage, Message: message("!ticket la app no carga desde ayer"), Command: true}, false},
+		{"mensaje corto", whatsapp.Event{Type: whatsapp.EventMessage, Message: message("gracias")}, false},
+		{"imagen", whatsapp.Event{Type: whatsapp.EventMessage, Message: &types.MediaMessage{Type: types.MessageTypeImage, Info: types.MessageInfo{Text: "captura del error de ayer"}}}, false},
+		{"otro evento", whatsapp.Event{Type: whatsapp.EventConnectionState}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			classifier := &countingClassifier{}
+			triage := &Triage{services: Services{Classifier: classifier}, sem: make(chan struct{}, 1)}
+
+			triage.HandleEvent(tt.evt)
+			waitFor(t, "la clasificación", func() bool { return triage.Pending() == 0 })
+
+			if got := classifier.calls.Load() == 1; got != tt.classify {
+				t.Errorf("clasificado = %v, se esperaba %v", got, tt.classify)
+			}
+		})
+	}
+}
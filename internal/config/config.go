@@ -1,10 +1,12 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -15,11 +17,45 @@ type Config struct {
 	Jira     JiraConfig     `json:"jira"`
 	Gemini   GeminiConfig   `json:"gemini"`
 	Server   ServerConfig   `json:"server"`
+	Alerts   AlertConfig    `json:"alerts"`
 }
 
 type DiscordConfig struct {
-	Token   string `json:"token"`
-	GuildID string `json:"guild_id"`
+	Token          string   `json:"token"`
+	GuildID        string   `json:"guild_id"`
+	Intents        []string `json:"intents"`
+	AlertChannelID string   `json:"alert_channel_id"`
+
+	// Puente WhatsApp -> Discord: JID del chat -> ID del canal
+	BridgeChannels map[string]string `json:"bridge_channels"`
+
+	// Canal donde se crea un hilo por cada chat de WhatsApp que no esté en
+	// BridgeChannels (vacío = solo los chats mapeados)
+	ThreadChannelID string `json:"thread_channel_id"`
+	// Inactividad tras la que se archiva el hilo de un chat
+	ThreadArchiveAfter time.Duration `json:"thread_archive_after"`
+
+	// Canal donde se publican los mensajes que la IA marca como bug o pedido
+	TriageChannelID string `json:"triage_channel_id"`
+
+	// Triaje por reacciones en los mensajes del puente: emoji -> acción
+	// (bug, priority, assign, resolve)
+	ReactionActions map[string]string `json:"reaction_actions"`
+	// Roles que pueden triar con reacciones (vacío = quien pueda gestionar mensajes)
+	TriageRoles []string `json:"triage_roles"`
+	// Usuario de Discord -> accountId de Jira (usuario en Data Center), para "asignarme"
+	JiraUsers map[string]string `json:"jira_users"`
+
+	// Canales y foros de Discord donde reportan clientes: ID -> proyecto de
+	// Jira (vacío = JIRA_PROJECT_KEY)
+	SourceChannels map[string]string `json:"source_channels"`
+
+	// Prefijo opcional de las respuestas enviadas a WhatsApp. {agente} se
+	// reemplaza por el nombre del agente (vacío = sin atribución)
+	ReplyPrefix string `json:"reply_prefix"`
+
+	// URL base de la API (solo para pruebas contra un servidor falso)
+	APIURL string `json:"api_url"`
 }
 
 type WhatsAppConfig struct {
@@ -31,6 +67,35 @@ type WhatsAppConfig struct {
 	User        string `json:"user"`
 	Password    string `json:"password"`
 	SSLMode     string `json:"ssl_mode"`
+
+	// Comandos dentro del chat
+	CommandPrefixes []string `json:"command_prefixes"`
+	AdminJIDs       []string `json:"admin_jids"`
+	OnCallJIDs      []string `json:"on_call_jids"`
+
+	// Número para vincular por código en lugar de QR (vacío = QR)
+	PairPhone string `json:"pair_phone"`
+
+	// Proxy de salida (http://, https:// o socks5://). MediaProxyURL permite
+	// usar otro proxy para subir y descargar multimedia.
+	ProxyURL      string `json:"proxy_url"`
+	ProxyUser     string `json:"proxy_user"`
+	ProxyPassword string `json:"-"`
+	MediaProxyURL string `json:"media_proxy_url"`
+
+	// Tamaño máximo de los archivos que se descargan (0 = sin límite)
+	MediaMaxMB int `json:"media_max_mb"`
+
+	// Mensajes de bienvenida y despedida en grupos
+	Templates      GroupTemplates            `json:"templates"`
+	GroupTemplates map[string]GroupTemplates `json:"group_templates"`
+}
+
+// GroupTemplates plantillas de bienvenida y despedida. Una plantilla vacía
+// desactiva el mensaje. Variables: {nombre}, {mencion} y {grupo}.
+type GroupTemplates struct {
+	Welcome string `json:"welcome"`
+	Goodbye string `json:"goodbye"`
 }
 
 type JiraConfig struct {
@@ -38,6 +103,36 @@ type JiraConfig struct {
 	Email      string `json:"email"`
 	Token      string `json:"token"`
 	ProjectKey string `json:"project_key"`
+
+	// Mode "cloud" (email + API token, REST v3 y ADF) o "datacenter" (token
+	// personal, REST v2 y wiki markup) para Jira Server y Data Center
+	Mode string `json:"mode"`
+
+	// Alias de transiciones: "en progreso" -> ["In Progress", "Start Progress"].
+	// Se suman a los alias por defecto del cliente y reemplazan los repetidos.
+	TransitionAliases map[string][]string `json:"transition_aliases"`
+
+	// Tiempo que se guardan los tipos de issue, prioridades y campos de cada proyecto
+	MetadataTTL time.Duration `json:"metadata_ttl"`
+
+	// Tamaño máximo de cada adjunto, en MB
+	AttachmentMaxMB int `json:"attachment_max_mb"`
+
+	// Webhook de Jira en el puerto del servidor. Sin secreto queda desactivado.
+	WebhookSecret   string                `json:"webhook_secret"`
+	WebhookPath     string                `json:"webhook_path"`
+	UpdateTemplates TicketUpdateTemplates `json:"update_templates"`
+}
+
+// TicketUpdateTemplates plantillas de las novedades de tickets que se avisan
+// en WhatsApp y Discord. Una plantilla vacía desactiva el aviso. Variables:
+// {clave}, {resumen}, {enlace}, {autor}, {anterior}, {estado}, {asignado},
+// {resolucion} y {comentario}.
+type TicketUpdateTemplates struct {
+	Status     string `json:"status"`
+	Comment    string `json:"comment"`
+	Assignee   string `json:"assignee"`
+	Resolution string `json:"resolution"`
 }
 
 type GeminiConfig struct {
@@ -45,6 +140,10 @@ type GeminiConfig struct {
 	Model  string `json:"model"`
 }
 
+type AlertConfig struct {
+	WebhookURLs []string `json:"webhook_urls"`
+}
+
 type ServerConfig struct {
 	Port        string `json:"port"`
 	LogLevel    string `json:"log_level"`
@@ -61,11 +160,18 @@ func Load() (*Config, error) {
 	cfg := &Config{}
 
 	cfg.Discord = DiscordConfig{
-		Token:   getEnv("DISCORD_TOKEN", ""),
-		GuildID: getEnv("DISCORD_GUILD_ID", ""),
+		Token:           getEnv("DISCORD_TOKEN", ""),
+		GuildID:         getEnv("DISCORD_GUILD_ID", ""),
+		Intents:         getEnvList("DISCORD_INTENTS", "guilds,guild_messages,guild_message_reactions,message_content,direct_messages"),
+		AlertChannelID:  getEnv("DISCORD_ALERT_CHANNEL_ID", ""),
+		ReplyPrefix:     getEnv("DISCORD_REPLY_PREFIX", ""),
+		TriageChannelID: getEnv("DISCORD_TRIAGE_CHANNEL_ID", ""),
+		TriageRoles:     getEnvList("DISCORD_TRIAGE_ROLES", ""),
+		APIURL:          getEnv("DISCORD_API_URL", ""),
 	}
 
 	port, _ := strconv.Atoi(getEnv("POSTGRES_PORT", "5432"))
+	mediaMaxMB, _ := strconv.Atoi(getEnv("WA_MEDIA_MAX_MB", "25"))
 	cfg.WhatsApp = WhatsAppConfig{
 		Host:     getEnv("POSTGRES_HOST", "localhost"),
 		Port:     port,
@@ -74,14 +180,115 @@ func Load() (*Config, error) {
 		Password: getEnv("POSTGRES_PASSWORD", ""),
 		SSLMode:  getEnv("POSTGRES_SSL", "disable"),
 		LogLevel: getEnv("LOG_LEVEL", "INFO"),
+
+		CommandPrefixes: getEnvList("WA_COMMAND_PREFIXES", "!,/"),
+		AdminJIDs:       getEnvList("WA_ADMIN_JIDS", ""),
+		OnCallJIDs:      getEnvList("WA_ONCALL_JIDS", ""),
+		PairPhone:       getEnv("WA_PAIR_PHONE", ""),
+
+		ProxyURL:      getEnv("WA_PROXY_URL", ""),
+		ProxyUser:     getEnv("WA_PROXY_USER", ""),
+		ProxyPassword: getEnv("WA_PROXY_PASSWORD", ""),
+		MediaProxyURL: getEnv("WA_MEDIA_PROXY_URL", ""),
+		MediaMaxMB:    mediaMaxMB,
+
+		Templates: GroupTemplates{
+			Welcome: getEnv("WA_WELCOME_TEMPLATE", ""),
+			Goodbye: getEnv("WA_GOODBYE_TEMPLATE", ""),
+		},
 	}
 	cfg.WhatsApp.DatabaseURI = buildPostgresURI(cfg.WhatsApp)
 
+	// Plantillas por grupo: {"<jid del grupo>": {"welcome": "...", "goodbye": "..."}}
+	if raw := getEnv("WA_GROUP_TEMPLATES", ""); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &cfg.WhatsApp.GroupTemplates); err != nil {
+			return nil, fmt.Errorf("WA_GROUP_TEMPLATES inválido: %w", err)
+		}
+	}
+
+	// Canales del puente: {"<jid del chat>": "<id del canal de Discord>"}
+	if raw := getEnv("DISCORD_BRIDGE_CHANNELS", ""); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &cfg.Discord.BridgeChannels); err != nil {
+			return nil, fmt.Errorf("DISCORD_BRIDGE_CHANNELS inválido: %w", err)
+		}
+	}
+
+	// Reacciones de triaje: {"🐛": "bug", ...}
+	cfg.Discord.ReactionActions = map[string]string{"🐛": "bug", "⚡": "priority", "👀": "assign", "✅": "resolve"}
+	if raw := getEnv("DISCORD_REACTION_ACTIONS", ""); raw != "" {
+		cfg.Discord.ReactionActions = nil
+		if err := json.Unmarshal([]byte(raw), &cfg.Discord.ReactionActions); err != nil {
+			return nil, fmt.Errorf("DISCORD_REACTION_ACTIONS inválido: %w", err)
+		}
+	}
+
+	// Usuarios de Jira de los agentes: {"<id de Discord>": "<accountId o usuario de Jira>"}
+	if raw := getEnv("DISCORD_JIRA_USERS", ""); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &cfg.Discord.JiraUsers); err != nil {
+			return nil, fmt.Errorf("DISCORD_JIRA_USERS inválido: %w", err)
+		}
+	}
+
+	// Canales monitoreados: {"<id del canal o foro>": "<proyecto de Jira>"}
+	if raw := getEnv("DISCORD_SOURCE_CHANNELS", ""); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &cfg.Discord.SourceChannels); err != nil {
+			return nil, fmt.Errorf("DISCORD_SOURCE_CHANNELS inválido: %w", err)
+		}
+	}
+
+	cfg.Discord.ThreadChannelID = getEnv("DISCORD_BRIDGE_THREAD_CHANNEL_ID", "")
+	archiveAfter, err := time.ParseDuration(getEnv("DISCORD_THREAD_ARCHIVE_AFTER", "24h"))
+	if err != nil {
+		return nil, fmt.Errorf("DISCORD_THREAD_ARCHIVE_AFTER inválido: %w", err)
+	}
+	cfg.Discord.ThreadArchiveAfter = archiveAfter
+
 	cfg.Jira = JiraConfig{
 		URL:        getEnv("JIRA_URL", ""),
 		Email:      getEnv("JIRA_EMAIL", ""),
 		Token:      getEnv("JIRA_TOKEN", ""),
 		ProjectKey: getEnv("JIRA_PROJECT_KEY", "SUPPORT"),
+		Mode:       strings.ToLower(getEnv("JIRA_MODE", "cloud")),
+	}
+	switch cfg.Jira.Mode {
+	case "cloud", "datacenter":
+	case "server", "dc":
+		cfg.Jira.Mode = "datacenter"
+	default:
+		return nil, fmt.Errorf("JIRA_MODE inválido: %q (use cloud o datacenter)", cfg.Jira.Mode)
+	}
+
+	metadataTTL, err := time.ParseDuration(getEnv("JIRA_METADATA_TTL", "1h"))
+	if err != nil {
+		return nil, fmt.Errorf("JIRA_METADATA_TTL inválido: %w", err)
+	}
+	cfg.Jira.MetadataTTL = metadataTTL
+
+	attachmentMaxMB, _ := strconv.Atoi(getEnv("JIRA_ATTACHMENT_MAX_MB", "10"))
+	cfg.Jira.AttachmentMaxMB = attachmentMaxMB
+
+	cfg.Jira.WebhookSecret = getEnv("JIRA_WEBHOOK_SECRET", "")
+	cfg.Jira.WebhookPath = getEnv("JIRA_WEBHOOK_PATH", "/webhooks/jira")
+	cfg.Jira.UpdateTemplates = TicketUpdateTemplates{
+		Status:     "Su caso *{clave}* cambió de estado: {anterior} → {estado}",
+		Comment:    "Novedades en su caso *{clave}* ({autor}):\n{comentario}",
+		Assignee:   "Su caso *{clave}* quedó a cargo de {asignado}",
+		Resolution: "Su caso *{clave}* fue resuelto ({resolucion})",
+	}
+
+	// Plantillas de novedades: {"status": "...", "comment": "", ...}. Las que
+	// no se indican conservan el valor por defecto.
+	if raw := getEnv("JIRA_UPDATE_TEMPLATES", ""); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &cfg.Jira.UpdateTemplates); err != nil {
+			return nil, fmt.Errorf("JIRA_UPDATE_TEMPLATES inválido: %w", err)
+		}
+	}
+
+	// Alias de transiciones: {"<alias>": ["<transición o estado>", ...]}
+	if raw := getEnv("JIRA_TRANSITION_ALIASES", ""); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &cfg.Jira.TransitionAliases); err != nil {
+			return nil, fmt.Errorf("JIRA_TRANSITION_ALIASES inválido: %w", err)
+		}
 	}
 
 	cfg.Gemini = GeminiConfig{
@@ -95,6 +302,10 @@ func Load() (*Config, error) {
 		Environment: getEnv("ENVIRONMENT", "development"),
 	}
 
+	cfg.Alerts = AlertConfig{
+		WebhookURLs: getEnvList("ALERT_WEBHOOK_URLS", ""),
+	}
+
 	if err := cfg.Validate(); err != nil {
 		return nil, fmt.Errorf("configuración inválida: %w", err)
 	}
@@ -113,11 +324,7 @@ func (c *Config) Validate() error {
 		missing = append(missing, "POSTGRES_PASSWORD")
 	}
 
-	if c.Gemini.APIKey == "" {
-		missing = append(missing, "GEMINI_API_KEY")
-	}
-
-	if c.Jira.URL == "" || c.Jira.Email == "" || c.Jira.Token == "" {
+	if c.Jira.URL == "" || c.Jira.Token == "" || (c.Jira.Email == "" && c.Jira.Mode != "datacenter") {
 		fmt.Println("ADVERTENCIA: Configuración de Jira incompleta. Algunas funciones pueden no estar disponibles.")
 	}
 
@@ -147,6 +354,17 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvList lee una lista separada por comas, ignorando elementos vacíos
+func getEnvList(key, defaultValue string) []string {
+	var items []string
+	for _, item := range strings.Split(getEnv(key, defaultValue), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func buildPostgresURI(cfg WhatsAppConfig) string {
 	return fmt.Sprintf(
 		"postgres://%s:%s@%s:%d/%s?sslmode=%s",
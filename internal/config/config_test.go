@@ -0,0 +1,43 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		missing string
+	}{
+		{
+			name: "sin Gemini ni Jira",
+			cfg:  Config{Discord: DiscordConfig{Token: "token"}, WhatsApp: WhatsAppConfig{Password: "clave"}},
+		},
+		{
+			name:    "sin token de Discord",
+			cfg:     Config{WhatsApp: WhatsAppConfig{Password: "clave"}, Gemini: GeminiConfig{APIKey: "clave"}},
+			missing: "DISCORD_TOKEN",
+		},
+		{
+			name:    "sin base de datos",
+			cfg:     Config{Discord: DiscordConfig{Token: "token"}},
+			missing: "POSTGRES_PASSWORD",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if tt.missing == "" {
+				if err != nil {
+					t.Errorf("Validate() = %v, se esperaba nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.missing) {
+				t.Errorf("Validate() = %v, se esperaba que falte %s", err, tt.missing)
+			}
+		})
+	}
+}
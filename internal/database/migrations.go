@@ -1 +1,208 @@
 package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// migration cambio de esquema versionado. Las tablas de Lisa usan el prefijo
+// lisa_ porque comparten base de datos con las tablas de whatsmeow.
+type migration struct {
+	version int
+	name    string
+	sql     string
+}
+
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "participant_events",
+		sql: `
+			CREATE TABLE lisa_participant_events (
+				id          BIGSERIAL PRIMARY KEY,
+				group_jid   TEXT NOT NULL,
+				group_name  TEXT NOT NULL DEFAULT '',
+				participant TEXT NOT NULL,
+				action      TEXT NOT NULL,
+				actor       TEXT NOT NULL DEFAULT '',
+				reason      TEXT NOT NULL DEFAULT '',
+				created_at  TIMESTAMPTZ NOT NULL
+			);
+			CREATE INDEX lisa_participant_events_group_idx
+				ON lisa_participant_events (group_jid, created_at DESC);
+			CREATE INDEX lisa_participant_events_participant_idx
+				ON lisa_participant_events (participant, created_at DESC);
+		`,
+	},
+	{
+		version: 2,
+		name:    "bridge_messages",
+		sql: `
+			CREATE TABLE lisa_bridge_messages (
+				id                 BIGSERIAL PRIMARY KEY,
+				wa_chat            TEXT NOT NULL,
+				wa_message_id      TEXT NOT NULL,
+				wa_sender          TEXT NOT NULL DEFAULT '',
+				wa_text            TEXT NOT NULL DEFAULT '',
+				discord_channel_id TEXT NOT NULL,
+				discord_message_id TEXT NOT NULL,
+				direction          TEXT NOT NULL,
+				created_at         TIMESTAMPTZ NOT NULL DEFAULT NOW()
+			);
+			CREATE UNIQUE INDEX lisa_bridge_messages_wa_idx
+				ON lisa_bridge_messages (wa_chat, wa_message_id);
+			CREATE INDEX lisa_bridge_messages_discord_idx
+				ON lisa_bridge_messages (discord_message_id);
+		`,
+	},
+	{
+		version: 3,
+		name:    "bridge_threads",
+		sql: `
+			CREATE TABLE lisa_bridge_threads (
+				wa_chat       TEXT PRIMARY KEY,
+				channel_id    TEXT NOT NULL,
+				thread_id     TEXT NOT NULL UNIQUE,
+				name          TEXT NOT NULL DEFAULT '',
+				ticket_key    TEXT NOT NULL DEFAULT '',
+				archived      BOOLEAN NOT NULL DEFAULT FALSE,
+				last_activity TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+				created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+			);
+			CREATE INDEX lisa_bridge_threads_activity_idx
+				ON lisa_bridge_threads (last_activity) WHERE NOT archived;
+		`,
+	},
+	{
+		version: 4,
+		name:    "triage_items",
+		sql: `
+			CREATE TABLE lisa_triage_items (
+				id                 BIGSERIAL PRIMARY KEY,
+				wa_chat            TEXT NOT NULL,
+				wa_message_id      TEXT NOT NULL,
+				wa_sender          TEXT NOT NULL DEFAULT '',
+				sender_name        TEXT NOT NULL DEFAULT '',
+				text               TEXT NOT NULL,
+				category           TEXT NOT NULL,
+				confidence         DOUBLE PRECISION NOT NULL DEFAULT 0,
+				summary            TEXT NOT NULL DEFAULT '',
+				priority           TEXT NOT NULL DEFAULT '',
+				issue_type         TEXT NOT NULL DEFAULT '',
+				suggestion         TEXT NOT NULL DEFAULT '',
+				discord_channel_id TEXT NOT NULL DEFAULT '',
+				discord_message_id TEXT NOT NULL DEFAULT '',
+				status             TEXT NOT NULL DEFAULT 'pending',
+				outcome            TEXT NOT NULL DEFAULT '',
+				acted_by           TEXT NOT NULL DEFAULT '',
+				created_at         TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+				updated_at         TIMESTAMPTZ NOT NULL DEFAULT NOW()
+			);
+			CREATE INDEX lisa_triage_items_status_idx
+				ON lisa_triage_items (status, created_at DESC);
+		`,
+	},
+	{
+		version: 5,
+		name:    "ticket_links",
+		sql: `
+			CREATE TABLE lisa_ticket_links (
+				ticket_key    TEXT NOT NULL,
+				wa_chat       TEXT NOT NULL,
+				wa_message_id TEXT NOT NULL,
+				created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+				PRIMARY KEY (ticket_key, wa_chat, wa_message_id)
+			);
+			CREATE INDEX lisa_ticket_links_chat_idx
+				ON lisa_ticket_links (wa_chat, created_at DESC);
+		`,
+	},
+	{
+		version: 6,
+		name:    "triage_sources",
+		sql: `
+			ALTER TABLE lisa_triage_items
+				ADD COLUMN source      TEXT NOT NULL DEFAULT 'whatsapp',
+				ADD COLUMN project_key TEXT NOT NULL DEFAULT '';
+		`,
+	},
+	{
+		version: 7,
+		name:    "source_messages",
+		sql: `
+			CREATE TABLE lisa_source_messages (
+				id          BIGSERIAL PRIMARY KEY,
+				source      TEXT NOT NULL,
+				chat        TEXT NOT NULL,
+				message_id  TEXT NOT NULL,
+				sender      TEXT NOT NULL DEFAULT '',
+				sender_name TEXT NOT NULL DEFAULT '',
+				text        TEXT NOT NULL,
+				project_key TEXT NOT NULL DEFAULT '',
+				sent_at     TIMESTAMPTZ NOT NULL,
+				created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+				UNIQUE (source, chat, message_id)
+			);
+			CREATE INDEX lisa_source_messages_chat_idx
+				ON lisa_source_messages (chat, sent_at DESC);
+		`,
+	},
+	{
+		version: 8,
+		name:    "ticket_attachments",
+		sql: `
+			CREATE TABLE lisa_ticket_attachments (
+				ticket_key TEXT NOT NULL,
+				sha256     TEXT NOT NULL,
+				filename   TEXT NOT NULL DEFAULT '',
+				created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+				PRIMARY KEY (ticket_key, sha256)
+			);
+		`,
+	},
+}
+
+// Migrate aplica en orden las migraciones pendientes, cada una en su transacción
+func (r *Repository) Migrate(ctx context.Context) error {
+	_, err := r.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS lisa_schema_migrations (
+			version    INTEGER PRIMARY KEY,
+			name       TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`)
+	if err != nil {
+		return fmt.Errorf("no se pudo crear la tabla de migraciones: %w", err)
+	}
+
+	var current int
+	err = r.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM lisa_schema_migrations`).Scan(&current)
+	if err != nil {
+		return fmt.Errorf("no se pudo leer la versión del esquema: %w", err)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+
+		tx, err := r.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, m.sql); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migración %d (%s) falló: %w", m.version, m.name, err)
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO lisa_schema_migrations (version, name) VALUES ($1, $2)`, m.version, m.name); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		log.Printf("DB: Migracion %d aplicada (%s)", m.version, m.name)
+	}
+
+	return nil
+}
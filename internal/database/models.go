@@ -1 +1,106 @@
 package database
+
+import "time"
+
+// ParticipantEvent registro de auditoría de un cambio de participantes en un grupo
+type ParticipantEvent struct {
+	ID          int64     `json:"id"`
+	GroupJID    string    `json:"group_jid"`
+	GroupName   string    `json:"group_name"`
+	Participant string    `json:"participant"`
+	Action      string    `json:"action"`
+	Actor       string    `json:"actor,omitempty"`
+	Reason      string    `json:"reason,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Direcciones de un mensaje del puente
+const (
+	DirectionToDiscord  = "wa_to_discord"
+	DirectionToWhatsApp = "discord_to_wa"
+)
+
+// BridgeMessage relación entre un mensaje de WhatsApp y su copia en Discord,
+// usada para mantener el contexto de las respuestas en ambos sentidos
+type BridgeMessage struct {
+	ID               int64     `json:"id"`
+	WAChat           string    `json:"wa_chat"`
+	WAMessageID      string    `json:"wa_message_id"`
+	WASender         string    `json:"wa_sender,omitempty"`
+	WAText           string    `json:"wa_text,omitempty"`
+	DiscordChannelID string    `json:"discord_channel_id"`
+	DiscordMessageID string    `json:"discord_message_id"`
+	Direction        string    `json:"direction"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// BridgeThread hilo de Discord asignado a un chat de WhatsApp
+type BridgeThread struct {
+	WAChat       string    `json:"wa_chat"`
+	ChannelID    string    `json:"channel_id"`
+	ThreadID     string    `json:"thread_id"`
+	Name         string    `json:"name"`
+	TicketKey    string    `json:"ticket_key,omitempty"`
+	Archived     bool      `json:"archived"`
+	LastActivity time.Time `json:"last_activity"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Estados de un caso de triaje
+const (
+	TriagePending   = "pending"
+	TriageTicket    = "ticket"
+	TriageDuplicate = "duplicate"
+	TriageReplied   = "replied"
+	TriageDismissed = "dismissed"
+)
+
+// TriageItem mensaje que la IA marcó como problema o pedido y que se publicó
+// en Discord para que un agente decida qué hacer. En los mensajes que llegan
+// de canales de Discord, WAChat y WAMessageID guardan el canal y el mensaje.
+type TriageItem struct {
+	ID               int64     `json:"id"`
+	Source           string    `json:"source"`
+	ProjectKey       string    `json:"project_key,omitempty"`
+	WAChat           string    `json:"wa_chat"`
+	WAMessageID      string    `json:"wa_message_id"`
+	WASender         string    `json:"wa_sender,omitempty"`
+	SenderName       string    `json:"sender_name,omitempty"`
+	Text             string    `json:"text"`
+	Category         string    `json:"category"`
+	Confidence       float64   `json:"confidence"`
+	Summary          string    `json:"summary,omitempty"`
+	Priority         string    `json:"priority,omitempty"`
+	IssueType        string    `json:"issue_type,omitempty"`
+	Suggestion       string    `json:"suggestion,omitempty"`
+	DiscordChannelID string    `json:"discord_channel_id,omitempty"`
+	DiscordMessageID string    `json:"discord_message_id,omitempty"`
+	Status           string    `json:"status"`
+	Outcome          string    `json:"outcome,omitempty"`
+	ActedBy          string    `json:"acted_by,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// TicketLink relación entre un ticket de Jira y un mensaje de WhatsApp que lo originó
+type TicketLink struct {
+	TicketKey   string    `json:"ticket_key"`
+	WAChat      string    `json:"wa_chat"`
+	WAMessageID string    `json:"wa_message_id"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// SourceMessage mensaje de un cliente recibido por una fuente monitoreada
+// (por ahora, canales de Discord), guardado como archivo de la conversación
+type SourceMessage struct {
+	ID         int64     `json:"id"`
+	Source     string    `json:"source"`
+	Chat       string    `json:"chat"`
+	MessageID  string    `json:"message_id"`
+	Sender     string    `json:"sender,omitempty"`
+	SenderName string    `json:"sender_name,omitempty"`
+	Text       string    `json:"text"`
+	ProjectKey string    `json:"project_key,omitempty"`
+	SentAt     time.Time `json:"sent_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -1 +1,444 @@
 package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+// Repository acceso a las tablas propias de Lisa en PostgreSQL
+type Repository struct {
+	db *sql.DB
+}
+
+// New abre la conexión y verifica que la base de datos responda
+func New(ctx context.Context, uri string) (*Repository, error) {
+	db, err := sql.Open("postgres", uri)
+	if err != nil {
+		return nil, fmt.Errorf("fallo al abrir PostgreSQL: %w", err)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("fallo al conectar a PostgreSQL: %w", err)
+	}
+	return &Repository{db: db}, nil
+}
+
+func (r *Repository) Close() error {
+	return r.db.Close()
+}
+
+func (r *Repository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+// SaveParticipantEvent guarda un cambio de participantes en el archivo
+func (r *Repository) SaveParticipantEvent(ctx context.Context, evt *ParticipantEvent) error {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO lisa_participant_events (group_jid, group_name, participant, action, actor, reason, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id`,
+		evt.GroupJID, evt.GroupName, evt.Participant, evt.Action, evt.Actor, evt.Reason, evt.CreatedAt,
+	).Scan(&evt.ID)
+	if err != nil {
+		return fmt.Errorf("no se pudo guardar el evento de participante: %w", err)
+	}
+	return nil
+}
+
+// ParticipantEvents devuelve el historial de acceso de un grupo, del más reciente al más antiguo
+func (r *Repository) ParticipantEvents(ctx context.Context, groupJID string, limit int) ([]ParticipantEvent, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, group_jid, group_name, participant, action, actor, reason, created_at
+		FROM lisa_participant_events
+		WHERE group_jid = $1
+		ORDER BY created_at DESC
+		LIMIT $2`, groupJID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo consultar eventos de participantes: %w", err)
+	}
+	defer rows.Close()
+
+	var events []ParticipantEvent
+	for rows.Next() {
+		var evt ParticipantEvent
+		if err := rows.Scan(&evt.ID, &evt.GroupJID, &evt.GroupName, &evt.Participant,
+			&evt.Action, &evt.Actor, &evt.Reason, &evt.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, evt)
+	}
+	return events, rows.Err()
+}
+
+// SaveBridgeMessage guarda la relación entre un mensaje de WhatsApp y su copia en Discord
+func (r *Repository) SaveBridgeMessage(ctx context.Context, msg *BridgeMessage) error {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO lisa_bridge_messages (wa_chat, wa_message_id, wa_sender, wa_text, discord_channel_id, discord_message_id, direction)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (wa_chat, wa_message_id) DO UPDATE
+			SET discord_channel_id = EXCLUDED.discord_channel_id,
+			    discord_message_id = EXCLUDED.discord_message_id
+		RETURNING id, created_at`,
+		msg.WAChat, msg.WAMessageID, msg.WASender, msg.WAText, msg.DiscordChannelID, msg.DiscordMessageID, msg.Direction,
+	).Scan(&msg.ID, &msg.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("no se pudo guardar el mensaje del puente: %w", err)
+	}
+	return nil
+}
+
+// BridgeMessageByWhatsApp busca la copia en Discord de un mensaje de WhatsApp.
+// Devuelve nil sin error si el mensaje no pasó por el puente.
+func (r *Repository) BridgeMessageByWhatsApp(ctx context.Context, chat, messageID string) (*BridgeMessage, error) {
+	return r.bridgeMessage(ctx, `WHERE wa_chat = $1 AND wa_message_id = $2`, chat, messageID)
+}
+
+// BridgeMessageByDiscord busca el mensaje de WhatsApp que corresponde a un mensaje de Discord.
+// Devuelve nil sin error si el mensaje no pasó por el puente.
+func (r *Repository) BridgeMessageByDiscord(ctx context.Context, discordMessageID string) (*BridgeMessage, error) {
+	return r.bridgeMessage(ctx, `WHERE discord_message_id = $1`, discordMessageID)
+}
+
+func (r *Repository) bridgeMessage(ctx context.Context, where string, args ...interface{}) (*BridgeMessage, error) {
+	var msg BridgeMessage
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, wa_chat, wa_message_id, wa_sender, wa_text, discord_channel_id, discord_message_id, direction, created_at
+		FROM lisa_bridge_messages `+where+`
+		ORDER BY id DESC
+		LIMIT 1`, args...,
+	).Scan(&msg.ID, &msg.WAChat, &msg.WAMessageID, &msg.WASender, &msg.WAText,
+		&msg.DiscordChannelID, &msg.DiscordMessageID, &msg.Direction, &msg.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo consultar el mensaje del puente: %w", err)
+	}
+	return &msg, nil
+}
+
+// SaveBridgeThread guarda (o reemplaza) el hilo asignado a un chat
+func (r *Repository) SaveBridgeThread(ctx context.Context, thread *BridgeThread) error {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO lisa_bridge_threads (wa_chat, channel_id, thread_id, name, ticket_key, archived, last_activity)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		ON CONFLICT (wa_chat) DO UPDATE
+			SET channel_id = EXCLUDED.channel_id,
+			    thread_id = EXCLUDED.thread_id,
+			    name = EXCLUDED.name,
+			    ticket_key = EXCLUDED.ticket_key,
+			    archived = EXCLUDED.archived,
+			    last_activity = NOW()
+		RETURNING last_activity, created_at`,
+		thread.WAChat, thread.ChannelID, thread.ThreadID, thread.Name, thread.TicketKey, thread.Archived,
+	).Scan(&thread.LastActivity, &thread.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("no se pudo guardar el hilo del puente: %w", err)
+	}
+	return nil
+}
+
+// BridgeThread devuelve el hilo de un chat, o nil si todavía no tiene
+func (r *Repository) BridgeThread(ctx context.Context, chat string) (*BridgeThread, error) {
+	threads, err := r.bridgeThreads(ctx, `WHERE wa_chat = $1`, chat)
+	if err != nil || len(threads) == 0 {
+		return nil, err
+	}
+	return &threads[0], nil
+}
+
+// BridgeThreadByID devuelve el chat asignado a un hilo de Discord, o nil si el hilo no es del puente
+func (r *Repository) BridgeThreadByID(ctx context.Context, threadID string) (*BridgeThread, error) {
+	threads, err := r.bridgeThreads(ctx, `WHERE thread_id = $1`, threadID)
+	if err != nil || len(threads) == 0 {
+		return nil, err
+	}
+	return &threads[0], nil
+}
+
+// BridgeThreadByTicket devuelve el hilo vinculado a un ticket, o nil si no hay
+func (r *Repository) BridgeThreadByTicket(ctx context.Context, ticketKey string) (*BridgeThread, error) {
+	threads, err := r.bridgeThreads(ctx, `WHERE ticket_key = $1`, ticketKey)
+	if err != nil || len(threads) == 0 {
+		return nil, err
+	}
+	return &threads[0], nil
+}
+
+// IdleBridgeThreads devuelve los hilos abiertos sin actividad desde before
+func (r *Repository) IdleBridgeThreads(ctx context.Context, before time.Time) ([]BridgeThread, error) {
+	return r.bridgeThreads(ctx, `WHERE NOT archived AND last_activity < $1`, before)
+}
+
+// TouchBridgeThread registra actividad en el hilo de un chat y su estado de archivo
+func (r *Repository) TouchBridgeThread(ctx context.Context, chat string, archived bool) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE lisa_bridge_threads SET archived = $2, last_activity = NOW() WHERE wa_chat = $1`,
+		chat, archived)
+	if err != nil {
+		return fmt.Errorf("no se pudo actualizar el hilo del puente: %w", err)
+	}
+	return nil
+}
+
+// SetBridgeThreadArchived marca el hilo como archivado sin contar como actividad
+func (r *Repository) SetBridgeThreadArchived(ctx context.Context, chat string, archived bool) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE lisa_bridge_threads SET archived = $2 WHERE wa_chat = $1`, chat, archived)
+	if err != nil {
+		return fmt.Errorf("no se pudo actualizar el hilo del puente: %w", err)
+	}
+	return nil
+}
+
+// DeleteBridgeThread elimina la asignación de un chat (por ejemplo, si el hilo se borró en Discord)
+func (r *Repository) DeleteBridgeThread(ctx context.Context, chat string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM lisa_bridge_threads WHERE wa_chat = $1`, chat)
+	if err != nil {
+		return fmt.Errorf("no se pudo eliminar el hilo del puente: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) bridgeThreads(ctx context.Context, where string, args ...interface{}) ([]BridgeThread, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT wa_chat, channel_id, thread_id, name, ticket_key, archived, last_activity, created_at
+		FROM lisa_bridge_threads `+where+`
+		ORDER BY last_activity`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo consultar los hilos del puente: %w", err)
+	}
+	defer rows.Close()
+
+	var threads []BridgeThread
+	for rows.Next() {
+		var t BridgeThread
+		if err := rows.Scan(&t.WAChat, &t.ChannelID, &t.ThreadID, &t.Name, &t.TicketKey,
+			&t.Archived, &t.LastActivity, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		threads = append(threads, t)
+	}
+	return threads, rows.Err()
+}
+
+// SaveTriageItem guarda un caso de triaje nuevo
+func (r *Repository) SaveTriageItem(ctx context.Context, item *TriageItem) error {
+	if item.Status == "" {
+		item.Status = TriagePending
+	}
+	if item.Source == "" {
+		item.Source = "whatsapp"
+	}
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO lisa_triage_items (wa_chat, wa_message_id, wa_sender, sender_name, text, category, confidence,
+			summary, priority, issue_type, suggestion, discord_channel_id, discord_message_id, status, source, project_key)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+		RETURNING id, created_at, updated_at`,
+		item.WAChat, item.WAMessageID, item.WASender, item.SenderName, item.Text, item.Category, item.Confidence,
+		item.Summary, item.Priority, item.IssueType, item.Suggestion, item.DiscordChannelID, item.DiscordMessageID, item.Status,
+		item.Source, item.ProjectKey,
+	).Scan(&item.ID, &item.CreatedAt, &item.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("no se pudo guardar el caso de triaje: %w", err)
+	}
+	return nil
+}
+
+// TriageItem devuelve un caso de triaje, o nil si no existe
+func (r *Repository) TriageItem(ctx context.Context, id int64) (*TriageItem, error) {
+	var item TriageItem
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, wa_chat, wa_message_id, wa_sender, sender_name, text, category, confidence, summary, priority,
+			issue_type, suggestion, discord_channel_id, discord_message_id, status, outcome, acted_by, created_at, updated_at,
+			source, project_key
+		FROM lisa_triage_items WHERE id = $1`, id,
+	).Scan(&item.ID, &item.WAChat, &item.WAMessageID, &item.WASender, &item.SenderName, &item.Text, &item.Category,
+		&item.Confidence, &item.Summary, &item.Priority, &item.IssueType, &item.Suggestion, &item.DiscordChannelID,
+		&item.DiscordMessageID, &item.Status, &item.Outcome, &item.ActedBy, &item.CreatedAt, &item.UpdatedAt,
+		&item.Source, &item.ProjectKey)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo consultar el caso de triaje: %w", err)
+	}
+	return &item, nil
+}
+
+// SetTriageMessage guarda dónde se publicó el caso en Discord
+func (r *Repository) SetTriageMessage(ctx context.Context, id int64, channelID, messageID string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE lisa_triage_items SET discord_channel_id = $2, discord_message_id = $3, updated_at = NOW()
+		WHERE id = $1`, id, channelID, messageID)
+	if err != nil {
+		return fmt.Errorf("no se pudo actualizar el caso de triaje: %w", err)
+	}
+	return nil
+}
+
+// ClaimTriageItem reserva un caso pendiente para un agente antes de ejecutar
+// la acción. Devuelve false si otro agente ya lo había tomado.
+func (r *Repository) ClaimTriageItem(ctx context.Context, id int64, status, actedBy string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE lisa_triage_items SET status = $2, acted_by = $3, updated_at = NOW()
+		WHERE id = $1 AND status = $4`, id, status, actedBy, TriagePending)
+	if err != nil {
+		return false, fmt.Errorf("no se pudo actualizar el caso de triaje: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// FinishTriageItem guarda el resultado de la acción sobre un caso reservado
+func (r *Repository) FinishTriageItem(ctx context.Context, id int64, outcome string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE lisa_triage_items SET outcome = $2, updated_at = NOW() WHERE id = $1`, id, outcome)
+	if err != nil {
+		return fmt.Errorf("no se pudo actualizar el caso de triaje: %w", err)
+	}
+	return nil
+}
+
+// ReleaseTriageItem devuelve a pendiente un caso cuya acción falló
+func (r *Repository) ReleaseTriageItem(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE lisa_triage_items SET status = $2, acted_by = '', updated_at = NOW() WHERE id = $1`, id, TriagePending)
+	if err != nil {
+		return fmt.Errorf("no se pudo actualizar el caso de triaje: %w", err)
+	}
+	return nil
+}
+
+// SaveTicketLinks vincula un ticket con los mensajes de WhatsApp que lo originaron
+func (r *Repository) SaveTicketLinks(ctx context.Context, ticketKey, chat string, messageIDs []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	for _, id := range messageIDs {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO lisa_ticket_links (ticket_key, wa_chat, wa_message_id)
+			VALUES ($1, $2, $3)
+			ON CONFLICT DO NOTHING`, ticketKey, chat, id)
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("no se pudo vincular el ticket %s: %w", ticketKey, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// TicketLinks devuelve los mensajes de WhatsApp vinculados a un ticket
+func (r *Repository) TicketLinks(ctx context.Context, ticketKey string) ([]TicketLink, error) {
+	return r.ticketLinks(ctx, `WHERE ticket_key = $1`, ticketKey)
+}
+
+// TicketLinksForChat devuelve los vínculos de un chat, del más nuevo al más antiguo
+func (r *Repository) TicketLinksForChat(ctx context.Context, chat string) ([]TicketLink, error) {
+	return r.ticketLinks(ctx, `WHERE wa_chat = $1`, chat)
+}
+
+// TicketKeyForMessage devuelve el ticket más reciente vinculado a un mensaje
+// de WhatsApp, o "" si no tiene
+func (r *Repository) TicketKeyForMessage(ctx context.Context, chat, messageID string) (string, error) {
+	links, err := r.ticketLinks(ctx, `WHERE wa_chat = $1 AND wa_message_id = $2`, chat, messageID)
+	if err != nil || len(links) == 0 {
+		return "", err
+	}
+	return links[0].TicketKey, nil
+}
+
+// HasTicketAttachment indica si ya se subió al ticket un archivo con ese hash SHA-256
+func (r *Repository) HasTicketAttachment(ctx context.Context, ticketKey, sha256 string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM lisa_ticket_attachments WHERE ticket_key = $1 AND sha256 = $2)`,
+		ticketKey, sha256,
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("no se pudo consultar los adjuntos de %s: %w", ticketKey, err)
+	}
+	return exists, nil
+}
+
+// SaveTicketAttachment registra un archivo subido al ticket
+func (r *Repository) SaveTicketAttachment(ctx context.Context, ticketKey, sha256, filename string) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO lisa_ticket_attachments (ticket_key, sha256, filename)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING`, ticketKey, sha256, filename)
+	if err != nil {
+		return fmt.Errorf("no se pudo registrar el adjunto de %s: %w", ticketKey, err)
+	}
+	return nil
+}
+
+func (r *Repository) ticketLinks(ctx context.Context, where string, args ...interface{}) ([]TicketLink, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT ticket_key, wa_chat, wa_message_id, created_at
+		FROM lisa_ticket_links `+where+`
+		ORDER BY created_at DESC`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo consultar los vínculos de tickets: %w", err)
+	}
+	defer rows.Close()
+
+	var links []TicketLink
+	for rows.Next() {
+		var l TicketLink
+		if err := rows.Scan(&l.TicketKey, &l.WAChat, &l.WAMessageID, &l.CreatedAt); err != nil {
+			return nil, err
+		}
+		links = append(links, l)
+	}
+	return links, rows.Err()
+}
+
+// SaveSourceMessage archiva un mensaje de una fuente monitoreada. Los mensajes
+// repetidos (misma fuente, chat e ID) se ignoran.
+func (r *Repository) SaveSourceMessage(ctx context.Context, msg *SourceMessage) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO lisa_source_messages (source, chat, message_id, sender, sender_name, text, project_key, sent_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (source, chat, message_id) DO NOTHING`,
+		msg.Source, msg.Chat, msg.MessageID, msg.Sender, msg.SenderName, msg.Text, msg.ProjectKey, msg.SentAt,
+	)
+	if err != nil {
+		return fmt.Errorf("no se pudo archivar el mensaje %s: %w", msg.MessageID, err)
+	}
+	return nil
+}
+
+// SourceMessages devuelve los últimos mensajes archivados de un chat, del más
+// antiguo al más nuevo
+func (r *Repository) SourceMessages(ctx context.Context, chat string, limit int) ([]SourceMessage, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, source, chat, message_id, sender, sender_name, text, project_key, sent_at, created_at
+		FROM (
+			SELECT * FROM lisa_source_messages
+			WHERE chat = $1
+			ORDER BY sent_at DESC
+			LIMIT $2
+		) recent
+		ORDER BY sent_at`, chat, limit)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo consultar el archivo de mensajes: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []SourceMessage
+	for rows.Next() {
+		var m SourceMessage
+		if err := rows.Scan(&m.ID, &m.Source, &m.Chat, &m.MessageID, &m.Sender, &m.SenderName,
+			&m.Text, &m.ProjectKey, &m.SentAt, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
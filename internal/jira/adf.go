@@ -0,0 +1,299 @@
+package jira
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ADFNode nodo de Atlassian Document Format, el formato de texto enriquecido
+// de las descripciones y comentarios de Jira Cloud
+type ADFNode struct {
+	Type    string                 `json:"type"`
+	Version int                    `json:"version,omitempty"`
+	Attrs   map[string]interface{} `json:"attrs,omitempty"`
+	Content []ADFNode              `json:"content,omitempty"`
+	Text    string                 `json:"text,omitempty"`
+	Marks   []ADFMark              `json:"marks,omitempty"`
+}
+
+// ADFMark formato de un texto (negrita, código, enlace...)
+type ADFMark struct {
+	Type  string                 `json:"type"`
+	Attrs map[string]interface{} `json:"attrs,omitempty"`
+}
+
+var (
+	fencePattern   = regexp.MustCompile("^\\s*```\\s*([\\w+-]*)\\s*$")
+	headingPattern = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	bulletPattern  = regexp.MustCompile(`^\s*[-*•]\s+(.*)$`)
+	orderedPattern = regexp.MustCompile(`^\s*\d+[.)]\s+(.*)$`)
+	quotePattern   = regexp.MustCompile(`^\s*>\s?(.*)$`)
+
+	// inlinePattern código, negrita (**x** o *x* de WhatsApp), cursiva, tachado y URLs
+	inlinePattern = regexp.MustCompile("`([^`\\n]+)`" +
+		`|\*\*([^*\n]+)\*\*` +
+		`|\*([^*\s][^*\n]*)\*` +
+		`|_([^_\s][^_\n]*)_` +
+		`|~~?([^~\n]+?)~~?` +
+		`|(https?://[^\s<>]+)`)
+)
+
+// MarkdownToADF convierte texto plano o Markdown (incluido el formato de
+// WhatsApp) a un documento ADF: párrafos con saltos de línea, títulos, listas,
+// citas, bloques de código, y código, negrita, cursiva, tachado y enlaces en línea
+func MarkdownToADF(text string) ADFNode {
+	doc := ADFNode{Type: "doc", Version: 1, Content: []ADFNode{}}
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	var paragraph []string
+	flush := func() {
+		if len(paragraph) > 0 {
+			doc.Content = append(doc.Content, adfParagraph(paragraph))
+			paragraph = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		if m := fencePattern.FindStringSubmatch(line); m != nil {
+			flush()
+			var code []string
+			for i++; i < len(lines) && !fencePattern.MatchString(lines[i]); i++ {
+				code = append(code, lines[i])
+			}
+			block := ADFNode{Type: "codeBlock"}
+			if m[1] != "" {
+				block.Attrs = map[string]interface{}{"language": m[1]}
+			}
+			if body := strings.Join(code, "\n"); body != "" {
+				block.Content = []ADFNode{{Type: "text", Text: body}}
+			}
+			doc.Content = append(doc.Content, block)
+			continue
+		}
+
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+
+		if m := headingPattern.FindStringSubmatch(line); m != nil {
+			flush()
+			doc.Content = append(doc.Content, ADFNode{
+				Type:    "heading",
+				Attrs:   map[string]interface{}{"level": len(m[1])},
+				Content: adfInline(m[2]),
+			})
+			continue
+		}
+
+		if listPattern := listKind(line); listPattern != nil {
+			flush()
+			list := ADFNode{Type: "bulletList"}
+			if listPattern == orderedPattern {
+				list.Type = "orderedList"
+			}
+			for ; i < len(lines); i++ {
+				m := listPattern.FindStringSubmatch(lines[i])
+				if m == nil {
+					break
+				}
+				list.Content = append(list.Content, ADFNode{
+					Type:    "listItem",
+					Content: []ADFNode{adfParagraph([]string{m[1]})},
+				})
+			}
+			i--
+			doc.Content = append(doc.Content, list)
+			continue
+		}
+
+		if quotePattern.MatchString(line) {
+			flush()
+			var quoted []string
+			for ; i < len(lines); i++ {
+				m := quotePattern.FindStringSubmatch(lines[i])
+				if m == nil {
+					break
+				}
+				quoted = append(quoted, m[1])
+			}
+			i--
+			doc.Content = append(doc.Content, ADFNode{
+				Type:    "blockquote",
+				Content: []ADFNode{adfParagraph(quoted)},
+			})
+			continue
+		}
+
+		paragraph = append(paragraph, line)
+	}
+	flush()
+
+	return doc
+}
+
+func listKind(line string) *regexp.Regexp {
+	switch {
+	case bulletPattern.MatchString(line):
+		return bulletPattern
+	case orderedPattern.MatchString(line):
+		return orderedPattern
+	}
+	return nil
+}
+
+// adfParagraph párrafo con un salto de línea entre cada línea
+func adfParagraph(lines []string) ADFNode {
+	p := ADFNode{Type: "paragraph"}
+	for i, line := range lines {
+		if i > 0 {
+			p.Content = append(p.Content, ADFNode{Type: "hardBreak"})
+		}
+		p.Content = append(p.Content, adfInline(line)...)
+	}
+	return p
+}
+
+// adfInline convierte el formato en línea. Los marcadores pegados a letras o
+// números (como en snake_case) se dejan como texto.
+func adfInline(text string) []ADFNode {
+	var nodes []ADFNode
+	plain := func(s string) {
+		if s == "" {
+			return
+		}
+		if n := len(nodes); n > 0 && nodes[n-1].Type == "text" && len(nodes[n-1].Marks) == 0 {
+			nodes[n-1].Text += s
+			return
+		}
+		nodes = append(nodes, ADFNode{Type: "text", Text: s})
+	}
+
+	pos := 0
+	for _, m := range inlinePattern.FindAllStringSubmatchIndex(text, -1) {
+		start, end := m[0], m[1]
+		if start < pos {
+			continue
+		}
+		isURL := m[12] >= 0
+		if !isURL && start > 0 && isWordByte(text[start-1]) {
+			continue
+		}
+
+		plain(text[pos:start])
+		switch {
+		case m[2] >= 0:
+			nodes = append(nodes, adfMarked(text[m[2]:m[3]], "code"))
+		case m[4] >= 0:
+			nodes = append(nodes, adfMarked(text[m[4]:m[5]], "strong"))
+		case m[6] >= 0:
+			nodes = append(nodes, adfMarked(text[m[6]:m[7]], "strong"))
+		case m[8] >= 0:
+			nodes = append(nodes, adfMarked(text[m[8]:m[9]], "em"))
+		case m[10] >= 0:
+			nodes = append(nodes, adfMarked(text[m[10]:m[11]], "strike"))
+		case isURL:
+			link := strings.TrimRight(text[m[12]:m[13]], ".,;:!?)")
+			end = m[12] + len(link)
+			nodes = append(nodes, ADFNode{
+				Type:  "text",
+				Text:  link,
+				Marks: []ADFMark{{Type: "link", Attrs: map[string]interface{}{"href": link}}},
+			})
+		}
+		pos = end
+	}
+	plain(text[pos:])
+	return nodes
+}
+
+func adfMarked(text, mark string) ADFNode {
+	return ADFNode{Type: "text", Text: text, Marks: []ADFMark{{Type: mark}}}
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+// ADFText convierte un documento ADF a texto plano, para mostrar descripciones
+// y comentarios en WhatsApp y Discord. Si el valor es una cadena (texto sin
+// formato) se devuelve tal cual.
+func ADFText(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var doc ADFNode
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return ""
+	}
+
+	var b strings.Builder
+	writeADF(&b, doc, "")
+	return strings.TrimSpace(b.String())
+}
+
+func writeADF(b *strings.Builder, n ADFNode, indent string) {
+	attr := func(key string) string {
+		v, _ := n.Attrs[key].(string)
+		return v
+	}
+
+	switch n.Type {
+	case "text":
+		b.WriteString(n.Text)
+		return
+	case "hardBreak":
+		b.WriteString("\n" + indent)
+		return
+	case "mention", "emoji":
+		b.WriteString(attr("text"))
+		return
+	case "inlineCard":
+		b.WriteString(attr("url"))
+		return
+	case "bulletList", "orderedList":
+		for i, item := range n.Content {
+			bullet := "- "
+			if n.Type == "orderedList" {
+				bullet = strconv.Itoa(i+1) + ". "
+			}
+			b.WriteString(indent + bullet)
+			for _, c := range item.Content {
+				writeADF(b, c, indent+"  ")
+			}
+		}
+		return
+	case "codeBlock":
+		b.WriteString("```\n")
+		for _, c := range n.Content {
+			writeADF(b, c, indent)
+		}
+		b.WriteString("\n```\n")
+		return
+	case "blockquote":
+		var inner strings.Builder
+		for _, c := range n.Content {
+			writeADF(&inner, c, "")
+		}
+		for _, line := range strings.Split(strings.TrimSpace(inner.String()), "\n") {
+			b.WriteString(indent + "> " + line + "\n")
+		}
+		return
+	}
+
+	for _, c := range n.Content {
+		writeADF(b, c, indent)
+	}
+	switch n.Type {
+	case "paragraph", "heading":
+		b.WriteString("\n")
+	}
+}
@@ -0,0 +1,161 @@
+package jira
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// describeADF representación compacta de un nodo para comparar en las
+// pruebas: tipo[hijos], marca(texto) y ⏎ para los saltos de línea
+func describeADF(n ADFNode) string {
+	switch n.Type {
+	case "text":
+		if len(n.Marks) == 0 {
+			return n.Text
+		}
+		if href, ok := n.Marks[0].Attrs["href"]; ok {
+			return fmt.Sprintf("link(%s>%s)", n.Text, href)
+		}
+		return n.Marks[0].Type + "(" + n.Text + ")"
+	case "hardBreak":
+		return "⏎"
+	}
+
+	name := n.Type
+	if level, ok := n.Attrs["level"]; ok {
+		name += fmt.Sprintf(":%v", level)
+	}
+	if lang, ok := n.Attrs["language"]; ok {
+		name += fmt.Sprintf(":%v", lang)
+	}
+	var children []string
+	for _, c := range n.Content {
+		children = append(children, describeADF(c))
+	}
+	return name + "[" + strings.Join(children, "") + "]"
+}
+
+func TestAdfInline(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"texto plano", "hola mundo", "hola mundo"},
+		{"snake_case", "revisar user_id_nuevo y mi_var_", "revisar user_id_nuevo y mi_var_"},
+		{"asteriscos entre números", "2*3*4", "2*3*4"},
+		{"negrita de WhatsApp", "es *urgente* hoy", "es strong(urgente) hoy"},
+		{"negrita de Markdown", "**muy** importante", "strong(muy) importante"},
+		{"cursiva", "_quizás_ mañana", "em(quizás) mañana"},
+		{"tachado", "~viejo~ y ~~más viejo~~", "strike(viejo) y strike(más viejo)"},
+		{"código", "ejecutar `a*b*_c_`", "ejecutar code(a*b*_c_)"},
+		{"URL", "ver https://example.com/a?b=1 ahora", "ver link(https://example.com/a?b=1>https://example.com/a?b=1) ahora"},
+		{"URL con punto final", "Entre a https://example.com/x.", "Entre a link(https://example.com/x>https://example.com/x)."},
+		{"URL entre paréntesis", "(https://example.com/x)", "(link(https://example.com/x>https://example.com/x))"},
+		{"URL con signos", "¿vio https://example.com/x?!", "¿vio link(https://example.com/x>https://example.com/x)?!"},
+		{"URL pegada a una palabra", "url:https://example.com", "url:link(https://example.com>https://example.com)"},
+		{"marcador sin cerrar", "precio *especial", "precio *especial"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var parts []string
+			for _, n := range adfInline(tt.in) {
+				parts = append(parts, describeADF(n))
+			}
+			if got := strings.Join(parts, ""); got != tt.want {
+				t.Errorf("adfInline(%q) = %q, se esperaba %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkdownToADF(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"vacío", "", "doc[]"},
+		{"saltos de línea", "Hola\nmundo", "doc[paragraph[Hola⏎mundo]]"},
+		{"CRLF", "Hola\r\nmundo", "doc[paragraph[Hola⏎mundo]]"},
+		{"párrafos", "uno\n\n\ndos", "doc[paragraph[uno]paragraph[dos]]"},
+		{"título", "## Pasos *clave*\ntexto", "doc[heading:2[Pasos strong(clave)]paragraph[texto]]"},
+		{
+			"lista con viñetas",
+			"Pasos:\n- abrir\n* cerrar\n• guardar\nfin",
+			"doc[paragraph[Pasos:]bulletList[listItem[paragraph[abrir]]listItem[paragraph[cerrar]]listItem[paragraph[guardar]]]paragraph[fin]]",
+		},
+		{
+			"lista numerada",
+			"1. uno\n2) dos",
+			"doc[orderedList[listItem[paragraph[uno]]listItem[paragraph[dos]]]]",
+		},
+		{
+			"listas de distinto tipo",
+			"- a\n1. b",
+			"doc[bulletList[listItem[paragraph[a]]]orderedList[listItem[paragraph[b]]]]",
+		},
+		{
+			"cita de varias líneas",
+			"> primera\n>segunda\ndespués",
+			"doc[blockquote[paragraph[primera⏎segunda]]paragraph[después]]",
+		},
+		{
+			"bloque de código",
+			"antes\n```go\nx := *y*\n\n# no es título\n```\ndespués",
+			"doc[paragraph[antes]codeBlock:go[x := *y*\n\n# no es título]paragraph[después]]",
+		},
+		{"bloque de código vacío", "```\n```", "doc[codeBlock[]]"},
+		{"bloque de código sin cerrar", "```\nSELECT 1", "doc[codeBlock[SELECT 1]]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := MarkdownToADF(tt.in)
+			if doc.Version != 1 {
+				t.Errorf("Version = %d, se esperaba 1", doc.Version)
+			}
+			if got := describeADF(doc); got != tt.want {
+				t.Errorf("MarkdownToADF(%q)\n got %q\nwant %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestADFText(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"nulo", "null", ""},
+		{"cadena", `"texto sin formato"`, "texto sin formato"},
+		{"inválido", `{"type": 1}`, ""},
+		{"vacío", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ADFText(json.RawMessage(tt.in)); got != tt.want {
+				t.Errorf("ADFText(%s) = %q, se esperaba %q", tt.in, got, tt.want)
+			}
+		})
+	}
+
+	roundTrip := []struct{ in, want string }{
+		{"Hola\nmundo", "Hola\nmundo"},
+		{"- uno\n- dos", "- uno\n- dos"},
+		{"1. uno\n2. dos", "1. uno\n2. dos"},
+		{"> cita", "> cita"},
+		{"ver *esto* y https://example.com.", "ver esto y https://example.com."},
+	}
+	for _, tt := range roundTrip {
+		data, err := json.Marshal(MarkdownToADF(tt.in))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ADFText(data); got != tt.want {
+			t.Errorf("ADFText(MarkdownToADF(%q)) = %q, se esperaba %q", tt.in, got, tt.want)
+		}
+	}
+}
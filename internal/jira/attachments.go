@@ -0,0 +1,190 @@
+package jira
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"time"
+
+	"Lisa/pkg/types"
+)
+
+// attachmentMemory tiempo que se recuerda el hash de un adjunto subido
+const attachmentMemory = 24 * time.Hour
+
+// Attachment adjunto de un issue
+type Attachment struct {
+	ID       string `json:"id"`
+	Filename string `json:"filename"`
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Content  string `json:"content"`
+}
+
+// AttachmentStore registro persistente de los archivos subidos a cada
+// ticket, por hash SHA-256 del contenido
+type AttachmentStore interface {
+	HasTicketAttachment(ctx context.Context, ticketKey, sha256 string) (bool, error)
+	SaveTicketAttachment(ctx context.Context, ticketKey, sha256, filename string) error
+}
+
+// SetAttachmentStore guarda los adjuntos subidos en store, para no repetirlos
+// después de reiniciar
+func (c *Client) SetAttachmentStore(store AttachmentStore) {
+	c.attachments = store
+}
+
+// AttachmentTooLargeError el archivo supera JIRA_ATTACHMENT_MAX_MB y no se subió
+type AttachmentTooLargeError struct {
+	Filename string
+	Size     int64
+	Limit    int64
+}
+
+func (e *AttachmentTooLargeError) Error() string {
+	return fmt.Sprintf("%s pesa %d MB y el límite de Jira es %d MB", e.Filename, e.Size>>20, e.Limit>>20)
+}
+
+// UploadAttachment sube un archivo al issue (multipart con X-Atlassian-Token)
+func (c *Client) UploadAttachment(ctx context.Context, key, filename, mimeType string, data []byte) ([]Attachment, error) {
+	if c.attachmentMaxBytes > 0 && int64(len(data)) > c.attachmentMaxBytes {
+		return nil, &AttachmentTooLargeError{Filename: filename, Size: int64(len(data)), Limit: c.attachmentMaxBytes}
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename=%q`, filename))
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	header.Set("Content-Type", mimeType)
+	part, err := form.CreatePart(header)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(data); err != nil {
+		return nil, err
+	}
+	if err := form.Close(); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.send(ctx, request{
+		method:      http.MethodPost,
+		path:        "/issue/" + url.PathEscape(key) + "/attachments",
+		body:        body.Bytes(),
+		contentType: form.FormDataContentType(),
+		header:      http.Header{"X-Atlassian-Token": {"no-check"}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var attachments []Attachment
+	if err := decodeJSON(resp, &attachments); err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+// Attachments adjuntos actuales del issue
+func (c *Client) Attachments(ctx context.Context, key string) ([]Attachment, error) {
+	var issue struct {
+		Fields struct {
+			Attachment []Attachment `json:"attachment"`
+		} `json:"fields"`
+	}
+	query := url.Values{"fields": {"attachment"}}
+	if err := c.do(ctx, http.MethodGet, "/issue/"+url.PathEscape(key), query, nil, &issue); err != nil {
+		return nil, err
+	}
+	return issue.Fields.Attachment, nil
+}
+
+// AddAttachment adjunta un archivo de WhatsApp al ticket. Los archivos ya
+// subidos al mismo ticket (mismo contenido, o mismo nombre y tamaño) se omiten.
+func (c *Client) AddAttachment(ctx context.Context, key string, media *types.MediaMessage) error {
+	if media == nil || len(media.Data) == 0 {
+		return fmt.Errorf("el archivo no se descargó")
+	}
+
+	filename := media.Filename
+	if filename == "" {
+		filename = media.Info.ID
+	}
+
+	sum := sha256.Sum256(media.Data)
+	hash := hex.EncodeToString(sum[:])
+	if c.seenAttachment(ctx, key, hash) {
+		log.Printf("JIRA: %s ya tiene %s, se omite", key, filename)
+		return nil
+	}
+
+	existing, err := c.Attachments(ctx, key)
+	if err != nil {
+		return err
+	}
+	for _, a := range existing {
+		if strings.EqualFold(a.Filename, filename) && a.Size == int64(len(media.Data)) {
+			log.Printf("JIRA: %s ya tiene %s, se omite", key, filename)
+			c.rememberAttachment(ctx, key, hash, filename)
+			return nil
+		}
+	}
+
+	if _, err := c.UploadAttachment(ctx, key, filename, media.MimeType, media.Data); err != nil {
+		return err
+	}
+	c.rememberAttachment(ctx, key, hash, filename)
+	log.Printf("JIRA: %s adjuntado a %s (%d KB)", filename, key, len(media.Data)>>10)
+	return nil
+}
+
+// seenAttachment indica si el archivo ya se subió al ticket. Si el registro
+// falla, se sigue con la comparación contra los adjuntos de Jira.
+func (c *Client) seenAttachment(ctx context.Context, key, hash string) bool {
+	c.attachMu.Lock()
+	at, ok := c.attached[key+":"+hash]
+	c.attachMu.Unlock()
+	if ok && time.Since(at) < attachmentMemory {
+		return true
+	}
+
+	if c.attachments == nil {
+		return false
+	}
+	seen, err := c.attachments.HasTicketAttachment(ctx, key, hash)
+	if err != nil {
+		log.Printf("JIRA: %v", err)
+		return false
+	}
+	return seen
+}
+
+func (c *Client) rememberAttachment(ctx context.Context, key, hash, filename string) {
+	c.attachMu.Lock()
+	now := time.Now()
+	for h, at := range c.attached {
+		if now.Sub(at) > attachmentMemory {
+			delete(c.attached, h)
+		}
+	}
+	c.attached[key+":"+hash] = now
+	c.attachMu.Unlock()
+
+	if c.attachments != nil {
+		if err := c.attachments.SaveTicketAttachment(ctx, key, hash, filename); err != nil {
+			log.Printf("JIRA: %v", err)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package jira
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"Lisa/pkg/types"
+)
+
+// memoryAttachments AttachmentStore en memoria
+type memoryAttachments struct {
+	mu    sync.Mutex
+	saved map[string]string
+}
+
+func (m *memoryAttachments) HasTicketAttachment(ctx context.Context, ticketKey, sha256 string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.saved[ticketKey+":"+sha256]
+	return ok, nil
+}
+
+func (m *memoryAttachments) SaveTicketAttachment(ctx context.Context, ticketKey, sha256, filename string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.saved[ticketKey+":"+sha256] = filename
+	return nil
+}
+
+// attachmentServer Jira sin adjuntos que cuenta las subidas
+func attachmentServer(uploads *atomic.Int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/attachments") {
+			uploads.Add(1)
+			w.Write([]byte(`[{"id": "1", "filename": "captura.png"}]`))
+			return
+		}
+		w.Write([]byte(`{"fields": {"attachment": []}}`))
+	}
+}
+
+func TestAddAttachmentUsesStore(t *testing.T) {
+	store := &memoryAttachments{saved: make(map[string]string)}
+	media := &types.MediaMessage{Filename: "captura.png", MimeType: "image/png", Data: []byte("png")}
+
+	var uploads atomic.Int32
+	c := newTestClient(t, attachmentServer(&uploads))
+	c.SetAttachmentStore(store)
+	if err := c.AddAttachment(context.Background(), "PROJ-1", media); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.saved) != 1 {
+		t.Fatalf("registros = %v, se esperaba uno", store.saved)
+	}
+
+	// Otro cliente con el mismo registro, como después de reiniciar: Jira no
+	// muestra el adjunto (se renombró) pero el hash ya está guardado
+	restarted := newTestClient(t, attachmentServer(&uploads))
+	restarted.SetAttachmentStore(store)
+	if err := restarted.AddAttachment(context.Background(), "PROJ-1", media); err != nil {
+		t.Fatal(err)
+	}
+	// El mismo archivo en otro ticket sí se sube
+	if err := restarted.AddAttachment(context.Background(), "PROJ-2", media); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := uploads.Load(); got != 2 {
+		t.Errorf("subidas = %d, se esperaban 2", got)
+	}
+}
@@ -1 +1,483 @@
 package jira
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"Lisa/internal/config"
+)
+
+// Modos de Jira (JIRA_MODE)
+const (
+	ModeCloud      = "cloud"
+	ModeDataCenter = "datacenter"
+)
+
+const (
+	// Rutas base de la API REST: v3 en Jira Cloud, v2 en Server y Data Center
+	cloudAPIPrefix      = "/rest/api/3"
+	dataCenterAPIPrefix = "/rest/api/2"
+
+	// requestTimeout tiempo máximo de cada intento si el contexto no tiene plazo
+	requestTimeout = 30 * time.Second
+
+	// maxRetries reintentos ante 429 y errores 5xx (ver shouldRetry)
+	maxRetries = 3
+
+	// Espera entre reintentos cuando Jira no envía Retry-After
+	retryBackoff    = time.Second
+	maxRetryBackoff = 30 * time.Second
+
+	// defaultPageSize resultados por página en las consultas paginadas
+	defaultPageSize = 50
+)
+
+// Client cliente de la API REST de Jira. En Cloud se autentica con email y
+// API token; en Server y Data Center, con un token de acceso personal.
+type Client struct {
+	baseURL    *url.URL
+	email      string
+	token      string
+	projectKey string
+	http       *http.Client
+
+	// dataCenter usa la API v2, wiki markup y usuarios por nombre en vez de accountId
+	dataCenter bool
+	apiPrefix  string
+
+	// aliases nombre normalizado -> transiciones o estados candidatos
+	aliases map[string][]string
+
+	// Metadatos de creación por proyecto
+	metaTTL time.Duration
+	metaMu  sync.Mutex
+	meta    map[string]*ProjectMeta
+
+	// Adjuntos subidos (ticket:hash) para no repetirlos. Sin attachments
+	// solo se recuerdan en memoria.
+	attachmentMaxBytes int64
+	attachments        AttachmentStore
+	attachMu           sync.Mutex
+	attached           map[string]time.Time
+}
+
+// NewClient crea el cliente con JIRA_URL, JIRA_EMAIL y JIRA_TOKEN según JIRA_MODE.
+// En Data Center JIRA_TOKEN es un token de acceso personal y no hace falta email.
+func NewClient(cfg config.JiraConfig) (*Client, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("falta JIRA_URL")
+	}
+
+	dataCenter := false
+	switch cfg.Mode {
+	case "", ModeCloud:
+		if cfg.Email == "" || cfg.Token == "" {
+			return nil, fmt.Errorf("faltan JIRA_EMAIL o JIRA_TOKEN")
+		}
+	case ModeDataCenter:
+		if cfg.Token == "" {
+			return nil, fmt.Errorf("falta JIRA_TOKEN (token de acceso personal)")
+		}
+		dataCenter = true
+	default:
+		return nil, fmt.Errorf("JIRA_MODE inválido: %q", cfg.Mode)
+	}
+	apiPrefix := cloudAPIPrefix
+	if dataCenter {
+		apiPrefix = dataCenterAPIPrefix
+	}
+
+	base, err := url.Parse(strings.TrimSuffix(cfg.URL, "/"))
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return nil, fmt.Errorf("JIRA_URL inválido: %q", cfg.URL)
+	}
+
+	return &Client{
+		baseURL:    base,
+		email:      cfg.Email,
+		token:      cfg.Token,
+		projectKey: cfg.ProjectKey,
+		http:       &http.Client{Timeout: requestTimeout},
+		dataCenter: dataCenter,
+		apiPrefix:  apiPrefix,
+		aliases:    buildAliases(cfg.TransitionAliases),
+		metaTTL:    cfg.MetadataTTL,
+		meta:       make(map[string]*ProjectMeta),
+
+		attachmentMaxBytes: int64(cfg.AttachmentMaxMB) << 20,
+		attached:           make(map[string]time.Time),
+	}, nil
+}
+
+// BaseURL devuelve la URL del sitio de Jira
+func (c *Client) BaseURL() string {
+	return c.baseURL.String()
+}
+
+// ProjectKey devuelve el proyecto por defecto
+func (c *Client) ProjectKey() string {
+	return c.projectKey
+}
+
+// Mode devuelve ModeCloud o ModeDataCenter
+func (c *Client) Mode() string {
+	if c.dataCenter {
+		return ModeDataCenter
+	}
+	return ModeCloud
+}
+
+// richText convierte texto plano o Markdown al formato de descripciones y
+// comentarios: ADF en Cloud, wiki markup en Data Center
+func (c *Client) richText(text string) interface{} {
+	if c.dataCenter {
+		return MarkdownToWiki(text)
+	}
+	return MarkdownToADF(text)
+}
+
+// userRef referencia a un usuario en los campos de un issue: accountId en
+// Cloud, nombre de usuario en Data Center
+func (c *Client) userRef(id string) map[string]interface{} {
+	key := "accountId"
+	if c.dataCenter {
+		key = "name"
+	}
+	if id == "" {
+		return map[string]interface{}{key: nil}
+	}
+	return map[string]interface{}{key: id}
+}
+
+// APIError respuesta de error de Jira, con los mensajes que devuelve la API
+type APIError struct {
+	StatusCode int
+	Method     string
+	Path       string
+
+	// Messages errores generales (errorMessages)
+	Messages []string
+	// Fields errores por campo (errors), por ejemplo "summary" -> "Field is required"
+	Fields map[string]string
+}
+
+func (e *APIError) Error() string {
+	var parts []string
+	parts = append(parts, e.Messages...)
+	for field, msg := range e.Fields {
+		parts = append(parts, fmt.Sprintf("%s: %s", field, msg))
+	}
+	if len(parts) == 0 {
+		parts = append(parts, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("jira respondió %d en %s %s: %s", e.StatusCode, e.Method, e.Path, strings.Join(parts, "; "))
+}
+
+// IsNotFound indica si Jira respondió 404 (ticket o proyecto inexistente, o sin permiso para verlo)
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// IsUnauthorized indica si las credenciales fueron rechazadas
+func IsUnauthorized(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden)
+}
+
+// request petición a la API. Body ya serializado para poder reenviarlo en los reintentos.
+type request struct {
+	method      string
+	path        string
+	query       url.Values
+	body        []byte
+	contentType string
+	header      http.Header
+}
+
+// do envía una petición JSON y decodifica la respuesta en out (si no es nil)
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in, out interface{}) error {
+	req := request{method: method, path: path, query: query}
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("no se pudo serializar la petición a %s: %w", path, err)
+		}
+		req.body, req.contentType = data, "application/json"
+	}
+
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return decodeJSON(resp, out)
+}
+
+// decodeJSON decodifica el cuerpo de una respuesta exitosa en out (si no es nil)
+func decodeJSON(resp *http.Response, out interface{}) error {
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("respuesta inválida de %s %s: %w", resp.Request.Method, resp.Request.URL.Path, err)
+	}
+	return nil
+}
+
+// send ejecuta la petición con reintentos ante 429 y 5xx, respetando
+// Retry-After. Los errores de red solo se reintentan en GET. Devuelve la
+// respuesta 2xx con el cuerpo sin leer, o un *APIError.
+func (c *Client) send(ctx context.Context, r request) (*http.Response, error) {
+	u := *c.baseURL
+	u.Path += c.apiPrefix + r.path
+	if len(r.query) > 0 {
+		u.RawQuery = r.query.Encode()
+	}
+
+	for attempt := 0; ; attempt++ {
+		var body io.Reader
+		if r.body != nil {
+			body = bytes.NewReader(r.body)
+		}
+		req, err := http.NewRequestWithContext(ctx, r.method, u.String(), body)
+		if err != nil {
+			return nil, err
+		}
+		if c.dataCenter {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		} else {
+			req.SetBasicAuth(c.email, c.token)
+		}
+		req.Header.Set("Accept", "application/json")
+		if r.contentType != "" {
+			req.Header.Set("Content-Type", r.contentType)
+		}
+		for k, v := range r.header {
+			req.Header[k] = v
+		}
+
+		resp, err := c.http.Do(req)
+		if err != nil {
+			if ctx.Err() != nil || r.method != http.MethodGet || attempt >= maxRetries {
+				return nil, fmt.Errorf("no se pudo conectar con Jira (%s %s): %w", r.method, r.path, err)
+			}
+			if err := wait(ctx, backoff(attempt)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return resp, nil
+		}
+
+		if shouldRetry(r.method, resp) && attempt < maxRetries {
+			delay := retryAfter(resp.Header.Get("Retry-After"), backoff(attempt))
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			log.Printf("JIRA: %s %s respondió %d, reintento en %s", r.method, r.path, resp.StatusCode, delay)
+			if err := wait(ctx, delay); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		apiErr := parseError(resp)
+		apiErr.Method, apiErr.Path = r.method, r.path
+		resp.Body.Close()
+		return nil, apiErr
+	}
+}
+
+// shouldRetry indica si se reintenta una respuesta de error. Los GET se
+// reintentan ante 429 y cualquier 5xx. El resto de los métodos no son
+// idempotentes, como con los errores de red: solo se reintentan cuando Jira
+// rechazó la petición sin procesarla (429, o 503 con Retry-After).
+func shouldRetry(method string, resp *http.Response) bool {
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return true
+	case method == http.MethodGet:
+		return resp.StatusCode >= 500
+	default:
+		return resp.StatusCode == http.StatusServiceUnavailable && resp.Header.Get("Retry-After") != ""
+	}
+}
+
+// parseError lee el cuerpo de error de Jira ({"errorMessages": [...], "errors": {...}})
+func parseError(resp *http.Response) *APIError {
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+
+	var body struct {
+		ErrorMessages []string          `json:"errorMessages"`
+		Errors        map[string]string `json:"errors"`
+		Message       string            `json:"message"`
+	}
+	if err := json.Unmarshal(data, &body); err == nil {
+		apiErr.Messages = body.ErrorMessages
+		apiErr.Fields = body.Errors
+		if body.Message != "" {
+			apiErr.Messages = append(apiErr.Messages, body.Message)
+		}
+	} else if text := strings.TrimSpace(string(data)); text != "" && len(text) < 500 {
+		apiErr.Messages = []string{text}
+	}
+	return apiErr
+}
+
+// retryAfter interpreta Retry-After en segundos o como fecha HTTP
+func retryAfter(header string, fallback time.Duration) time.Duration {
+	if header == "" {
+		return fallback
+	}
+	if secs, err := strconv.Atoi(strings.TrimSpace(header)); err == nil {
+		return capBackoff(time.Duration(secs) * time.Second)
+	}
+	if at, err := http.ParseTime(header); err == nil {
+		return capBackoff(time.Until(at))
+	}
+	return fallback
+}
+
+func backoff(attempt int) time.Duration {
+	return capBackoff(retryBackoff << attempt)
+}
+
+func capBackoff(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	if d > maxRetryBackoff {
+		return maxRetryBackoff
+	}
+	return d
+}
+
+func wait(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// listOffset recorre una consulta paginada con startAt/maxResults y junta los
+// elementos de itemsKey ("values", "issues", "comments"...). limit <= 0 trae todos.
+func listOffset[T any](ctx context.Context, c *Client, path string, query url.Values, itemsKey string, limit int) ([]T, error) {
+	q := url.Values{}
+	for k, v := range query {
+		q[k] = v
+	}
+
+	var items []T
+	for start := 0; ; {
+		size := defaultPageSize
+		if limit > 0 && limit-len(items) < size {
+			size = limit - len(items)
+		}
+		q.Set("startAt", strconv.Itoa(start))
+		q.Set("maxResults", strconv.Itoa(size))
+
+		var page map[string]json.RawMessage
+		if err := c.do(ctx, http.MethodGet, path, q, nil, &page); err != nil {
+			return nil, err
+		}
+		var batch []T
+		if raw, ok := page[itemsKey]; ok {
+			if err := json.Unmarshal(raw, &batch); err != nil {
+				return nil, fmt.Errorf("respuesta inválida de %s: %w", path, err)
+			}
+		}
+		items = append(items, batch...)
+
+		var total int
+		var isLast bool
+		json.Unmarshal(page["total"], &total)
+		json.Unmarshal(page["isLast"], &isLast)
+
+		start += len(batch)
+		switch {
+		case len(batch) == 0, isLast:
+			return items, nil
+		case limit > 0 && len(items) >= limit:
+			return items[:limit], nil
+		case total > 0 && start >= total:
+			return items, nil
+		}
+	}
+}
+
+// listCursor recorre una consulta paginada con nextPageToken (como
+// /search/jql) y junta los elementos de itemsKey. limit <= 0 trae todos.
+func listCursor[T any](ctx context.Context, c *Client, path string, query url.Values, itemsKey string, limit int) ([]T, error) {
+	q := url.Values{}
+	for k, v := range query {
+		q[k] = v
+	}
+
+	var items []T
+	for {
+		size := defaultPageSize
+		if limit > 0 && limit-len(items) < size {
+			size = limit - len(items)
+		}
+		q.Set("maxResults", strconv.Itoa(size))
+
+		var page map[string]json.RawMessage
+		if err := c.do(ctx, http.MethodGet, path, q, nil, &page); err != nil {
+			return nil, err
+		}
+		var batch []T
+		if raw, ok := page[itemsKey]; ok {
+			if err := json.Unmarshal(raw, &batch); err != nil {
+				return nil, fmt.Errorf("respuesta inválida de %s: %w", path, err)
+			}
+		}
+		items = append(items, batch...)
+
+		var next string
+		var isLast bool
+		json.Unmarshal(page["nextPageToken"], &next)
+		json.Unmarshal(page["isLast"], &isLast)
+
+		switch {
+		case limit > 0 && len(items) >= limit:
+			return items[:limit], nil
+		case isLast, next == "", len(batch) == 0:
+			return items, nil
+		}
+		q.Set("nextPageToken", next)
+	}
+}
+
+// Myself devuelve el usuario de las credenciales configuradas
+func (c *Client) Myself(ctx context.Context) (*User, error) {
+	var user User
+	if err := c.do(ctx, http.MethodGet, "/myself", nil, nil, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Ping verifica la conexión y las credenciales
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.Myself(ctx)
+	return err
+}
@@ -0,0 +1,337 @@
+package jira
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"Lisa/internal/config"
+)
+
+// newTestClient cliente de Cloud contra un servidor httptest con el handler indicado
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	c, err := NewClient(config.JiraConfig{URL: srv.URL, Email: "lisa@example.com", Token: "token", ProjectKey: "PROJ"})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	return c
+}
+
+// failingServer responde status con los headers indicados las primeras fails
+// veces y después {} con 200. Cuenta las peticiones en calls.
+func failingServer(calls *atomic.Int32, fails int32, status int, header map[string]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= fails {
+			for k, v := range header {
+				w.Header().Set(k, v)
+			}
+			w.WriteHeader(status)
+			return
+		}
+		w.Write([]byte("{}"))
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	fallback := 7 * time.Second
+	tests := []struct {
+		name   string
+		header string
+		min    time.Duration
+		max    time.Duration
+	}{
+		{"vacío", "", fallback, fallback},
+		{"segundos", "2", 2 * time.Second, 2 * time.Second},
+		{"segundos con espacios", " 3 ", 3 * time.Second, 3 * time.Second},
+		{"se limita al máximo", "3600", maxRetryBackoff, maxRetryBackoff},
+		{"fecha HTTP", time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat), 8 * time.Second, 10 * time.Second},
+		{"fecha HTTP pasada", time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), 0, 0},
+		{"inválido", "pronto", fallback, fallback},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := retryAfter(tt.header, fallback)
+			if got < tt.min || got > tt.max {
+				t.Errorf("retryAfter(%q) = %s, se esperaba entre %s y %s", tt.header, got, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestSendRetries(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		status int
+		header map[string]string
+		calls  int32
+		ok     bool
+	}{
+		{"GET 429 en segundos", http.MethodGet, http.StatusTooManyRequests, map[string]string{"Retry-After": "0"}, 2, true},
+		{"GET 502 con fecha HTTP", http.MethodGet, http.StatusBadGateway, map[string]string{"Retry-After": time.Now().Add(-time.Second).UTC().Format(http.TimeFormat)}, 2, true},
+		{"POST 429", http.MethodPost, http.StatusTooManyRequests, map[string]string{"Retry-After": "0"}, 2, true},
+		{"POST 503 con Retry-After", http.MethodPost, http.StatusServiceUnavailable, map[string]string{"Retry-After": "0"}, 2, true},
+		{"POST 503 sin Retry-After", http.MethodPost, http.StatusServiceUnavailable, nil, 1, false},
+		{"POST 500", http.MethodPost, http.StatusInternalServerError, map[string]string{"Retry-After": "0"}, 1, false},
+		{"PUT 502", http.MethodPut, http.StatusBadGateway, map[string]string{"Retry-After": "0"}, 1, false},
+		{"GET 400 no se reintenta", http.MethodGet, http.StatusBadRequest, nil, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			c := newTestClient(t, failingServer(&calls, 1, tt.status, tt.header))
+
+			err := c.do(context.Background(), tt.method, "/issue/PROJ-1", nil, nil, nil)
+			if (err == nil) != tt.ok {
+				t.Errorf("do() error = %v, se esperaba ok = %v", err, tt.ok)
+			}
+			if got := calls.Load(); got != tt.calls {
+				t.Errorf("peticiones = %d, se esperaban %d", got, tt.calls)
+			}
+		})
+	}
+}
+
+func TestSendHonorsRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		header func() string
+	}{
+		{"segundos", func() string { return "1" }},
+		// Las fechas HTTP tienen resolución de segundos: se pide dos para esperar al menos uno
+		{"fecha HTTP", func() string { return time.Now().Add(2 * time.Second).UTC().Format(http.TimeFormat) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := tt.header()
+			var calls atomic.Int32
+			c := newTestClient(t, failingServer(&calls, 1, http.StatusTooManyRequests, map[string]string{"Retry-After": header}))
+
+			start := time.Now()
+			if err := c.do(context.Background(), http.MethodGet, "/myself", nil, nil, nil); err != nil {
+				t.Fatalf("do: %v", err)
+			}
+			if elapsed := time.Since(start); elapsed < 900*time.Millisecond {
+				t.Errorf("reintentó a los %s, antes de Retry-After %q", elapsed, header)
+			}
+			if got := calls.Load(); got != 2 {
+				t.Errorf("peticiones = %d, se esperaban 2", got)
+			}
+		})
+	}
+}
+
+func TestSendGivesUpAfterMaxRetries(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, failingServer(&calls, 100, http.StatusTooManyRequests, map[string]string{"Retry-After": "0"}))
+
+	err := c.do(context.Background(), http.MethodGet, "/myself", nil, nil, nil)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("error = %v, se esperaba APIError 429", err)
+	}
+	if got := calls.Load(); got != maxRetries+1 {
+		t.Errorf("peticiones = %d, se esperaban %d", got, maxRetries+1)
+	}
+}
+
+func TestSendCancelledDuringWait(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, failingServer(&calls, 100, http.StatusServiceUnavailable, map[string]string{"Retry-After": "30"}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := c.do(ctx, http.MethodGet, "/myself", nil, nil, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error = %v, se esperaba context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("la cancelación tardó %s", elapsed)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("peticiones = %d, se esperaba 1", got)
+	}
+}
+
+func TestAPIErrorDecoding(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		messages []string
+		fields   map[string]string
+		text     string
+	}{
+		{
+			name:     "errorMessages",
+			status:   http.StatusNotFound,
+			body:     `{"errorMessages": ["Issue does not exist"], "errors": {}}`,
+			messages: []string{"Issue does not exist"},
+			text:     "jira respondió 404 en GET /issue/PROJ-1: Issue does not exist",
+		},
+		{
+			name:   "errors por campo",
+			status: http.StatusBadRequest,
+			body:   `{"errorMessages": [], "errors": {"summary": "Field is required"}}`,
+			fields: map[string]string{"summary": "Field is required"},
+			text:   "jira respondió 400 en GET /issue/PROJ-1: summary: Field is required",
+		},
+		{
+			name:     "message",
+			status:   http.StatusUnauthorized,
+			body:     `{"message": "Client must be authenticated"}`,
+			messages: []string{"Client must be authenticated"},
+			text:     "jira respondió 401 en GET /issue/PROJ-1: Client must be authenticated",
+		},
+		{
+			name:     "texto plano",
+			status:   http.StatusForbidden,
+			body:     "Forbidden by proxy",
+			messages: []string{"Forbidden by proxy"},
+			text:     "jira respondió 403 en GET /issue/PROJ-1: Forbidden by proxy",
+		},
+		{
+			name:   "sin cuerpo",
+			status: http.StatusConflict,
+			text:   "jira respondió 409 en GET /issue/PROJ-1: Conflict",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			})
+
+			err := c.do(context.Background(), http.MethodGet, "/issue/PROJ-1", nil, nil, nil)
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("error = %v, se esperaba *APIError", err)
+			}
+			if apiErr.StatusCode != tt.status || fmt.Sprint(apiErr.Messages) != fmt.Sprint(tt.messages) || fmt.Sprint(apiErr.Fields) != fmt.Sprint(tt.fields) {
+				t.Errorf("APIError = %+v", apiErr)
+			}
+			if err.Error() != tt.text {
+				t.Errorf("Error() = %q, se esperaba %q", err.Error(), tt.text)
+			}
+		})
+	}
+}
+
+// pagedItem elemento de las respuestas paginadas de prueba
+type pagedItem struct {
+	ID int `json:"id"`
+}
+
+func TestListOffset(t *testing.T) {
+	tests := []struct {
+		name  string
+		total int
+		// isLast si se envía isLast en vez de total
+		isLast bool
+		limit  int
+		want   int
+		pages  int32
+	}{
+		{name: "por total", total: 5, want: 5, pages: 1},
+		{name: "por isLast", total: 120, isLast: true, want: 120, pages: 3},
+		{name: "con límite", total: 120, limit: 60, want: 60, pages: 2},
+		{name: "vacío", total: 0, want: 0, pages: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var pages atomic.Int32
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				pages.Add(1)
+				start, _ := strconv.Atoi(r.URL.Query().Get("startAt"))
+				size, _ := strconv.Atoi(r.URL.Query().Get("maxResults"))
+				var values []pagedItem
+				for i := start; i < start+size && i < tt.total; i++ {
+					values = append(values, pagedItem{ID: i})
+				}
+				page := map[string]interface{}{"values": values}
+				if tt.isLast {
+					page["isLast"] = start+size >= tt.total
+				} else {
+					page["total"] = tt.total
+				}
+				json.NewEncoder(w).Encode(page)
+			})
+
+			items, err := listOffset[pagedItem](context.Background(), c, "/project/search", url.Values{"query": {"a"}}, "values", tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(items) != tt.want {
+				t.Errorf("elementos = %d, se esperaban %d", len(items), tt.want)
+			}
+			for i, item := range items {
+				if item.ID != i {
+					t.Fatalf("elemento %d = %d, fuera de orden", i, item.ID)
+				}
+			}
+			if got := pages.Load(); got != tt.pages {
+				t.Errorf("páginas = %d, se esperaban %d", got, tt.pages)
+			}
+		})
+	}
+}
+
+func TestListCursor(t *testing.T) {
+	tests := []struct {
+		name  string
+		total int
+		limit int
+		want  int
+		pages int32
+	}{
+		{name: "una página", total: 10, want: 10, pages: 1},
+		{name: "varias páginas", total: 120, want: 120, pages: 3},
+		{name: "con límite", total: 120, limit: 70, want: 70, pages: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var pages atomic.Int32
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				pages.Add(1)
+				start, _ := strconv.Atoi(r.URL.Query().Get("nextPageToken"))
+				size, _ := strconv.Atoi(r.URL.Query().Get("maxResults"))
+				var issues []pagedItem
+				for i := start; i < start+size && i < tt.total; i++ {
+					issues = append(issues, pagedItem{ID: i})
+				}
+				page := map[string]interface{}{"issues": issues}
+				// La última página no trae nextPageToken
+				if start+size < tt.total {
+					page["nextPageToken"] = strconv.Itoa(start + size)
+				}
+				json.NewEncoder(w).Encode(page)
+			})
+
+			items, err := listCursor[pagedItem](context.Background(), c, "/search/jql", url.Values{"jql": {"project = PROJ"}}, "issues", tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(items) != tt.want {
+				t.Errorf("elementos = %d, se esperaban %d", len(items), tt.want)
+			}
+			if got := pages.Load(); got != tt.pages {
+				t.Errorf("páginas = %d, se esperaban %d", got, tt.pages)
+			}
+		})
+	}
+}
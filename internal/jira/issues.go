@@ -1 +1,281 @@
 package jira
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"Lisa/pkg/types"
+)
+
+const (
+	// defaultIssueType tipo de issue cuando el borrador no indica uno
+	defaultIssueType = "Task"
+
+	// lisaLabel etiqueta de los tickets creados por Lisa
+	lisaLabel = "lisa"
+)
+
+// issueFields campos que se piden por defecto al consultar un issue
+var issueFields = []string{
+	"summary", "description", "status", "priority", "issuetype", "resolution",
+	"assignee", "reporter", "labels", "components", "project", "created", "updated",
+}
+
+// IssueInput datos para crear un issue. Los campos vacíos se omiten.
+type IssueInput struct {
+	ProjectKey string
+	Summary    string
+	// Description en texto plano o Markdown, se convierte a ADF o wiki markup
+	Description string
+	IssueType   string
+	Priority    string
+	Labels      []string
+	Components  []string
+	// AssigneeID y ReporterID accountId en Cloud, nombre de usuario en Data Center
+	AssigneeID string
+	ReporterID string
+}
+
+// issueInputFields arma los campos de la petición de creación
+func (c *Client) issueInputFields(in IssueInput) map[string]interface{} {
+	issueType := in.IssueType
+	if issueType == "" {
+		issueType = defaultIssueType
+	}
+
+	fields := map[string]interface{}{
+		"project":   map[string]string{"key": in.ProjectKey},
+		"summary":   in.Summary,
+		"issuetype": map[string]string{"name": issueType},
+	}
+	if strings.TrimSpace(in.Description) != "" {
+		fields["description"] = c.richText(in.Description)
+	}
+	if in.Priority != "" {
+		fields["priority"] = map[string]string{"name": in.Priority}
+	}
+	if len(in.Labels) > 0 {
+		labels := make([]string, 0, len(in.Labels))
+		for _, l := range in.Labels {
+			// Jira no admite espacios en las etiquetas
+			if l = strings.Join(strings.Fields(l), "-"); l != "" {
+				labels = append(labels, l)
+			}
+		}
+		fields["labels"] = labels
+	}
+	if len(in.Components) > 0 {
+		components := make([]map[string]string, 0, len(in.Components))
+		for _, name := range in.Components {
+			components = append(components, map[string]string{"name": name})
+		}
+		fields["components"] = components
+	}
+	if in.AssigneeID != "" {
+		fields["assignee"] = c.userRef(in.AssigneeID)
+	}
+	if in.ReporterID != "" {
+		fields["reporter"] = c.userRef(in.ReporterID)
+	}
+	return fields
+}
+
+// CreateIssue crea un issue y devuelve su clave e ID
+func (c *Client) CreateIssue(ctx context.Context, in IssueInput) (*Issue, error) {
+	if in.ProjectKey == "" {
+		in.ProjectKey = c.projectKey
+	}
+	if strings.TrimSpace(in.Summary) == "" {
+		return nil, fmt.Errorf("el resumen del ticket es obligatorio")
+	}
+
+	var created Issue
+	body := map[string]interface{}{"fields": c.issueInputFields(in)}
+	if err := c.do(ctx, http.MethodPost, "/issue", nil, body, &created); err != nil {
+		return nil, err
+	}
+	log.Printf("JIRA: Issue %s creado en %s", created.Key, in.ProjectKey)
+	return &created, nil
+}
+
+// GetIssue consulta un issue. Sin fields se piden los campos que usa Lisa.
+func (c *Client) GetIssue(ctx context.Context, key string, fields ...string) (*Issue, error) {
+	if len(fields) == 0 {
+		fields = issueFields
+	}
+	query := url.Values{"fields": {strings.Join(fields, ",")}}
+
+	var issue Issue
+	if err := c.do(ctx, http.MethodGet, "/issue/"+url.PathEscape(key), query, nil, &issue); err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
+
+// EditIssue modifica campos de un issue con los valores de la API de Jira
+// (por ejemplo "priority": {"name": "High"}). Una descripción en texto se
+// convierte a ADF o wiki markup según el modo.
+func (c *Client) EditIssue(ctx context.Context, key string, fields map[string]interface{}) error {
+	if text, ok := fields["description"].(string); ok {
+		fields["description"] = c.richText(text)
+	}
+	body := map[string]interface{}{"fields": fields}
+	return c.do(ctx, http.MethodPut, "/issue/"+url.PathEscape(key), nil, body, nil)
+}
+
+// AddComment agrega un comentario en texto plano o Markdown
+func (c *Client) AddComment(ctx context.Context, key, text string) (*Comment, error) {
+	var comment Comment
+	body := map[string]interface{}{"body": c.richText(text)}
+	if err := c.do(ctx, http.MethodPost, "/issue/"+url.PathEscape(key)+"/comment", nil, body, &comment); err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// AssignIssue asigna el issue (accountId en Cloud, nombre de usuario en Data
+// Center). Un accountID vacío lo deja sin asignar.
+func (c *Client) AssignIssue(ctx context.Context, key, accountID string) error {
+	return c.do(ctx, http.MethodPut, "/issue/"+url.PathEscape(key)+"/assignee", nil, c.userRef(accountID), nil)
+}
+
+// IssueURL enlace al issue en el sitio de Jira
+func (c *Client) IssueURL(key string) string {
+	return c.BaseURL() + "/browse/" + key
+}
+
+// ticketInfo resume un issue para los comandos de WhatsApp y Discord
+func (c *Client) ticketInfo(issue *Issue) *types.TicketInfo {
+	f := issue.Fields
+	info := &types.TicketInfo{
+		Key:     issue.Key,
+		Summary: f.Summary,
+		URL:     c.IssueURL(issue.Key),
+		Updated: f.UpdatedAt(),
+	}
+	if f.Status != nil {
+		info.Status = f.Status.Name
+	}
+	if f.Priority != nil {
+		info.Priority = f.Priority.Name
+	}
+	if f.Assignee != nil {
+		info.Assignee = f.Assignee.DisplayName
+		info.AssigneeID = f.Assignee.ID()
+	}
+	return info
+}
+
+// CreateTicket crea un ticket a partir de un borrador, validado antes con los
+// metadatos del proyecto. Quién lo reportó y el chat de origen se agregan al
+// final de la descripción.
+func (c *Client) CreateTicket(ctx context.Context, draft types.TicketDraft) (*types.TicketInfo, error) {
+	draft.Labels = append([]string{lisaLabel}, draft.Labels...)
+	if draft.SourceChat != "" {
+		draft.Labels = append(draft.Labels, ChatLabel(draft.SourceChat))
+	}
+	draft, err := c.PrepareDraft(ctx, draft)
+	if err != nil {
+		return nil, err
+	}
+
+	description := strings.TrimSpace(draft.Description)
+	var origin []string
+	if draft.Reporter != "" {
+		origin = append(origin, "Reportado por: "+draft.Reporter)
+	}
+	if draft.SourceChat != "" {
+		origin = append(origin, "Origen: "+draft.SourceChat)
+	}
+	if len(origin) > 0 {
+		description += "\n\n" + strings.Join(origin, "\n")
+	}
+
+	in := IssueInput{
+		ProjectKey:  draft.ProjectKey,
+		Summary:     draft.Summary,
+		Description: description,
+		IssueType:   draft.IssueType,
+		Priority:    draft.Priority,
+		Labels:      draft.Labels,
+		Components:  draft.Components,
+	}
+	created, err := c.CreateIssue(ctx, in)
+	if err != nil {
+		// Un 400 suele indicar que cambió la configuración del proyecto
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusBadRequest {
+			c.RefreshProjectMeta(in.ProjectKey)
+		}
+		return nil, err
+	}
+
+	ticket, err := c.GetTicket(ctx, created.Key)
+	if err != nil {
+		// El ticket ya existe; se informa con los datos del borrador
+		log.Printf("JIRA: No se pudo leer %s recién creado: %v", created.Key, err)
+		return &types.TicketInfo{
+			Key:      created.Key,
+			Summary:  draft.Summary,
+			Priority: draft.Priority,
+			URL:      c.IssueURL(created.Key),
+		}, nil
+	}
+	return ticket, nil
+}
+
+// GetTicket consulta un ticket
+func (c *Client) GetTicket(ctx context.Context, key string) (*types.TicketInfo, error) {
+	issue, err := c.GetIssue(ctx, key, ticketFields...)
+	if err != nil {
+		return nil, err
+	}
+	return c.ticketInfo(issue), nil
+}
+
+// AssignTicket asigna el ticket y devuelve su estado actualizado
+func (c *Client) AssignTicket(ctx context.Context, key, accountID string) (*types.TicketInfo, error) {
+	if err := c.AssignIssue(ctx, key, accountID); err != nil {
+		return nil, err
+	}
+	return c.GetTicket(ctx, key)
+}
+
+// SetPriority cambia la prioridad del ticket y devuelve su estado actualizado
+func (c *Client) SetPriority(ctx context.Context, key, priority string) (*types.TicketInfo, error) {
+	fields := map[string]interface{}{"priority": map[string]string{"name": priority}}
+	if err := c.EditIssue(ctx, key, fields); err != nil {
+		return nil, err
+	}
+	return c.GetTicket(ctx, key)
+}
+
+// AssignableUsers usuarios que pueden asignarse al ticket, filtrados por nombre o email
+func (c *Client) AssignableUsers(ctx context.Context, key, query string) ([]types.JiraUser, error) {
+	params := url.Values{"issueKey": {key}, "maxResults": {"20"}}
+	if query != "" {
+		// Data Center filtra con username (nombre, usuario o email)
+		if c.dataCenter {
+			params.Set("username", query)
+		} else {
+			params.Set("query", query)
+		}
+	}
+
+	var users []User
+	if err := c.do(ctx, http.MethodGet, "/user/assignable/search", params, nil, &users); err != nil {
+		return nil, err
+	}
+	result := make([]types.JiraUser, 0, len(users))
+	for _, u := range users {
+		if u.Active {
+			result = append(result, types.JiraUser{AccountID: u.ID(), DisplayName: u.DisplayName, Email: u.EmailAddress})
+		}
+	}
+	return result, nil
+}
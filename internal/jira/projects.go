@@ -1 +1,297 @@
 package jira
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"Lisa/pkg/types"
+)
+
+// lisaFields campos que Lisa completa al crear un issue; el resto de los
+// obligatorios debe tener valor por defecto
+var lisaFields = map[string]bool{
+	"project": true, "summary": true, "issuetype": true, "description": true,
+	"priority": true, "labels": true, "components": true, "assignee": true, "reporter": true,
+}
+
+// ProjectMeta tipos de issue, prioridades, componentes, versiones y campos de
+// creación de un proyecto
+type ProjectMeta struct {
+	Key        string
+	Name       string
+	IssueTypes []IssueTypeMeta
+	Priorities []Named
+	Components []Named
+	Versions   []Named
+	Fetched    time.Time
+}
+
+// IssueTypeMeta tipo de issue con los campos de su pantalla de creación
+type IssueTypeMeta struct {
+	ID      string
+	Name    string
+	Subtask bool
+	Fields  map[string]FieldMeta
+}
+
+// FieldMeta campo de la pantalla de creación
+type FieldMeta struct {
+	FieldID         string  `json:"fieldId"`
+	Name            string  `json:"name"`
+	Required        bool    `json:"required"`
+	HasDefaultValue bool    `json:"hasDefaultValue"`
+	AllowedValues   []Named `json:"allowedValues,omitempty"`
+}
+
+// IssueType busca un tipo de issue por nombre o ID, sin distinguir mayúsculas
+func (m *ProjectMeta) IssueType(name string) (*IssueTypeMeta, bool) {
+	for i, t := range m.IssueTypes {
+		if t.ID == name || normalizeName(t.Name) == normalizeName(name) {
+			return &m.IssueTypes[i], true
+		}
+	}
+	return nil, false
+}
+
+// DraftError problemas de un borrador que harían fallar la creación del ticket
+type DraftError struct {
+	Project  string
+	Problems []string
+}
+
+func (e *DraftError) Error() string {
+	return fmt.Sprintf("el ticket no es válido para %s: %s", e.Project, strings.Join(e.Problems, "; "))
+}
+
+// ProjectMeta devuelve los metadatos del proyecto, guardados durante JIRA_METADATA_TTL
+func (c *Client) ProjectMeta(ctx context.Context, key string) (*ProjectMeta, error) {
+	key = strings.ToUpper(key)
+
+	c.metaMu.Lock()
+	meta, ok := c.meta[key]
+	c.metaMu.Unlock()
+	if ok && time.Since(meta.Fetched) < c.metaTTL {
+		return meta, nil
+	}
+
+	meta, err := c.fetchProjectMeta(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	c.metaMu.Lock()
+	c.meta[key] = meta
+	c.metaMu.Unlock()
+	return meta, nil
+}
+
+// RefreshProjectMeta descarta los metadatos guardados del proyecto (o de
+// todos si key está vacío) para que la próxima consulta los vuelva a leer
+func (c *Client) RefreshProjectMeta(key string) {
+	c.metaMu.Lock()
+	defer c.metaMu.Unlock()
+	if key == "" {
+		c.meta = make(map[string]*ProjectMeta)
+		return
+	}
+	delete(c.meta, strings.ToUpper(key))
+}
+
+// fetchProjectMeta lee el proyecto, sus componentes y versiones, y los campos
+// de creación de cada tipo de issue (createmeta)
+func (c *Client) fetchProjectMeta(ctx context.Context, key string) (*ProjectMeta, error) {
+	project := "/project/" + url.PathEscape(key)
+
+	var info struct {
+		Key  string `json:"key"`
+		Name string `json:"name"`
+	}
+	if err := c.do(ctx, http.MethodGet, project, nil, nil, &info); err != nil {
+		return nil, err
+	}
+	meta := &ProjectMeta{Key: info.Key, Name: info.Name, Fetched: time.Now()}
+
+	if err := c.do(ctx, http.MethodGet, project+"/components", nil, nil, &meta.Components); err != nil {
+		return nil, err
+	}
+	if err := c.do(ctx, http.MethodGet, project+"/versions", nil, nil, &meta.Versions); err != nil {
+		return nil, err
+	}
+
+	createmeta := "/issue/createmeta/" + url.PathEscape(key) + "/issuetypes"
+	// Cloud pagina los tipos en issueTypes y los campos en fields; Data Center
+	// usa values en ambos
+	typesKey, fieldsKey := "issueTypes", "fields"
+	if c.dataCenter {
+		typesKey, fieldsKey = "values", "values"
+	}
+	issueTypes, err := listOffset[struct {
+		ID      string `json:"id"`
+		Name    string `json:"name"`
+		Subtask bool   `json:"subtask"`
+	}](ctx, c, createmeta, nil, typesKey, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range issueTypes {
+		fields, err := listOffset[FieldMeta](ctx, c, createmeta+"/"+url.PathEscape(t.ID), nil, fieldsKey, 0)
+		if err != nil {
+			return nil, err
+		}
+		it := IssueTypeMeta{ID: t.ID, Name: t.Name, Subtask: t.Subtask, Fields: make(map[string]FieldMeta)}
+		for _, f := range fields {
+			it.Fields[f.FieldID] = f
+		}
+		if p, ok := it.Fields["priority"]; ok && len(meta.Priorities) == 0 {
+			meta.Priorities = p.AllowedValues
+		}
+		meta.IssueTypes = append(meta.IssueTypes, it)
+	}
+
+	log.Printf("JIRA: Metadatos de %s: %d tipos de issue, %d componentes", key, len(meta.IssueTypes), len(meta.Components))
+	return meta, nil
+}
+
+// PrepareDraft valida el borrador con los metadatos del proyecto antes de
+// crearlo: corrige mayúsculas de tipo, prioridad y componentes, descarta los
+// campos que el proyecto no admite y devuelve un *DraftError con las opciones
+// válidas si algo no existe o falta un campo obligatorio. Si no se pueden
+// leer los metadatos, el borrador sigue sin validar.
+func (c *Client) PrepareDraft(ctx context.Context, draft types.TicketDraft) (types.TicketDraft, error) {
+	if draft.ProjectKey == "" {
+		draft.ProjectKey = c.projectKey
+	}
+	draft.ProjectKey = strings.ToUpper(draft.ProjectKey)
+
+	meta, err := c.ProjectMeta(ctx, draft.ProjectKey)
+	if err != nil {
+		if IsNotFound(err) {
+			return draft, fmt.Errorf("el proyecto %s no existe o Lisa no tiene acceso", draft.ProjectKey)
+		}
+		log.Printf("JIRA: No se pudo validar el borrador para %s: %v", draft.ProjectKey, err)
+		return draft, nil
+	}
+
+	invalid := &DraftError{Project: draft.ProjectKey}
+
+	issueType, ok := meta.defaultIssueType(draft.IssueType)
+	if !ok {
+		invalid.Problems = append(invalid.Problems, fmt.Sprintf("el tipo %q no existe, opciones: %s", draft.IssueType, meta.issueTypeNames()))
+		return draft, invalid
+	}
+	draft.IssueType = issueType.Name
+
+	if draft.Priority != "" {
+		if _, allowed := issueType.Fields["priority"]; !allowed {
+			log.Printf("JIRA: %s/%s no admite prioridad, se omite %q", draft.ProjectKey, issueType.Name, draft.Priority)
+			draft.Priority = ""
+		} else if len(meta.Priorities) > 0 {
+			if p, found := findNamed(meta.Priorities, draft.Priority); found {
+				draft.Priority = p.Label()
+			} else {
+				invalid.Problems = append(invalid.Problems, fmt.Sprintf("la prioridad %q no existe, opciones: %s", draft.Priority, namedList(meta.Priorities)))
+			}
+		}
+	}
+
+	if len(draft.Components) > 0 {
+		if _, allowed := issueType.Fields["components"]; !allowed {
+			draft.Components = nil
+		} else {
+			for i, name := range draft.Components {
+				if comp, found := findNamed(meta.Components, name); found {
+					draft.Components[i] = comp.Label()
+				} else {
+					invalid.Problems = append(invalid.Problems, fmt.Sprintf("el componente %q no existe, opciones: %s", name, namedList(meta.Components)))
+				}
+			}
+		}
+	}
+
+	if _, allowed := issueType.Fields["labels"]; !allowed {
+		draft.Labels = nil
+	}
+
+	for id, f := range issueType.Fields {
+		if f.Required && !f.HasDefaultValue && !lisaFields[id] {
+			invalid.Problems = append(invalid.Problems, fmt.Sprintf("%s es obligatorio en %s y Lisa no puede completarlo", f.Name, issueType.Name))
+		}
+	}
+
+	if len(invalid.Problems) > 0 {
+		return draft, invalid
+	}
+	return draft, nil
+}
+
+// defaultIssueType devuelve el tipo pedido o, si está vacío, Task o el
+// primer tipo que no sea subtarea
+func (m *ProjectMeta) defaultIssueType(name string) (*IssueTypeMeta, bool) {
+	if name != "" {
+		return m.IssueType(name)
+	}
+	if t, ok := m.IssueType(defaultIssueType); ok && !t.Subtask {
+		return t, true
+	}
+	for i, t := range m.IssueTypes {
+		if !t.Subtask {
+			return &m.IssueTypes[i], true
+		}
+	}
+	return nil, false
+}
+
+func (m *ProjectMeta) issueTypeNames() string {
+	var names []string
+	for _, t := range m.IssueTypes {
+		if !t.Subtask {
+			names = append(names, t.Name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+func findNamed(values []Named, name string) (Named, bool) {
+	for _, v := range values {
+		if v.ID == name || normalizeName(v.Label()) == normalizeName(name) {
+			return v, true
+		}
+	}
+	return Named{}, false
+}
+
+func namedList(values []Named) string {
+	if len(values) == 0 {
+		return "(ninguna)"
+	}
+	names := make([]string, 0, len(values))
+	for _, v := range values {
+		names = append(names, v.Label())
+	}
+	return strings.Join(names, ", ")
+}
+
+// Projects proyectos visibles para el autocompletado de /ticket
+func (c *Client) Projects(ctx context.Context) ([]types.ProjectInfo, error) {
+	if c.dataCenter {
+		// Data Center no tiene /project/search: /project devuelve todos
+		var projects []types.ProjectInfo
+		if err := c.do(ctx, http.MethodGet, "/project", nil, nil, &projects); err != nil {
+			return nil, err
+		}
+		sort.Slice(projects, func(i, j int) bool { return projects[i].Key < projects[j].Key })
+		return projects, nil
+	}
+	projects, err := listOffset[types.ProjectInfo](ctx, c, "/project/search", url.Values{"orderBy": {"key"}}, "values", 0)
+	if err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
@@ -0,0 +1,155 @@
+package jira
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"Lisa/pkg/types"
+)
+
+// testProjectMeta proyecto WEB con Bug (prioridad y componentes), Task (sin
+// prioridad ni etiquetas), Sub-task y Incident (con un campo obligatorio propio)
+func testProjectMeta() *ProjectMeta {
+	priorities := []Named{{ID: "1", Name: "Highest"}, {ID: "2", Name: "High"}, {ID: "3", Name: "Medium"}}
+	common := map[string]FieldMeta{
+		"summary":   {FieldID: "summary", Name: "Summary", Required: true},
+		"issuetype": {FieldID: "issuetype", Name: "Issue Type", Required: true},
+	}
+	with := func(extra map[string]FieldMeta) map[string]FieldMeta {
+		fields := make(map[string]FieldMeta)
+		for k, v := range common {
+			fields[k] = v
+		}
+		for k, v := range extra {
+			fields[k] = v
+		}
+		return fields
+	}
+
+	return &ProjectMeta{
+		Key:        "WEB",
+		Name:       "Sitio web",
+		Priorities: priorities,
+		Components: []Named{{ID: "10", Name: "Checkout"}, {ID: "11", Name: "Login"}},
+		Fetched:    time.Now(),
+		IssueTypes: []IssueTypeMeta{
+			{ID: "1", Name: "Bug", Fields: with(map[string]FieldMeta{
+				"priority":   {FieldID: "priority", Name: "Priority", AllowedValues: priorities},
+				"components": {FieldID: "components", Name: "Component/s"},
+				"labels":     {FieldID: "labels", Name: "Labels"},
+			})},
+			{ID: "2", Name: "Sub-task", Subtask: true, Fields: with(nil)},
+			{ID: "3", Name: "Task", Fields: with(map[string]FieldMeta{
+				"environment": {FieldID: "environment", Name: "Environment", Required: true, HasDefaultValue: true},
+			})},
+			{ID: "4", Name: "Incident", Fields: with(map[string]FieldMeta{
+				"customfield_10020": {FieldID: "customfield_10020", Name: "Impacto", Required: true},
+			})},
+		},
+	}
+}
+
+func TestPrepareDraft(t *testing.T) {
+	tests := []struct {
+		name     string
+		draft    types.TicketDraft
+		want     types.TicketDraft
+		problems []string
+	}{
+		{
+			name:  "tipo por defecto y proyecto en mayúsculas",
+			draft: types.TicketDraft{ProjectKey: "web", Summary: "x", Labels: []string{"whatsapp"}},
+			want:  types.TicketDraft{ProjectKey: "WEB", Summary: "x", IssueType: "Task"},
+		},
+		{
+			name:  "corrige mayúsculas de tipo, prioridad y componentes",
+			draft: types.TicketDraft{ProjectKey: "WEB", IssueType: "bug", Priority: "high", Components: []string{"login", "10"}, Labels: []string{"whatsapp"}},
+			want:  types.TicketDraft{ProjectKey: "WEB", IssueType: "Bug", Priority: "High", Components: []string{"Login", "Checkout"}, Labels: []string{"whatsapp"}},
+		},
+		{
+			name:  "descarta lo que el tipo no admite",
+			draft: types.TicketDraft{ProjectKey: "WEB", IssueType: "Task", Priority: "High", Components: []string{"Login"}},
+			want:  types.TicketDraft{ProjectKey: "WEB", IssueType: "Task"},
+		},
+		{
+			name:     "tipo inexistente",
+			draft:    types.TicketDraft{ProjectKey: "WEB", IssueType: "Story"},
+			problems: []string{`el tipo "Story" no existe, opciones: Bug, Task, Incident`},
+		},
+		{
+			name:  "prioridad y componente inexistentes",
+			draft: types.TicketDraft{ProjectKey: "WEB", IssueType: "Bug", Priority: "Urgente", Components: []string{"Pagos"}},
+			problems: []string{
+				`la prioridad "Urgente" no existe, opciones: Highest, High, Medium`,
+				`el componente "Pagos" no existe, opciones: Checkout, Login`,
+			},
+		},
+		{
+			name:     "campo obligatorio sin valor por defecto",
+			draft:    types.TicketDraft{ProjectKey: "WEB", IssueType: "Incident"},
+			problems: []string{"Impacto es obligatorio en Incident y Lisa no puede completarlo"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Client{projectKey: "WEB", metaTTL: time.Hour, meta: map[string]*ProjectMeta{"WEB": testProjectMeta()}}
+
+			got, err := c.PrepareDraft(context.Background(), tt.draft)
+			if tt.problems != nil {
+				var draftErr *DraftError
+				if !errors.As(err, &draftErr) {
+					t.Fatalf("error = %v, se esperaba *DraftError", err)
+				}
+				if draftErr.Project != "WEB" || !reflect.DeepEqual(draftErr.Problems, tt.problems) {
+					t.Errorf("problemas = %q, se esperaba %q", draftErr.Problems, tt.problems)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PrepareDraft: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PrepareDraft() = %+v, se esperaba %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrepareDraftWithoutMetadata(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr string
+	}{
+		{"proyecto inexistente", http.StatusNotFound, "el proyecto NOPE no existe o Lisa no tiene acceso"},
+		// Sin metadatos el borrador sigue sin validar y Jira decide al crearlo
+		{"metadatos no disponibles", http.StatusForbidden, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			})
+
+			draft := types.TicketDraft{ProjectKey: "nope", IssueType: "cualquiera"}
+			got, err := c.PrepareDraft(context.Background(), draft)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("PrepareDraft: %v", err)
+				}
+				if got.ProjectKey != "NOPE" || got.IssueType != "cualquiera" {
+					t.Errorf("PrepareDraft() = %+v, se esperaba el borrador sin cambios", got)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, se esperaba %q", err, tt.wantErr)
+			}
+		})
+	}
+}
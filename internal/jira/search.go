@@ -0,0 +1,191 @@
+package jira
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"Lisa/pkg/types"
+)
+
+// recentDays antigüedad máxima de los tickets del autocompletado
+const recentDays = 30
+
+// ticketFields campos necesarios para resumir un ticket
+var ticketFields = []string{"summary", "status", "priority", "assignee", "updated"}
+
+var (
+	labelUnsafe  = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+	phonePattern = regexp.MustCompile(`^\+?[\d\s().-]+$`)
+	phoneDigits  = regexp.MustCompile(`\D`)
+)
+
+// ChatLabel etiqueta con la que se marcan los tickets creados desde un chat,
+// para encontrarlos luego con JQL
+func ChatLabel(chat string) string {
+	id, _, _ := strings.Cut(chat, "@")
+	return "chat-" + strings.Trim(labelUnsafe.ReplaceAllString(id, "-"), "-")
+}
+
+// JQL constructor de consultas JQL. Las condiciones se combinan con AND.
+type JQL struct {
+	clauses []string
+	order   string
+}
+
+// NewJQL crea una consulta vacía
+func NewJQL() *JQL {
+	return &JQL{}
+}
+
+// Where agrega una condición JQL tal cual
+func (q *JQL) Where(clause string) *JQL {
+	if clause != "" {
+		q.clauses = append(q.clauses, clause)
+	}
+	return q
+}
+
+// Project limita la consulta a un proyecto
+func (q *JQL) Project(key string) *JQL {
+	return q.Where("project = " + quoteJQL(strings.ToUpper(key)))
+}
+
+// Open deja solo los tickets que no están terminados
+func (q *JQL) Open() *JQL {
+	return q.Where("statusCategory != Done")
+}
+
+// Text busca tickets que mencionan el texto
+func (q *JQL) Text(text string) *JQL {
+	return q.Where("text ~ " + quoteJQL(text))
+}
+
+// Label busca tickets con la etiqueta
+func (q *JQL) Label(label string) *JQL {
+	return q.Where("labels = " + quoteJQL(label))
+}
+
+// FromChat busca tickets creados desde el chat
+func (q *JQL) FromChat(chat string) *JQL {
+	return q.Label(ChatLabel(chat))
+}
+
+// Customer busca tickets de un cliente: los que lo mencionan (Lisa agrega
+// quién reportó a la descripción) o, si se indica un chat o un teléfono, los
+// creados desde ese chat
+func (q *JQL) Customer(customer string) *JQL {
+	phrase := quoteJQL(`"` + strings.ReplaceAll(customer, `"`, "") + `"`)
+	chat := customer
+	if !strings.Contains(chat, "@") {
+		chat = phoneDigits.ReplaceAllString(customer, "")
+		if len(chat) < 6 || !phonePattern.MatchString(customer) {
+			return q.Where("text ~ " + phrase)
+		}
+	}
+	return q.Where(fmt.Sprintf("(labels = %s OR text ~ %s)", quoteJQL(ChatLabel(chat)), phrase))
+}
+
+// OrderBy define el orden, por ejemplo "updated DESC"
+func (q *JQL) OrderBy(order string) *JQL {
+	q.order = order
+	return q
+}
+
+// String arma la consulta
+func (q *JQL) String() string {
+	jql := strings.Join(q.clauses, " AND ")
+	if q.order != "" {
+		jql = strings.TrimSpace(jql + " ORDER BY " + q.order)
+	}
+	return jql
+}
+
+// quoteJQL encierra un valor entre comillas dobles escapando las especiales
+func quoteJQL(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+	return `"` + s + `"`
+}
+
+// OpenIssuesForCustomer tickets abiertos de un cliente
+func OpenIssuesForCustomer(customer string) *JQL {
+	return NewJQL().Customer(customer).Open().OrderBy("updated DESC")
+}
+
+// IssuesMentioning tickets que mencionan el texto
+func IssuesMentioning(text string) *JQL {
+	return NewJQL().Text(text).OrderBy("updated DESC")
+}
+
+// IssuesFromChat tickets creados desde el chat
+func IssuesFromChat(chat string) *JQL {
+	return NewJQL().FromChat(chat).OrderBy("created DESC")
+}
+
+// Search ejecuta una consulta JQL y devuelve hasta limit issues (todos si
+// limit <= 0) con los campos pedidos, o los que usa Lisa si fields está vacío
+func (c *Client) Search(ctx context.Context, jql string, fields []string, limit int) ([]Issue, error) {
+	if len(fields) == 0 {
+		fields = issueFields
+	}
+	query := url.Values{
+		"jql":    {jql},
+		"fields": {strings.Join(fields, ",")},
+	}
+	var issues []Issue
+	var err error
+	if c.dataCenter {
+		issues, err = listOffset[Issue](ctx, c, "/search", query, "issues", limit)
+	} else {
+		issues, err = listCursor[Issue](ctx, c, "/search/jql", query, "issues", limit)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("búsqueda %q: %w", jql, err)
+	}
+	return issues, nil
+}
+
+// SearchTickets busca tickets con los criterios de la consulta, los más
+// recientes primero
+func (c *Client) SearchTickets(ctx context.Context, query types.TicketQuery, limit int) ([]types.TicketInfo, error) {
+	q := NewJQL().OrderBy("updated DESC")
+	if query.ProjectKey != "" {
+		q.Project(query.ProjectKey)
+	}
+	if query.Chat != "" {
+		q.FromChat(query.Chat)
+	}
+	if query.Customer != "" {
+		q.Customer(query.Customer)
+	}
+	if query.Text != "" {
+		q.Text(query.Text)
+	}
+	if query.OpenOnly {
+		q.Open()
+	}
+	if len(q.clauses) == 0 {
+		return nil, fmt.Errorf("indique qué buscar")
+	}
+	return c.searchTickets(ctx, q, limit)
+}
+
+// RecentTickets tickets actualizados hace poco, para el autocompletado
+func (c *Client) RecentTickets(ctx context.Context, limit int) ([]types.TicketInfo, error) {
+	q := NewJQL().Where(fmt.Sprintf("updated >= -%dd", recentDays)).OrderBy("updated DESC")
+	return c.searchTickets(ctx, q, limit)
+}
+
+func (c *Client) searchTickets(ctx context.Context, q *JQL, limit int) ([]types.TicketInfo, error) {
+	issues, err := c.Search(ctx, q.String(), ticketFields, limit)
+	if err != nil {
+		return nil, err
+	}
+	tickets := make([]types.TicketInfo, 0, len(issues))
+	for i := range issues {
+		tickets = append(tickets, *c.ticketInfo(&issues[i]))
+	}
+	return tickets, nil
+}
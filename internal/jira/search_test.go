@@ -0,0 +1,93 @@
+package jira
+
+import "testing"
+
+func TestQuoteJQL(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"PROJ", `"PROJ"`},
+		{"", `""`},
+		{`dijo "hola"`, `"dijo \"hola\""`},
+		{`C:\temp`, `"C:\\temp"`},
+		{`fin\"`, `"fin\\\""`},
+		{"x OR project = SECRETO", `"x OR project = SECRETO"`},
+	}
+	for _, tt := range tests {
+		if got := quoteJQL(tt.in); got != tt.want {
+			t.Errorf("quoteJQL(%q) = %s, se esperaba %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestChatLabel(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"5491122334455@s.whatsapp.net", "chat-5491122334455"},
+		{"120363000000000000@g.us", "chat-120363000000000000"},
+		{"5491122334455", "chat-5491122334455"},
+		{"canal #soporte!", "chat-canal-soporte"},
+	}
+	for _, tt := range tests {
+		if got := ChatLabel(tt.in); got != tt.want {
+			t.Errorf("ChatLabel(%q) = %s, se esperaba %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestJQLCustomer(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"nombre", "Ana Pérez", `text ~ "\"Ana Pérez\""`},
+		{"nombre con comillas", `Ana "la jefa"`, `text ~ "\"Ana la jefa\""`},
+		{"número corto", "12345", `text ~ "\"12345\""`},
+		{"número con letras", "ticket 5491122334455", `text ~ "\"ticket 5491122334455\""`},
+		{
+			"teléfono con formato",
+			"+54 9 11 2233-4455",
+			`(labels = "chat-5491122334455" OR text ~ "\"+54 9 11 2233-4455\"")`,
+		},
+		{
+			"JID",
+			"5491122334455@s.whatsapp.net",
+			`(labels = "chat-5491122334455" OR text ~ "\"5491122334455@s.whatsapp.net\"")`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewJQL().Customer(tt.in).String(); got != tt.want {
+				t.Errorf("Customer(%q) = %s, se esperaba %s", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJQLString(t *testing.T) {
+	tests := []struct {
+		name string
+		q    *JQL
+		want string
+	}{
+		{"vacía", NewJQL(), ""},
+		{"solo orden", NewJQL().OrderBy("updated DESC"), "ORDER BY updated DESC"},
+		{"condición vacía", NewJQL().Where("").Open(), "statusCategory != Done"},
+		{
+			"combinada",
+			NewJQL().Project("web").Text(`no "carga"`).Open().OrderBy("created DESC"),
+			`project = "WEB" AND text ~ "no \"carga\"" AND statusCategory != Done ORDER BY created DESC`,
+		},
+		{"tickets de un chat", IssuesFromChat("5491122334455@s.whatsapp.net"), `labels = "chat-5491122334455" ORDER BY created DESC`},
+		{
+			"abiertos de un cliente",
+			OpenIssuesForCustomer("Ana"),
+			`text ~ "\"Ana\"" AND statusCategory != Done ORDER BY updated DESC`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.q.String(); got != tt.want {
+				t.Errorf("String() = %s, se esperaba %s", got, tt.want)
+			}
+		})
+	}
+}
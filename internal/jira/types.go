@@ -1 +1,97 @@
 package jira
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// timeLayout formato de fechas de la API de Jira
+const timeLayout = "2006-01-02T15:04:05.000-0700"
+
+// User usuario de Jira. Cloud lo identifica por AccountID; Server y Data
+// Center, por Name (nombre de usuario).
+type User struct {
+	AccountID    string `json:"accountId,omitempty"`
+	Name         string `json:"name,omitempty"`
+	Key          string `json:"key,omitempty"`
+	DisplayName  string `json:"displayName"`
+	EmailAddress string `json:"emailAddress,omitempty"`
+	Active       bool   `json:"active"`
+}
+
+// ID identificador para asignar al usuario: accountId o nombre de usuario
+func (u User) ID() string {
+	if u.AccountID != "" {
+		return u.AccountID
+	}
+	return u.Name
+}
+
+// Named referencia por nombre o ID (prioridad, componente, tipo de issue,
+// resolución). Las opciones de campos personalizados usan Value.
+type Named struct {
+	ID    string `json:"id,omitempty"`
+	Name  string `json:"name,omitempty"`
+	Value string `json:"value,omitempty"`
+}
+
+// Label nombre visible de la opción
+func (n Named) Label() string {
+	if n.Name != "" {
+		return n.Name
+	}
+	return n.Value
+}
+
+// Status estado de un issue
+type Status struct {
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	StatusCategory struct {
+		Key  string `json:"key"` // new, indeterminate, done
+		Name string `json:"name"`
+	} `json:"statusCategory"`
+}
+
+// Issue issue de Jira con los campos que usa Lisa
+type Issue struct {
+	ID     string      `json:"id"`
+	Key    string      `json:"key"`
+	Self   string      `json:"self"`
+	Fields IssueFields `json:"fields"`
+}
+
+// IssueFields campos de un issue. Description queda sin decodificar porque
+// es ADF en Jira Cloud y texto en wiki markup en Data Center.
+type IssueFields struct {
+	Summary     string          `json:"summary"`
+	Description json.RawMessage `json:"description,omitempty"`
+	Status      *Status         `json:"status,omitempty"`
+	Priority    *Named          `json:"priority,omitempty"`
+	IssueType   *Named          `json:"issuetype,omitempty"`
+	Resolution  *Named          `json:"resolution,omitempty"`
+	Assignee    *User           `json:"assignee,omitempty"`
+	Reporter    *User           `json:"reporter,omitempty"`
+	Labels      []string        `json:"labels,omitempty"`
+	Components  []Named         `json:"components,omitempty"`
+	Project     *struct {
+		Key  string `json:"key"`
+		Name string `json:"name"`
+	} `json:"project,omitempty"`
+	Created string `json:"created,omitempty"`
+	Updated string `json:"updated,omitempty"`
+}
+
+// UpdatedAt fecha de la última modificación
+func (f IssueFields) UpdatedAt() time.Time {
+	t, _ := time.Parse(timeLayout, f.Updated)
+	return t
+}
+
+// Comment comentario de un issue
+type Comment struct {
+	ID      string          `json:"id"`
+	Author  *User           `json:"author,omitempty"`
+	Body    json.RawMessage `json:"body"`
+	Created string          `json:"created"`
+}
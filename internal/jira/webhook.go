@@ -0,0 +1,222 @@
+package jira
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"Lisa/pkg/types"
+)
+
+const (
+	// webhookMaxBody tamaño máximo del cuerpo de un webhook
+	webhookMaxBody = 1 << 20
+
+	// webhookTimeout tiempo para avisar las novedades de un webhook
+	webhookTimeout = 30 * time.Second
+
+	// commentMemory tiempo que se recuerdan los comentarios avisados, porque
+	// Jira puede enviar el mismo comentario en comment_created y en jira:issue_updated
+	commentMemory = time.Hour
+)
+
+// TicketEventHandler recibe las novedades de un webhook
+type TicketEventHandler func(ctx context.Context, events []types.TicketEvent)
+
+// webhookPayload cuerpo de los webhooks de issues y comentarios
+type webhookPayload struct {
+	Timestamp    int64  `json:"timestamp"`
+	WebhookEvent string `json:"webhookEvent"`
+	User         *User  `json:"user"`
+	Issue        *Issue `json:"issue"`
+	Changelog    *struct {
+		Items []struct {
+			Field      string `json:"field"`
+			FromString string `json:"fromString"`
+			ToString   string `json:"toString"`
+		} `json:"items"`
+	} `json:"changelog"`
+	Comment *struct {
+		ID         string          `json:"id"`
+		Body       json.RawMessage `json:"body"`
+		Author     *User           `json:"author"`
+		JsdPublic  *bool           `json:"jsdPublic"`
+		Visibility *struct {
+			Type  string `json:"type"`
+			Value string `json:"value"`
+		} `json:"visibility"`
+	} `json:"comment"`
+}
+
+// webhookHandler verifica y traduce los webhooks de Jira
+type webhookHandler struct {
+	client *Client
+	secret string
+	handle TicketEventHandler
+
+	mu       sync.Mutex
+	comments map[string]time.Time
+}
+
+// WebhookHandler crea el receptor de webhooks de Jira. Cada petición debe
+// venir firmada con el secreto (X-Hub-Signature: sha256=<hmac del cuerpo>) o
+// traerlo en el parámetro secret de la URL. Las novedades se procesan en
+// segundo plano para responder enseguida.
+func (c *Client) WebhookHandler(secret string, handle TicketEventHandler) (http.Handler, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("falta JIRA_WEBHOOK_SECRET")
+	}
+	return &webhookHandler{
+		client:   c,
+		secret:   secret,
+		handle:   handle,
+		comments: make(map[string]time.Time),
+	}, nil
+}
+
+func (h *webhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, webhookMaxBody+1))
+	if err != nil || len(body) > webhookMaxBody {
+		http.Error(w, "cuerpo inválido", http.StatusBadRequest)
+		return
+	}
+	if !h.verify(r, body) {
+		log.Printf("JIRA: Webhook rechazado desde %s: firma inválida", r.RemoteAddr)
+		http.Error(w, "firma inválida", http.StatusUnauthorized)
+		return
+	}
+
+	var payload webhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		http.Error(w, "JSON inválido", http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+
+	events := h.events(&payload)
+	if len(events) == 0 {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+		defer cancel()
+		h.handle(ctx, events)
+	}()
+}
+
+// verify comprueba la firma HMAC o, si no viene firma, el secreto en la URL
+func (h *webhookHandler) verify(r *http.Request, body []byte) bool {
+	if signature := r.Header.Get("X-Hub-Signature"); signature != "" {
+		algo, digest, ok := strings.Cut(signature, "=")
+		if !ok || algo != "sha256" {
+			return false
+		}
+		got, err := hex.DecodeString(digest)
+		if err != nil {
+			return false
+		}
+		mac := hmac.New(sha256.New, []byte(h.secret))
+		mac.Write(body)
+		return hmac.Equal(got, mac.Sum(nil))
+	}
+
+	secret := r.URL.Query().Get("secret")
+	return secret != "" && subtle.ConstantTimeCompare([]byte(secret), []byte(h.secret)) == 1
+}
+
+// events traduce el webhook a novedades: cambios de estado, asignación y
+// resolución del changelog, y comentarios públicos
+func (h *webhookHandler) events(p *webhookPayload) []types.TicketEvent {
+	if p.Issue == nil || p.Issue.Key == "" {
+		return nil
+	}
+
+	base := types.TicketEvent{
+		Key:       p.Issue.Key,
+		Summary:   p.Issue.Fields.Summary,
+		URL:       h.client.IssueURL(p.Issue.Key),
+		Timestamp: time.Now(),
+	}
+	if p.Timestamp > 0 {
+		base.Timestamp = time.UnixMilli(p.Timestamp)
+	}
+	if p.User != nil {
+		base.Author = p.User.DisplayName
+	}
+
+	var events []types.TicketEvent
+	if p.Changelog != nil {
+		for _, item := range p.Changelog.Items {
+			evt := base
+			evt.From, evt.To = item.FromString, item.ToString
+			switch strings.ToLower(item.Field) {
+			case "status":
+				evt.Type = types.TicketEventStatus
+			case "assignee":
+				evt.Type = types.TicketEventAssignee
+			case "resolution":
+				if item.ToString == "" {
+					continue // reapertura: el cambio de estado ya lo avisa
+				}
+				evt.Type = types.TicketEventResolution
+			default:
+				continue
+			}
+			events = append(events, evt)
+		}
+	}
+
+	if c := p.Comment; c != nil && p.WebhookEvent != "comment_updated" && p.WebhookEvent != "comment_deleted" {
+		// Los comentarios internos o restringidos no se avisan al cliente
+		internal := c.Visibility != nil || (c.JsdPublic != nil && !*c.JsdPublic)
+		if !internal && h.firstTime(c.ID) {
+			evt := base
+			evt.Type = types.TicketEventComment
+			evt.Comment = ADFText(c.Body)
+			if c.Author != nil {
+				evt.Author = c.Author.DisplayName
+			}
+			if evt.Comment != "" {
+				events = append(events, evt)
+			}
+		}
+	}
+	return events
+}
+
+// firstTime indica si el comentario todavía no se avisó
+func (h *webhookHandler) firstTime(commentID string) bool {
+	if commentID == "" {
+		return true
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	for id, at := range h.comments {
+		if now.Sub(at) > commentMemory {
+			delete(h.comments, id)
+		}
+	}
+	if _, seen := h.comments[commentID]; seen {
+		return false
+	}
+	h.comments[commentID] = now
+	return true
+}
@@ -0,0 +1,232 @@
+package jira
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"Lisa/internal/config"
+	"Lisa/pkg/types"
+)
+
+const testWebhookSecret = "secreto"
+
+// sign firma el cuerpo como Jira (X-Hub-Signature)
+func sign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newTestWebhook(t *testing.T, handle TicketEventHandler) *webhookHandler {
+	t.Helper()
+	c, err := NewClient(config.JiraConfig{URL: "https://ejemplo.atlassian.net", Email: "lisa@example.com", Token: "token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := c.WebhookHandler(testWebhookSecret, handle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h.(*webhookHandler)
+}
+
+func TestWebhookVerify(t *testing.T) {
+	body := `{"webhookEvent": "jira:issue_updated"}`
+	tests := []struct {
+		name      string
+		signature string
+		query     string
+		want      bool
+	}{
+		{name: "firma válida", signature: sign(testWebhookSecret, body), want: true},
+		{name: "firma con otro secreto", signature: sign("otro", body)},
+		{name: "firma de otro cuerpo", signature: sign(testWebhookSecret, body+" ")},
+		{name: "otro algoritmo", signature: strings.Replace(sign(testWebhookSecret, body), "sha256", "sha1", 1)},
+		{name: "firma sin algoritmo", signature: "abcdef"},
+		{name: "firma no hexadecimal", signature: "sha256=zz"},
+		{name: "secreto en la URL", query: "?secret=" + testWebhookSecret, want: true},
+		{name: "secreto incorrecto en la URL", query: "?secret=secret"},
+		{name: "firma inválida con secreto en la URL", signature: sign("otro", body), query: "?secret=" + testWebhookSecret},
+		{name: "sin firma ni secreto"},
+	}
+	h := newTestWebhook(t, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/webhooks/jira"+tt.query, strings.NewReader(body))
+			if tt.signature != "" {
+				r.Header.Set("X-Hub-Signature", tt.signature)
+			}
+			if got := h.verify(r, []byte(body)); got != tt.want {
+				t.Errorf("verify() = %v, se esperaba %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// describeEvents representación compacta de las novedades: tipo:de>a o tipo:comentario
+func describeEvents(events []types.TicketEvent) string {
+	var parts []string
+	for _, e := range events {
+		if e.Type == types.TicketEventComment {
+			parts = append(parts, fmt.Sprintf("%s:%s (%s)", e.Type, e.Comment, e.Author))
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s:%s>%s", e.Type, e.From, e.To))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func TestWebhookEvents(t *testing.T) {
+	const issue = `"issue": {"key": "PROJ-7", "fields": {"summary": "No carga"}}, "user": {"displayName": "Marta"}`
+	const comment = `{"type": "doc", "version": 1, "content": [{"type": "paragraph", "content": [{"type": "text", "text": "Ya está resuelto"}]}]}`
+
+	tests := []struct {
+		name    string
+		payload string
+		want    string
+	}{
+		{"sin issue", `{"webhookEvent": "jira:issue_updated"}`, ""},
+		{
+			"cambios del changelog",
+			`{"webhookEvent": "jira:issue_updated", ` + issue + `, "changelog": {"items": [
+				{"field": "status", "fromString": "To Do", "toString": "In Progress"},
+				{"field": "assignee", "fromString": "", "toString": "Marta"},
+				{"field": "summary", "fromString": "a", "toString": "b"},
+				{"field": "resolution", "fromString": "", "toString": "Done"}
+			]}}`,
+			"status:To Do>In Progress, assignee:>Marta, resolution:>Done",
+		},
+		{
+			"reapertura",
+			`{"webhookEvent": "jira:issue_updated", ` + issue + `, "changelog": {"items": [
+				{"field": "resolution", "fromString": "Done", "toString": ""},
+				{"field": "status", "fromString": "Done", "toString": "Reopened"}
+			]}}`,
+			"status:Done>Reopened",
+		},
+		{
+			"comentario público",
+			`{"webhookEvent": "comment_created", ` + issue + `, "comment": {"id": "100", "body": ` + comment + `, "author": {"displayName": "Pedro"}}}`,
+			"comment:Ya está resuelto (Pedro)",
+		},
+		{
+			"comentario en texto plano de Data Center",
+			`{"webhookEvent": "comment_created", ` + issue + `, "comment": {"id": "101", "body": "Listo"}}`,
+			"comment:Listo (Marta)",
+		},
+		{
+			"comentario con visibilidad restringida",
+			`{"webhookEvent": "comment_created", ` + issue + `, "comment": {"id": "102", "body": "interno", "visibility": {"type": "role", "value": "Developers"}}}`,
+			"",
+		},
+		{
+			"comentario interno de Service Management",
+			`{"webhookEvent": "comment_created", ` + issue + `, "comment": {"id": "103", "body": "interno", "jsdPublic": false}}`,
+			"",
+		},
+		{
+			"comentario editado",
+			`{"webhookEvent": "comment_updated", ` + issue + `, "comment": {"id": "104", "body": "editado"}}`,
+			"",
+		},
+		{
+			"comentario vacío",
+			`{"webhookEvent": "comment_created", ` + issue + `, "comment": {"id": "105", "body": ""}}`,
+			"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestWebhook(t, nil)
+			var p webhookPayload
+			if err := json.Unmarshal([]byte(tt.payload), &p); err != nil {
+				t.Fatalf("payload inválido: %v", err)
+			}
+
+			events := h.events(&p)
+			if got := describeEvents(events); got != tt.want {
+				t.Errorf("events() = %q, se esperaba %q", got, tt.want)
+			}
+			for _, e := range events {
+				if e.Key != "PROJ-7" || e.Summary != "No carga" || e.URL != "https://ejemplo.atlassian.net/browse/PROJ-7" {
+					t.Errorf("novedad sin datos del ticket: %+v", e)
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookCommentOnlyOnce(t *testing.T) {
+	h := newTestWebhook(t, nil)
+	payload := `{"webhookEvent": "%s", "issue": {"key": "PROJ-7"}, "comment": {"id": "200", "body": "Hola"}}`
+
+	// Jira envía el mismo comentario en comment_created y en jira:issue_updated
+	for i, event := range []string{"comment_created", "jira:issue_updated"} {
+		var p webhookPayload
+		if err := json.Unmarshal([]byte(fmt.Sprintf(payload, event)), &p); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := len(h.events(&p)), 1-i; got != want {
+			t.Errorf("%s: %d novedades, se esperaba %d", event, got, want)
+		}
+	}
+}
+
+func TestWebhookServeHTTP(t *testing.T) {
+	received := make(chan []types.TicketEvent, 1)
+	h := newTestWebhook(t, func(ctx context.Context, events []types.TicketEvent) {
+		received <- events
+	})
+	body := `{"webhookEvent": "jira:issue_updated", "timestamp": 1700000000000, "issue": {"key": "PROJ-7"},
+		"changelog": {"items": [{"field": "status", "fromString": "To Do", "toString": "Done"}]}}`
+
+	tests := []struct {
+		name      string
+		method    string
+		body      string
+		signature string
+		status    int
+	}{
+		{"método no permitido", http.MethodGet, "", "", http.StatusMethodNotAllowed},
+		{"firma inválida", http.MethodPost, body, sign("otro", body), http.StatusUnauthorized},
+		{"JSON inválido", http.MethodPost, "{", sign(testWebhookSecret, "{"), http.StatusBadRequest},
+		{"cuerpo demasiado grande", http.MethodPost, strings.Repeat(" ", webhookMaxBody+1), "", http.StatusBadRequest},
+		{"válido", http.MethodPost, body, sign(testWebhookSecret, body), http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/webhooks/jira", strings.NewReader(tt.body))
+			if tt.signature != "" {
+				r.Header.Set("X-Hub-Signature", tt.signature)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != tt.status {
+				t.Errorf("status = %d, se esperaba %d", w.Code, tt.status)
+			}
+		})
+	}
+
+	select {
+	case events := <-received:
+		if len(events) != 1 || events[0].To != "Done" || !events[0].Timestamp.Equal(time.UnixMilli(1700000000000)) {
+			t.Errorf("novedades = %+v", events)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("el handler no recibió las novedades")
+	}
+	select {
+	case events := <-received:
+		t.Errorf("novedades inesperadas de una petición rechazada: %+v", events)
+	default:
+	}
+}
@@ -0,0 +1,101 @@
+package jira
+
+import (
+	"strconv"
+	"strings"
+)
+
+// wikiEscaper escapa los caracteres que abren macros y enlaces en wiki markup
+var wikiEscaper = strings.NewReplacer(`{`, `\{`, `[`, `\[`)
+
+// MarkdownToWiki convierte texto plano o Markdown (incluido el formato de
+// WhatsApp) a wiki markup, el formato de las descripciones y comentarios de
+// Jira Server y Data Center. Reconoce lo mismo que MarkdownToADF.
+func MarkdownToWiki(text string) string {
+	var b strings.Builder
+	for i, block := range MarkdownToADF(text).Content {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		writeWikiBlock(&b, block)
+	}
+	return b.String()
+}
+
+func writeWikiBlock(b *strings.Builder, n ADFNode) {
+	switch n.Type {
+	case "heading":
+		level, _ := n.Attrs["level"].(int)
+		if level < 1 {
+			level = 1
+		}
+		b.WriteString("h" + strconv.Itoa(level) + ". ")
+		writeWikiInline(b, n.Content)
+	case "bulletList", "orderedList":
+		bullet := "* "
+		if n.Type == "orderedList" {
+			bullet = "# "
+		}
+		for i, item := range n.Content {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			b.WriteString(bullet)
+			for _, c := range item.Content {
+				writeWikiInline(b, c.Content)
+			}
+		}
+	case "codeBlock":
+		macro := "{code}"
+		if lang, _ := n.Attrs["language"].(string); lang != "" {
+			macro = "{code:" + lang + "}"
+		}
+		b.WriteString(macro + "\n")
+		for _, c := range n.Content {
+			b.WriteString(c.Text)
+		}
+		b.WriteString("\n{code}")
+	case "blockquote":
+		b.WriteString("{quote}\n")
+		for _, c := range n.Content {
+			writeWikiInline(b, c.Content)
+		}
+		b.WriteString("\n{quote}")
+	default:
+		writeWikiInline(b, n.Content)
+	}
+}
+
+// writeWikiInline escribe el texto con su formato en línea
+func writeWikiInline(b *strings.Builder, nodes []ADFNode) {
+	for _, n := range nodes {
+		if n.Type == "hardBreak" {
+			b.WriteString("\n")
+			continue
+		}
+		if len(n.Marks) == 0 {
+			b.WriteString(wikiEscaper.Replace(n.Text))
+			continue
+		}
+
+		switch mark := n.Marks[0]; mark.Type {
+		case "code":
+			b.WriteString("{{" + n.Text + "}}")
+		case "strong":
+			b.WriteString("*" + wikiEscaper.Replace(n.Text) + "*")
+		case "em":
+			b.WriteString("_" + wikiEscaper.Replace(n.Text) + "_")
+		case "strike":
+			b.WriteString("-" + wikiEscaper.Replace(n.Text) + "-")
+		case "link":
+			href, _ := mark.Attrs["href"].(string)
+			if href == n.Text {
+				b.WriteString("[" + href + "]")
+			} else {
+				b.WriteString("[" + wikiEscaper.Replace(n.Text) + "|" + href + "]")
+			}
+		default:
+			b.WriteString(wikiEscaper.Replace(n.Text))
+		}
+	}
+}
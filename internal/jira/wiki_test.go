@@ -0,0 +1,65 @@
+package jira
+
+import "testing"
+
+func TestMarkdownToWiki(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"vacío", "", ""},
+		{"párrafos", "uno\ndos\n\ntres", "uno\ndos\n\ntres"},
+		{"título", "### Pasos", "h3. Pasos"},
+		{"formato en línea", "*negrita* **fuerte** _cursiva_ ~tachado~", "*negrita* *fuerte* _cursiva_ -tachado-"},
+		{"snake_case", "el campo user_id_nuevo", "el campo user_id_nuevo"},
+		{"código en línea", "usar `map[string]{}`", "usar {{map[string]{}}}"},
+		{"escapa macros y enlaces", "valor {code} en [corchetes]", `valor \{code} en \[corchetes]`},
+		{"escapa dentro del formato", "*{panel}* _[x]_", `*\{panel}* _\[x]_`},
+		{"enlace", "ver https://example.com/a.", "ver [https://example.com/a]."},
+		{"lista con viñetas", "- uno\n- *dos*", "* uno\n* *dos*"},
+		{"lista numerada", "1. uno\n2. dos", "# uno\n# dos"},
+		{"cita", "> primera\n> segunda", "{quote}\nprimera\nsegunda\n{quote}"},
+		{"bloque de código", "```sql\nSELECT '{x}' FROM [t]\n```", "{code:sql}\nSELECT '{x}' FROM [t]\n{code}"},
+		{"bloque sin lenguaje", "```\nx\n```", "{code}\nx\n{code}"},
+		{
+			"bloques mezclados",
+			"# Error\nNo carga\n- paso 1\n> dijo el cliente",
+			"h1. Error\n\nNo carga\n\n* paso 1\n\n{quote}\ndijo el cliente\n{quote}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MarkdownToWiki(tt.in); got != tt.want {
+				t.Errorf("MarkdownToWiki(%q)\n got %q\nwant %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserRef(t *testing.T) {
+	tests := []struct {
+		mode string
+		id   string
+		key  string
+	}{
+		{ModeCloud, "5b10a2844c20165700ede21g", "accountId"},
+		{ModeDataCenter, "jperez", "name"},
+		{ModeDataCenter, "", "name"},
+	}
+	for _, tt := range tests {
+		c := &Client{dataCenter: tt.mode == ModeDataCenter}
+		ref := c.userRef(tt.id)
+		v, ok := ref[tt.key]
+		if !ok || len(ref) != 1 {
+			t.Errorf("userRef(%q) en %s = %v, se esperaba la clave %s", tt.id, tt.mode, ref, tt.key)
+			continue
+		}
+		if tt.id == "" && v != nil {
+			t.Errorf("userRef(\"\") = %v, se esperaba nil para desasignar", ref)
+		}
+		if tt.id != "" && v != tt.id {
+			t.Errorf("userRef(%q) = %v", tt.id, ref)
+		}
+	}
+}
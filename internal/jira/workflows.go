@@ -1 +1,232 @@
 package jira
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"Lisa/pkg/types"
+)
+
+// categoryPrefix alias que apunta a una categoría de estado (new, indeterminate, done)
+const categoryPrefix = "category:"
+
+// defaultAliasGroups alias por defecto. Cada grupo de nombres amistosos apunta
+// a los nombres de transición o de estado que se prueban en orden.
+var defaultAliasGroups = []struct {
+	aliases []string
+	targets []string
+}{
+	{
+		aliases: []string{"en progreso", "en curso", "progreso", "iniciar", "empezar", "in progress", "start", "start progress"},
+		targets: []string{"Start Progress", "In Progress", "En curso", "En progreso", categoryPrefix + "indeterminate"},
+	},
+	{
+		aliases: []string{"resolve", "resolver", "resuelto", "resolved", "solucionado"},
+		targets: []string{"Resolve Issue", "Resolve", "Resolved", "Resuelto", "Done", "Listo", "Finalizada", categoryPrefix + "done"},
+	},
+	{
+		aliases: []string{"cerrar", "cerrado", "close", "closed", "done", "hecho", "listo", "terminado", "finalizado"},
+		targets: []string{"Close Issue", "Close", "Closed", "Cerrado", "Done", "Listo", "Finalizada", categoryPrefix + "done"},
+	},
+	{
+		aliases: []string{"reabrir", "reopen", "abrir", "abierto", "open", "por hacer", "to do", "todo", "pendiente"},
+		targets: []string{"Reopen Issue", "Reopen", "To Do", "Open", "Por hacer", "Abierto", categoryPrefix + "new"},
+	},
+	{
+		aliases: []string{"en espera", "esperando", "esperando cliente", "waiting", "waiting for customer", "on hold"},
+		targets: []string{"Waiting for customer", "Waiting for support", "Pending", "On Hold", "En espera"},
+	},
+}
+
+// preferredResolutions resoluciones que se eligen cuando la transición la exige
+var preferredResolutions = []string{"Done", "Fixed", "Resolved", "Hecho", "Resuelto", "Listo"}
+
+// IssueTransition transición disponible para un issue, con los campos de su pantalla
+type IssueTransition struct {
+	ID     string                     `json:"id"`
+	Name   string                     `json:"name"`
+	To     Status                     `json:"to"`
+	Fields map[string]TransitionField `json:"fields,omitempty"`
+}
+
+// TransitionField campo de la pantalla de una transición
+type TransitionField struct {
+	Required        bool    `json:"required"`
+	Name            string  `json:"name"`
+	HasDefaultValue bool    `json:"hasDefaultValue"`
+	AllowedValues   []Named `json:"allowedValues,omitempty"`
+}
+
+// buildAliases combina los alias por defecto con los configurados
+func buildAliases(custom map[string][]string) map[string][]string {
+	aliases := make(map[string][]string)
+	for _, group := range defaultAliasGroups {
+		for _, alias := range group.aliases {
+			aliases[normalizeName(alias)] = group.targets
+		}
+	}
+	for alias, targets := range custom {
+		aliases[normalizeName(alias)] = targets
+	}
+	return aliases
+}
+
+var accentReplacer = strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u", "ñ", "n")
+
+// normalizeName compara nombres sin mayúsculas, tildes ni espacios de más
+func normalizeName(s string) string {
+	s = accentReplacer.Replace(strings.ToLower(s))
+	return strings.Join(strings.Fields(strings.NewReplacer("_", " ", "-", " ").Replace(s)), " ")
+}
+
+// IssueTransitions devuelve las transiciones disponibles para el issue, con
+// los campos de cada pantalla
+func (c *Client) IssueTransitions(ctx context.Context, key string) ([]IssueTransition, error) {
+	var resp struct {
+		Transitions []IssueTransition `json:"transitions"`
+	}
+	query := url.Values{"expand": {"transitions.fields"}}
+	if err := c.do(ctx, http.MethodGet, "/issue/"+url.PathEscape(key)+"/transitions", query, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Transitions, nil
+}
+
+// DoTransition ejecuta una transición con los campos de su pantalla
+func (c *Client) DoTransition(ctx context.Context, key, transitionID string, fields map[string]interface{}) error {
+	body := map[string]interface{}{"transition": map[string]string{"id": transitionID}}
+	if len(fields) > 0 {
+		body["fields"] = fields
+	}
+	return c.do(ctx, http.MethodPost, "/issue/"+url.PathEscape(key)+"/transitions", nil, body, nil)
+}
+
+// ResolveTransition busca la transición pedida por ID, por nombre, por estado
+// de destino o por alias. Si no existe, el error lista las opciones válidas.
+func (c *Client) ResolveTransition(transitions []IssueTransition, name string) (*IssueTransition, error) {
+	want := normalizeName(name)
+	if want == "" {
+		return nil, fmt.Errorf("indique la transición")
+	}
+
+	for i, t := range transitions {
+		if t.ID == strings.TrimSpace(name) || normalizeName(t.Name) == want {
+			return &transitions[i], nil
+		}
+	}
+	for i, t := range transitions {
+		if normalizeName(t.To.Name) == want {
+			return &transitions[i], nil
+		}
+	}
+
+	for _, target := range c.aliases[want] {
+		if category, ok := strings.CutPrefix(target, categoryPrefix); ok {
+			for i, t := range transitions {
+				if t.To.StatusCategory.Key == category {
+					return &transitions[i], nil
+				}
+			}
+			continue
+		}
+		target = normalizeName(target)
+		for i, t := range transitions {
+			if normalizeName(t.Name) == target || normalizeName(t.To.Name) == target {
+				return &transitions[i], nil
+			}
+		}
+	}
+
+	if len(transitions) == 0 {
+		return nil, fmt.Errorf("el ticket no tiene transiciones disponibles")
+	}
+	return nil, fmt.Errorf("la transición %q no está disponible, opciones: %s", name, transitionOptions(transitions))
+}
+
+// transitionOptions lista las transiciones como "Nombre (→ Estado)"
+func transitionOptions(transitions []IssueTransition) string {
+	options := make([]string, 0, len(transitions))
+	for _, t := range transitions {
+		if normalizeName(t.Name) == normalizeName(t.To.Name) {
+			options = append(options, t.Name)
+		} else {
+			options = append(options, fmt.Sprintf("%s (→ %s)", t.Name, t.To.Name))
+		}
+	}
+	return strings.Join(options, ", ")
+}
+
+// screenFields completa los campos obligatorios de la pantalla de la
+// transición que Lisa puede elegir (la resolución). Si quedan otros sin valor
+// por defecto, devuelve un error con sus nombres.
+func screenFields(t *IssueTransition) (map[string]interface{}, error) {
+	fields := make(map[string]interface{})
+	var missing []string
+
+	for id, f := range t.Fields {
+		if !f.Required || f.HasDefaultValue {
+			continue
+		}
+		if id == "resolution" && len(f.AllowedValues) > 0 {
+			fields[id] = map[string]string{"id": pickResolution(f.AllowedValues).ID}
+			continue
+		}
+		missing = append(missing, f.Name)
+	}
+
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("la transición %q exige completar en Jira: %s", t.Name, strings.Join(missing, ", "))
+	}
+	return fields, nil
+}
+
+func pickResolution(values []Named) Named {
+	for _, preferred := range preferredResolutions {
+		for _, v := range values {
+			if normalizeName(v.Name) == normalizeName(preferred) {
+				return v
+			}
+		}
+	}
+	return values[0]
+}
+
+// TransitionTicket cambia el estado del ticket con una transición indicada
+// por ID, nombre, estado de destino o alias, y devuelve su estado actualizado
+func (c *Client) TransitionTicket(ctx context.Context, key, transition string) (*types.TicketInfo, error) {
+	transitions, err := c.IssueTransitions(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	t, err := c.ResolveTransition(transitions, transition)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := screenFields(t)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.DoTransition(ctx, key, t.ID, fields); err != nil {
+		return nil, err
+	}
+	log.Printf("JIRA: %s pasó a %s (%s)", key, t.To.Name, t.Name)
+	return c.GetTicket(ctx, key)
+}
+
+// Transitions transiciones disponibles para el autocompletado de /transition
+func (c *Client) Transitions(ctx context.Context, key string) ([]types.Transition, error) {
+	transitions, err := c.IssueTransitions(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]types.Transition, 0, len(transitions))
+	for _, t := range transitions {
+		result = append(result, types.Transition{ID: t.ID, Name: t.Name, ToStatus: t.To.Name})
+	}
+	return result, nil
+}
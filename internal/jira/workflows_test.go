@@ -0,0 +1,128 @@
+package jira
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// transition transición de prueba hacia el estado to, de la categoría indicada
+func transition(id, name, to, category string) IssueTransition {
+	t := IssueTransition{ID: id, Name: name}
+	t.To.Name = to
+	t.To.StatusCategory.Key = category
+	return t
+}
+
+func TestResolveTransition(t *testing.T) {
+	transitions := []IssueTransition{
+		transition("11", "Start Progress", "In Progress", "indeterminate"),
+		transition("21", "Send to QA", "En revisión", "indeterminate"),
+		transition("31", "Finish", "Terminado", "done"),
+	}
+	c := &Client{aliases: buildAliases(map[string][]string{"qa": {"Send to QA"}})}
+
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		wantErr string
+	}{
+		{name: "por ID", in: " 21 ", want: "21"},
+		{name: "por nombre", in: "start progress", want: "11"},
+		{name: "por nombre con guiones", in: "start-progress", want: "11"},
+		{name: "por estado sin tildes", in: "en revision", want: "21"},
+		{name: "alias por defecto", in: "En curso", want: "11"},
+		{name: "alias a categoría", in: "cerrar", want: "31"},
+		{name: "alias configurado", in: "QA", want: "21"},
+		{name: "vacío", in: "  ", wantErr: "indique la transición"},
+		{name: "inexistente", in: "borrar", wantErr: "Start Progress (→ In Progress), Send to QA (→ En revisión), Finish (→ Terminado)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.ResolveTransition(transitions, tt.in)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ResolveTransition(%q) error = %v, se esperaba %q", tt.in, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveTransition(%q): %v", tt.in, err)
+			}
+			if got.ID != tt.want {
+				t.Errorf("ResolveTransition(%q) = %s, se esperaba %s", tt.in, got.ID, tt.want)
+			}
+		})
+	}
+
+	if _, err := c.ResolveTransition(nil, "cerrar"); err == nil || !strings.Contains(err.Error(), "no tiene transiciones") {
+		t.Errorf("sin transiciones: error = %v", err)
+	}
+}
+
+func TestScreenFields(t *testing.T) {
+	resolutions := []Named{{ID: "1", Name: "Won't Do"}, {ID: "2", Name: "Hecho"}, {ID: "3", Name: "Fixed"}}
+
+	tests := []struct {
+		name    string
+		fields  map[string]TransitionField
+		want    map[string]interface{}
+		wantErr string
+	}{
+		{
+			name: "sin pantalla",
+			want: map[string]interface{}{},
+		},
+		{
+			name: "resolución preferida",
+			fields: map[string]TransitionField{
+				"resolution": {Required: true, Name: "Resolution", AllowedValues: resolutions},
+			},
+			want: map[string]interface{}{"resolution": map[string]string{"id": "3"}},
+		},
+		{
+			name: "resolución sin preferidas",
+			fields: map[string]TransitionField{
+				"resolution": {Required: true, Name: "Resolution", AllowedValues: []Named{{ID: "9", Name: "Duplicate"}}},
+			},
+			want: map[string]interface{}{"resolution": map[string]string{"id": "9"}},
+		},
+		{
+			name: "opcionales y con valor por defecto",
+			fields: map[string]TransitionField{
+				"comment":    {Name: "Comment"},
+				"resolution": {Required: true, HasDefaultValue: true, Name: "Resolution", AllowedValues: resolutions},
+			},
+			want: map[string]interface{}{},
+		},
+		{
+			name: "obligatorio que Lisa no completa",
+			fields: map[string]TransitionField{
+				"resolution":        {Required: true, Name: "Resolution", AllowedValues: resolutions},
+				"customfield_10010": {Required: true, Name: "Causa raíz"},
+			},
+			wantErr: `la transición "Finish" exige completar en Jira: Causa raíz`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := transition("31", "Finish", "Terminado", "done")
+			tr.Fields = tt.fields
+
+			got, err := screenFields(&tr)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("screenFields() error = %v, se esperaba %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("screenFields() = %v, se esperaba %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -4,24 +4,48 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sync"
+	"sync/atomic"
 
 	_ "github.com/lib/pq"
 	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/store"
 	"go.mau.fi/whatsmeow/store/sqlstore"
+	waTypes "go.mau.fi/whatsmeow/types"
 	"go.mau.fi/whatsmeow/types/events"
 	waLog "go.mau.fi/whatsmeow/util/log"
+	"google.golang.org/protobuf/proto"
 
 	"Lisa/internal/config"
 	"Lisa/pkg/types"
 )
 
 type Client struct {
+	mu             sync.RWMutex
 	whatsAppClient *whatsmeow.Client
 	container      *sqlstore.Container
 	messageHandler MessageHandler
+	commands       *CommandRouter
+	history        *History
+	groups         *GroupCache
+	events         *EventBus
+	avatars        avatarCache
+	media          mediaCache
+	mediaMaxBytes  int64
 	logger         waLog.Logger
 	ctx            context.Context
 	cancel         context.CancelFunc
+
+	defaultTemplates config.GroupTemplates
+	groupTemplates   map[string]config.GroupTemplates
+
+	// Estado de la conexión y re-vinculación
+	state       ConnectionState
+	stateReason string
+	pairPhone   string
+	repairing   atomic.Bool
+	proxy       *proxySettings
 }
 
 type MessageHandler func(*events.Message)
@@ -32,6 +56,11 @@ type Config struct {
 }
 
 func NewClient(cfg *config.Config) (*Client, error) {
+	proxy, err := newProxySettings(cfg.WhatsApp)
+	if err != nil {
+		return nil, err
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 
 	// Crear logger
@@ -51,36 +80,92 @@ func NewClient(cfg *config.Config) (*Client, error) {
 		return nil, fmt.Errorf("fallo al obtener el dispositivo: %v", err)
 	}
 
-	// Crear cliente WhatsApp
-	whatsAppClient := whatsmeow.NewClient(deviceStore, nil)
-
 	client := &Client{
-		whatsAppClient: whatsAppClient,
-		container:      container,
-		logger:         logger,
-		ctx:            ctx,
-		cancel:         cancel,
+		container: container,
+		commands:  NewCommandRouter(cfg.WhatsApp.CommandPrefixes, cfg.WhatsApp.AdminJIDs),
+		history:   NewHistory(defaultHistoryLimit),
+		events:    NewEventBus(),
+		logger:    logger,
+		ctx:       ctx,
+		cancel:    cancel,
+
+		avatars:       avatarCache{entries: make(map[string]avatarEntry)},
+		media:         mediaCache{chats: make(map[string][]*events.Message)},
+		mediaMaxBytes: int64(cfg.WhatsApp.MediaMaxMB) << 20,
+
+		defaultTemplates: cfg.WhatsApp.Templates,
+		groupTemplates:   cfg.WhatsApp.GroupTemplates,
+
+		state:     StateDisconnected,
+		pairPhone: cfg.WhatsApp.PairPhone,
+		proxy:     proxy,
 	}
+	client.groups = NewGroupCache(func(jid waTypes.JID) (*waTypes.GroupInfo, error) {
+		return client.wa().GetGroupInfo(jid)
+	})
 
-	// Configurar manejadores de eventos
-	client.setupEventHandlers()
+	// Crear cliente WhatsApp
+	whatsAppClient, err := client.newWhatsAppClient(deviceStore)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	client.setClient(whatsAppClient)
 
 	return client, nil
 }
 
-func (c *Client) setupEventHandlers() {
-	c.whatsAppClient.AddEventHandler(func(evt interface{}) {
+// newWhatsAppClient crea el cliente de whatsmeow para un dispositivo, con el
+// proxy configurado y los manejadores de eventos registrados
+func (c *Client) newWhatsAppClient(device *store.Device) (*whatsmeow.Client, error) {
+	whatsAppClient := whatsmeow.NewClient(device, nil)
+	if err := c.proxy.apply(whatsAppClient); err != nil {
+		return nil, err
+	}
+	whatsAppClient.AutoReconnectHook = c.reconnectError
+	c.setupEventHandlers(whatsAppClient)
+	return whatsAppClient, nil
+}
+
+// wa devuelve el cliente de whatsmeow actual. Puede cambiar tras un cierre de sesión.
+func (c *Client) wa() *whatsmeow.Client {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.whatsAppClient
+}
+
+func (c *Client) setClient(whatsAppClient *whatsmeow.Client) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.whatsAppClient = whatsAppClient
+}
+
+func (c *Client) setupEventHandlers(whatsAppClient *whatsmeow.Client) {
+	whatsAppClient.AddEventHandler(func(evt interface{}) {
 		switch v := evt.(type) {
 		case *events.Message:
 			c.handleMessage(v)
+		case *events.GroupInfo:
+			c.handleGroupInfo(v)
+		case *events.JoinedGroup:
+			c.groups.Put(&v.GroupInfo)
+			c.logger.Infof("Agregado al grupo %s (%s)", v.GroupInfo.Name, v.JID)
 		case *events.Receipt:
 			c.logger.Infof("Mensaje entregado: %s", v.MessageIDs[0])
 		case *events.Connected:
 			c.logger.Infof("Cliente WhatsApp conectado")
+			c.setState(StateConnected, "")
 		case *events.Disconnected:
 			c.logger.Warnf("Cliente WhatsApp desconectado")
+			if c.State() == StateConnected {
+				c.setState(StateDisconnected, "")
+			}
+			go c.checkProxy()
+		case *events.KeepAliveTimeout:
+			c.logger.Warnf("WhatsApp no responde (%d intentos)", v.ErrorCount)
+			go c.checkProxy()
 		case *events.LoggedOut:
-			c.logger.Warnf("Cliente WhatsApp sesion cerrada")
+			c.handleLoggedOut(v)
 		}
 	})
 }
@@ -94,12 +179,7 @@ func (c *Client) handleMessage(msg *events.Message) {
 	// Obtener información del grupo si es necesario
 	groupName := ""
 	if msg.Info.IsGroup {
-		groupInfo, err := c.whatsAppClient.GetGroupInfo(msg.Info.Chat)
-		if err == nil {
-			groupName = groupInfo.Name
-		} else {
-			groupName = "Grupo desconocido"
-		}
+		groupName = c.groups.Name(msg.Info.Chat)
 	}
 
 	// Intentar obtener texto del mensaje
@@ -108,6 +188,34 @@ func (c *Client) handleMessage(msg *events.Message) {
 		text = msg.Message.GetExtendedTextMessage().GetText()
 	}
 
+	// Guardar en el historial del chat
+	info := types.NewMessageInfoFromEvent(msg)
+	info.GroupName = groupName
+	if info.Text == "" {
+		info.Text = fmt.Sprintf("[%s]", types.GetMessageType(msg).String())
+	}
+	c.history.Add(info)
+	c.media.add(msg)
+
+	// Despachar comandos del chat (!ayuda, @Lisa estado ...). Se hace antes de
+	// publicar para que los consumidores sepan si el mensaje era un comando.
+	command := c.commands.Dispatch(c, msg)
+
+	// Publicar el mensaje para el puente con Discord y demás consumidores
+	media := NewMediaMessage(msg)
+	media.Info = info
+	media.GroupName = groupName
+	c.events.Publish(Event{
+		Type:        EventMessage,
+		Chat:        msg.Info.Chat,
+		ChatName:    groupName,
+		Participant: msg.Info.Sender,
+		Message:     media,
+		Raw:         msg,
+		Command:     command,
+		Timestamp:   msg.Info.Timestamp,
+	})
+
 	// Si hay texto, es un mensaje de texto
 	if text != "" {
 		if msg.Info.IsGroup {
@@ -146,12 +254,7 @@ func (c *Client) processComplexMessage(msg *events.Message) {
 	// Obtener información básica del mensaje
 	groupName := ""
 	if msg.Info.IsGroup {
-		groupInfo, err := c.whatsAppClient.GetGroupInfo(msg.Info.Chat)
-		if err == nil {
-			groupName = groupInfo.Name
-		} else {
-			groupName = "Grupo desconocido"
-		}
+		groupName = c.groups.Name(msg.Info.Chat)
 	}
 
 	// Procesar según el tipo de mensaje
@@ -280,42 +383,46 @@ func (c *Client) SetMessageHandler(handler MessageHandler) {
 	c.messageHandler = handler
 }
 
+// Commands devuelve el router de comandos del chat, para registrar comandos
+func (c *Client) Commands() *CommandRouter {
+	return c.commands
+}
+
+// History devuelve el historial reciente de mensajes por chat
+func (c *Client) History() *History {
+	return c.history
+}
+
+// Events devuelve el bus de eventos del cliente (cambios de participantes, etc.)
+func (c *Client) Events() *EventBus {
+	return c.events
+}
+
+// Groups devuelve el cache de información de grupos
+func (c *Client) Groups() *GroupCache {
+	return c.groups
+}
+
 func (c *Client) Connect() error {
 	// Verificar si ya hay una sesión
-	if c.whatsAppClient.Store.ID == nil {
-		// Primera vez - necesita QR
-		qrChan, _ := c.whatsAppClient.GetQRChannel(c.ctx)
-		err := c.whatsAppClient.Connect()
-		if err != nil {
-			return fmt.Errorf("no se pudo conectar: %v", err)
-		}
-
-		log.Println("WA: Escanea el codigo QR para iniciar sesion...")
+	if c.wa().Store.ID == nil {
+		// Primera vez - necesita QR o código de vinculación
+		return c.pair()
+	}
 
-		for evt := range qrChan {
-			if evt.Event == "code" {
-				// Usar la nueva función para mostrar el QR en terminal
-				c.DisplayQRInTerminal(evt.Code)
-			} else if evt.Event == "success" {
-				log.Println("WA: QR escaneado exitosamente")
-				break
-			}
-		}
-	} else {
-		// Sesión existente - conectar directamente
-		err := c.whatsAppClient.Connect()
-		if err != nil {
-			return fmt.Errorf("no se pudo conectar: %v", err)
-		}
-		log.Println("WA: Sesion restaurada exitosamente")
+	// Sesión existente - conectar directamente
+	c.setState(StateConnecting, "")
+	if err := c.wa().Connect(); err != nil {
+		return c.connectError(err)
 	}
+	log.Println("WA: Sesion restaurada exitosamente")
 
 	return nil
 }
 
 func (c *Client) Disconnect() {
-	if c.whatsAppClient != nil {
-		c.whatsAppClient.Disconnect()
+	if whatsAppClient := c.wa(); whatsAppClient != nil {
+		whatsAppClient.Disconnect()
 	}
 	if c.cancel != nil {
 		c.cancel()
@@ -324,18 +431,76 @@ func (c *Client) Disconnect() {
 }
 
 func (c *Client) IsConnected() bool {
-	return c.whatsAppClient != nil && c.whatsAppClient.IsConnected()
+	whatsAppClient := c.wa()
+	return whatsAppClient != nil && whatsAppClient.IsConnected()
 }
 
 func (c *Client) GetJID() string {
-	if c.whatsAppClient.Store.ID == nil {
+	id := c.wa().Store.ID
+	if id == nil {
 		return ""
 	}
-	return c.whatsAppClient.Store.ID.String()
+	return id.String()
+}
+
+// ownUsers devuelve los identificadores del bot (número y LID) para detectar menciones
+func (c *Client) ownUsers() []string {
+	var users []string
+	device := c.wa().Store
+	if device.ID != nil {
+		users = append(users, device.ID.User)
+	}
+	if !device.LID.IsEmpty() {
+		users = append(users, device.LID.User)
+	}
+	return users
+}
+
+// IsGroupAdmin indica si un participante es administrador de un grupo
+func (c *Client) IsGroupAdmin(group, user waTypes.JID) bool {
+	groupInfo, err := c.groups.Get(group)
+	if err != nil {
+		c.logger.Warnf("No se pudo obtener info del grupo %s: %v", group, err)
+		return false
+	}
+
+	if i := findParticipant(groupInfo, user); i >= 0 {
+		p := groupInfo.Participants[i]
+		return p.IsAdmin || p.IsSuperAdmin
+	}
+	return false
 }
 
-// Métodos para enviar mensajes (para uso futuro)
+// SendTextMessage envía un mensaje de texto a un chat (JID o número de teléfono)
 func (c *Client) SendTextMessage(jid, text string) error {
-	// TODO: Implementar envío de mensajes si es necesario
-	return fmt.Errorf("envio de mensajes no implementado aun")
+	to, err := ParseJID(jid)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.wa().SendMessage(c.ctx, to, &waE2E.Message{
+		Conversation: proto.String(text),
+	})
+	if err != nil {
+		return fmt.Errorf("no se pudo enviar el mensaje a %s: %v", to, err)
+	}
+	return nil
+}
+
+// ReplyToMessage responde en el mismo chat citando el mensaje original
+func (c *Client) ReplyToMessage(msg *events.Message, text string) error {
+	_, err := c.wa().SendMessage(c.ctx, msg.Info.Chat, &waE2E.Message{
+		ExtendedTextMessage: &waE2E.ExtendedTextMessage{
+			Text: proto.String(text),
+			ContextInfo: &waE2E.ContextInfo{
+				StanzaID:      proto.String(msg.Info.ID),
+				Participant:   proto.String(msg.Info.Sender.ToNonAD().String()),
+				QuotedMessage: msg.Message,
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("no se pudo responder en %s: %v", msg.Info.Chat, err)
+	}
+	return nil
 }
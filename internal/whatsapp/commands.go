@@ -0,0 +1,366 @@
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"Lisa/pkg/types"
+)
+
+const (
+	// transcriptSize cantidad de mensajes recientes que se adjuntan a un ticket
+	transcriptSize = 15
+
+	// searchLimit cantidad máxima de resultados de !buscar
+	searchLimit = 10
+)
+
+var issueKeyPattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]+-\d+$`)
+
+// TicketService operaciones de tickets que usan los comandos del chat
+type TicketService interface {
+	CreateTicket(ctx context.Context, draft types.TicketDraft) (*types.TicketInfo, error)
+	GetTicket(ctx context.Context, key string) (*types.TicketInfo, error)
+}
+
+// WorkflowService cambios de estado de tickets. La transición puede indicarse
+// por nombre, por el estado de destino o por un alias ("en progreso", "cerrar").
+type WorkflowService interface {
+	TransitionTicket(ctx context.Context, key, transition string) (*types.TicketInfo, error)
+}
+
+// AttachmentService adjunta archivos de la conversación a un ticket
+type AttachmentService interface {
+	AddAttachment(ctx context.Context, key string, media *types.MediaMessage) error
+}
+
+// TicketSearcher búsqueda de tickets
+type TicketSearcher interface {
+	SearchTickets(ctx context.Context, query types.TicketQuery, limit int) ([]types.TicketInfo, error)
+}
+
+// Summarizer genera un resumen de una conversación (normalmente con IA)
+type Summarizer interface {
+	Summarize(ctx context.Context, messages []types.MessageInfo) (string, error)
+}
+
+// CommandServices dependencias de los comandos por defecto. Los servicios
+// nil se reportan como no configurados al usar el comando.
+type CommandServices struct {
+	Tickets     TicketService
+	Workflow    WorkflowService
+	Search      TicketSearcher
+	Attachments AttachmentService
+	Summarizer  Summarizer
+
+	// Guardias que se agregan a las salas de incidente
+	OnCall []string
+}
+
+// RegisterDefaultCommands registra los comandos de soporte de Lisa
+func RegisterDefaultCommands(r *CommandRouter, services CommandServices) error {
+	commands := []Command{
+		{
+			Name:        "ticket",
+			Aliases:     []string{"reportar"},
+			Description: "Crea un ticket en Jira con la conversación reciente",
+			Usage:       "<resumen del problema>",
+			MinArgs:     1,
+			Handler:     services.handleTicket,
+		},
+		{
+			Name:        "estado",
+			Aliases:     []string{"status"},
+			Description: "Consulta el estado de un ticket o lo cambia (solo administradores)",
+			Usage:       "<CLAVE-123> [nuevo estado]",
+			MinArgs:     1,
+			Handler:     services.handleStatus,
+		},
+		{
+			Name:        "buscar",
+			Aliases:     []string{"search", "tickets"},
+			Description: "Lista los tickets abiertos del chat o busca tickets que mencionen un texto",
+			Usage:       "[texto]",
+			Handler:     services.handleSearch,
+		},
+		{
+			Name:        "resumen",
+			Aliases:     []string{"summary"},
+			Description: "Resume los mensajes recientes del chat",
+			Usage:       "[cantidad de mensajes]",
+			Permission:  PermissionGroupAdmin,
+			Handler:     services.handleSummary,
+		},
+		{
+			Name:        "sala",
+			Aliases:     []string{"warroom"},
+			Description: "Crea un grupo de incidente con las guardias y los contactos indicados",
+			Usage:       "\"<nombre>\" [contactos...]",
+			MinArgs:     1,
+			Permission:  PermissionBotAdmin,
+			Handler:     services.handleWarRoom,
+		},
+	}
+
+	for _, cmd := range commands {
+		if err := r.Register(cmd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s CommandServices) handleTicket(cmd *CommandContext) error {
+	if s.Tickets == nil {
+		return cmd.Reply("Jira no está configurado, no puedo crear tickets todavía.")
+	}
+
+	chat := cmd.Chat().String()
+	recent := withoutMessage(cmd.Client.History().Recent(chat, transcriptSize+1), cmd.Message.Info.ID)
+	draft := types.TicketDraft{
+		Summary:     cmd.Parsed.RawArgs,
+		Description: Transcript(recent),
+		Reporter:    cmd.Message.Info.PushName,
+		SourceChat:  chat,
+	}
+
+	ticket, err := s.Tickets.CreateTicket(cmd.Ctx, draft)
+	if err != nil {
+		return fmt.Errorf("no se pudo crear el ticket: %v", err)
+	}
+
+	reply := fmt.Sprintf("Ticket creado: *%s*\n%s\n%s", ticket.Key, ticket.Summary, ticket.URL)
+	if n := s.attachMedia(cmd, ticket.Key, recent); n > 0 {
+		reply += fmt.Sprintf("\nSe adjuntaron %d archivos de la conversación.", n)
+	}
+	return cmd.Reply(reply)
+}
+
+// withoutMessage quita de la conversación el mensaje del comando, que no es
+// parte del reporte
+func withoutMessage(messages []types.MessageInfo, id string) []types.MessageInfo {
+	result := make([]types.MessageInfo, 0, len(messages))
+	for _, msg := range messages {
+		if msg.ID != id {
+			result = append(result, msg)
+		}
+	}
+	if len(result) > transcriptSize {
+		result = result[len(result)-transcriptSize:]
+	}
+	return result
+}
+
+// attachMedia adjunta al ticket las imágenes, audios y documentos de la
+// conversación y devuelve cuántos se subieron
+func (s CommandServices) attachMedia(cmd *CommandContext, key string, messages []types.MessageInfo) int {
+	if s.Attachments == nil {
+		return 0
+	}
+
+	ids := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		ids = append(ids, msg.ID)
+	}
+
+	attached := 0
+	for _, media := range cmd.Client.ConversationMedia(cmd.Ctx, cmd.Chat().String(), ids) {
+		if err := s.Attachments.AddAttachment(cmd.Ctx, key, media); err != nil {
+			log.Printf("WA: No se pudo adjuntar %s a %s: %v", media.Filename, key, err)
+			continue
+		}
+		attached++
+	}
+	return attached
+}
+
+func (s CommandServices) handleStatus(cmd *CommandContext) error {
+	key := strings.ToUpper(cmd.Args[0])
+	if !issueKeyPattern.MatchString(key) {
+		return cmd.Reply(fmt.Sprintf("%q no es una clave de ticket válida (ejemplo: PROJ-123).", cmd.Args[0]))
+	}
+	if len(cmd.Args) > 1 {
+		return s.changeStatus(cmd, key, strings.Join(cmd.Args[1:], " "))
+	}
+	if s.Tickets == nil {
+		return cmd.Reply("Jira no está configurado, no puedo consultar tickets todavía.")
+	}
+
+	ticket, err := s.Tickets.GetTicket(cmd.Ctx, key)
+	if err != nil {
+		return fmt.Errorf("no se pudo consultar %s: %v", key, err)
+	}
+
+	return cmd.Reply(FormatTicket(ticket))
+}
+
+// changeStatus aplica una transición. Solo la pueden usar los administradores
+// del grupo o de Lisa, para que los clientes no cierren sus propios tickets.
+func (s CommandServices) changeStatus(cmd *CommandContext, key, transition string) error {
+	if !cmd.Router.allowed(cmd.Client, cmd.Message, PermissionGroupAdmin) {
+		return cmd.Reply(fmt.Sprintf("Solo %s pueden cambiar el estado de un ticket.", PermissionGroupAdmin))
+	}
+	if s.Workflow == nil {
+		return cmd.Reply("Jira no está configurado, no puedo cambiar el estado de tickets todavía.")
+	}
+
+	ticket, err := s.Workflow.TransitionTicket(cmd.Ctx, key, transition)
+	if err != nil {
+		return fmt.Errorf("no se pudo cambiar el estado de %s: %v", key, err)
+	}
+
+	return cmd.Reply(FormatTicket(ticket))
+}
+
+// handleSearch sin texto lista los tickets abiertos del chat. Los clientes
+// solo ven los tickets de su chat; los administradores buscan en todo Jira.
+func (s CommandServices) handleSearch(cmd *CommandContext) error {
+	if s.Search == nil {
+		return cmd.Reply("Jira no está configurado, no puedo buscar tickets todavía.")
+	}
+
+	text := strings.TrimSpace(cmd.Parsed.RawArgs)
+	query := types.TicketQuery{Text: text, OpenOnly: text == ""}
+	if text == "" || !cmd.Router.allowed(cmd.Client, cmd.Message, PermissionGroupAdmin) {
+		query.Chat = cmd.Chat().String()
+	}
+
+	tickets, err := s.Search.SearchTickets(cmd.Ctx, query, searchLimit)
+	if err != nil {
+		return fmt.Errorf("no se pudo buscar: %v", err)
+	}
+	if len(tickets) == 0 {
+		if text == "" {
+			return cmd.Reply("No hay tickets abiertos de este chat.")
+		}
+		return cmd.Reply(fmt.Sprintf("No encontré tickets que mencionen %q.", text))
+	}
+
+	var sb strings.Builder
+	if text == "" {
+		sb.WriteString("Tickets abiertos de este chat:")
+	} else {
+		sb.WriteString(fmt.Sprintf("Tickets que mencionan %q:", text))
+	}
+	for _, t := range tickets {
+		sb.WriteString(fmt.Sprintf("\n*%s* · %s · %s", t.Key, t.Status, truncate(t.Summary, 60)))
+	}
+	return cmd.Reply(sb.String())
+}
+
+func (s CommandServices) handleSummary(cmd *CommandContext) error {
+	count := 30
+	if len(cmd.Args) > 0 {
+		n, err := strconv.Atoi(cmd.Args[0])
+		if err != nil || n <= 0 {
+			return cmd.Reply("La cantidad de mensajes debe ser un número positivo.")
+		}
+		count = n
+	}
+
+	messages := cmd.Client.History().Recent(cmd.Chat().String(), count)
+	if len(messages) == 0 {
+		return cmd.Reply("No hay mensajes recientes para resumir.")
+	}
+
+	if s.Summarizer != nil {
+		summary, err := s.Summarizer.Summarize(cmd.Ctx, messages)
+		if err != nil {
+			return fmt.Errorf("no se pudo generar el resumen: %v", err)
+		}
+		return cmd.Reply("*Resumen*\n" + summary)
+	}
+
+	return cmd.Reply(BasicSummary(messages))
+}
+
+func (s CommandServices) handleWarRoom(cmd *CommandContext) error {
+	room, err := cmd.Client.CreateWarRoom(cmd.Ctx, WarRoomRequest{
+		Name:        cmd.Args[0],
+		Description: fmt.Sprintf("Sala de incidente creada por %s", cmd.Message.Info.PushName),
+		OnCall:      s.OnCall,
+		Customers:   cmd.Args[1:],
+	})
+	if err != nil {
+		return err
+	}
+
+	text := fmt.Sprintf("Sala creada: *%s* (%d participantes)", room.Group.Name, room.Group.ParticipantCount)
+	if room.InviteLink != "" {
+		text += "\nEnlace: " + room.InviteLink
+	}
+	if len(room.Invited) > 0 {
+		text += fmt.Sprintf("\nInvitación enviada por privado a %d contacto(s)", len(room.Invited))
+	}
+	return cmd.Reply(text)
+}
+
+// FormatTicket arma el texto con los datos principales de un ticket
+func FormatTicket(ticket *types.TicketInfo) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("*%s* %s\nEstado: %s", ticket.Key, ticket.Summary, ticket.Status))
+	if ticket.Priority != "" {
+		sb.WriteString("\nPrioridad: " + ticket.Priority)
+	}
+	assignee := ticket.Assignee
+	if assignee == "" {
+		assignee = "sin asignar"
+	}
+	sb.WriteString("\nAsignado: " + assignee)
+	if ticket.URL != "" {
+		sb.WriteString("\n" + ticket.URL)
+	}
+	return sb.String()
+}
+
+// Transcript convierte mensajes en una transcripción legible
+func Transcript(messages []types.MessageInfo) string {
+	var sb strings.Builder
+	for _, msg := range messages {
+		sb.WriteString(fmt.Sprintf("[%s] %s: %s\n", msg.Timestamp.Format("2006-01-02 15:04"), msg.PushName, msg.Text))
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// BasicSummary resumen sin IA: participantes, período y últimos mensajes
+func BasicSummary(messages []types.MessageInfo) string {
+	counts := make(map[string]int)
+	var names []string
+	for _, msg := range messages {
+		if counts[msg.PushName] == 0 {
+			names = append(names, msg.PushName)
+		}
+		counts[msg.PushName]++
+	}
+
+	var sb strings.Builder
+	first, last := messages[0].Timestamp, messages[len(messages)-1].Timestamp
+	sb.WriteString(fmt.Sprintf("*Resumen* (%d mensajes, %s - %s)\n\nParticipantes:\n",
+		len(messages), first.Format("02/01 15:04"), last.Format("02/01 15:04")))
+	for _, name := range names {
+		sb.WriteString(fmt.Sprintf("- %s: %d\n", name, counts[name]))
+	}
+
+	sb.WriteString("\nÚltimos mensajes:\n")
+	start := len(messages) - 5
+	if start < 0 {
+		start = 0
+	}
+	for _, msg := range messages[start:] {
+		sb.WriteString(fmt.Sprintf("- %s: %s\n", msg.PushName, truncate(msg.Text, 120)))
+	}
+
+	return strings.TrimSpace(sb.String())
+}
+
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max-1]) + "…"
+}
@@ -0,0 +1,86 @@
+package whatsapp
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	waTypes "go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+
+	"Lisa/pkg/types"
+)
+
+// EventType tipos de evento que publica el cliente
+type EventType string
+
+const (
+	// Message y Raw contienen el mensaje recibido (sin descargar el archivo)
+	EventMessage EventType = "message"
+
+	EventParticipantJoined   EventType = "participant_joined"
+	EventParticipantLeft     EventType = "participant_left"
+	EventParticipantPromoted EventType = "participant_promoted"
+	EventParticipantDemoted  EventType = "participant_demoted"
+
+	EventConnectionState EventType = "connection_state"
+	EventLoggedOut       EventType = "logged_out"
+	EventPairingCode     EventType = "pairing_code" // Reason contiene el código
+	EventPaired          EventType = "paired"
+)
+
+// Event evento de WhatsApp ya interpretado por Lisa
+type Event struct {
+	Type        EventType
+	Chat        waTypes.JID
+	ChatName    string
+	Participant waTypes.JID
+	Actor       waTypes.JID // Quién realizó el cambio (vacío si fue el propio participante)
+	State       ConnectionState
+	Reason      string
+	Timestamp   time.Time
+
+	Message *types.MediaMessage
+	Raw     *events.Message
+	// Command el router de comandos atendió el mensaje (EventMessage)
+	Command bool
+}
+
+// EventSubscriber función que recibe los eventos publicados
+type EventSubscriber func(Event)
+
+// EventBus distribuye eventos a todos los suscriptores registrados
+type EventBus struct {
+	mu          sync.RWMutex
+	subscribers []EventSubscriber
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{}
+}
+
+// Subscribe registra un suscriptor para todos los eventos
+func (b *EventBus) Subscribe(fn EventSubscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers = append(b.subscribers, fn)
+}
+
+// Publish entrega el evento a cada suscriptor. Un suscriptor que entra en
+// pánico no impide que los demás reciban el evento.
+func (b *EventBus) Publish(evt Event) {
+	b.mu.RLock()
+	subscribers := append([]EventSubscriber(nil), b.subscribers...)
+	b.mu.RUnlock()
+
+	for _, fn := range subscribers {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("WA: Suscriptor de eventos fallo con %s: %v", evt.Type, r)
+				}
+			}()
+			fn(evt)
+		}()
+	}
+}
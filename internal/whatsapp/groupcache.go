@@ -0,0 +1,110 @@
+package whatsapp
+
+import (
+	"sync"
+	"time"
+
+	waTypes "go.mau.fi/whatsmeow/types"
+)
+
+// groupCacheTTL tiempo tras el cual se vuelve a consultar la info de un grupo
+const groupCacheTTL = 15 * time.Minute
+
+type cachedGroup struct {
+	info      *waTypes.GroupInfo
+	fetchedAt time.Time
+}
+
+// GroupCache guarda la información de los grupos para no consultar a WhatsApp
+// en cada mensaje. Los cambios de participantes se aplican sobre la copia en
+// memoria a medida que llegan los eventos.
+type GroupCache struct {
+	mu     sync.RWMutex
+	groups map[waTypes.JID]*cachedGroup
+	fetch  func(waTypes.JID) (*waTypes.GroupInfo, error)
+}
+
+func NewGroupCache(fetch func(waTypes.JID) (*waTypes.GroupInfo, error)) *GroupCache {
+	return &GroupCache{
+		groups: make(map[waTypes.JID]*cachedGroup),
+		fetch:  fetch,
+	}
+}
+
+// Get devuelve la info del grupo, consultándola si no está o expiró
+func (gc *GroupCache) Get(jid waTypes.JID) (*waTypes.GroupInfo, error) {
+	gc.mu.RLock()
+	cached, ok := gc.groups[jid]
+	gc.mu.RUnlock()
+	if ok && time.Since(cached.fetchedAt) < groupCacheTTL {
+		return cached.info, nil
+	}
+
+	info, err := gc.fetch(jid)
+	if err != nil {
+		// Preferir datos viejos a no tener datos
+		if ok {
+			return cached.info, nil
+		}
+		return nil, err
+	}
+	gc.Put(info)
+	return info, nil
+}
+
+// Name devuelve el nombre del grupo o un texto genérico si no se conoce
+func (gc *GroupCache) Name(jid waTypes.JID) string {
+	info, err := gc.Get(jid)
+	if err != nil {
+		return "Grupo desconocido"
+	}
+	return info.Name
+}
+
+// Put guarda o reemplaza la info de un grupo
+func (gc *GroupCache) Put(info *waTypes.GroupInfo) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	gc.groups[info.JID] = &cachedGroup{info: info, fetchedAt: time.Now()}
+}
+
+// Invalidate elimina un grupo del cache
+func (gc *GroupCache) Invalidate(jid waTypes.JID) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	delete(gc.groups, jid)
+}
+
+// Clear vacía el cache (por ejemplo, al vincular otra cuenta)
+func (gc *GroupCache) Clear() {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	gc.groups = make(map[waTypes.JID]*cachedGroup)
+}
+
+// Update aplica una modificación sobre la copia en cache, si existe
+func (gc *GroupCache) Update(jid waTypes.JID, fn func(*waTypes.GroupInfo)) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+
+	cached, ok := gc.groups[jid]
+	if !ok {
+		return
+	}
+
+	// Copiar para no modificar info que otros ya obtuvieron
+	info := *cached.info
+	info.Participants = append([]waTypes.GroupParticipant(nil), cached.info.Participants...)
+	fn(&info)
+	gc.groups[jid] = &cachedGroup{info: &info, fetchedAt: cached.fetchedAt}
+}
+
+// findParticipant busca un participante por número, LID o JID principal
+func findParticipant(info *waTypes.GroupInfo, user waTypes.JID) int {
+	for i, p := range info.Participants {
+		if p.JID.User == user.User || p.PhoneNumber.User == user.User || p.LID.User == user.User {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,244 @@
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"go.mau.fi/whatsmeow"
+	waTypes "go.mau.fi/whatsmeow/types"
+
+	"Lisa/pkg/types"
+)
+
+// Código de error de WhatsApp cuando la privacidad del usuario no permite agregarlo
+const participantErrorPrivacy = 403
+
+// ParticipantError indica qué participantes no se pudieron modificar y por qué
+type ParticipantError struct {
+	Action string
+	Failed map[string]int // JID -> código de error de WhatsApp
+}
+
+func (e *ParticipantError) Error() string {
+	parts := make([]string, 0, len(e.Failed))
+	for jid, code := range e.Failed {
+		parts = append(parts, fmt.Sprintf("%s (código %d)", jid, code))
+	}
+	return fmt.Sprintf("no se pudo %s a: %s", e.Action, strings.Join(parts, ", "))
+}
+
+// WarRoomRequest datos para crear un grupo de incidente
+type WarRoomRequest struct {
+	Name        string
+	Description string
+	OnCall      []string // Se agregan y se promueven a administradores
+	Customers   []string // Contactos del cliente afectado
+}
+
+// WarRoom resultado de crear un grupo de incidente
+type WarRoom struct {
+	Group      types.GroupInfo
+	InviteLink string
+	Invited    []string // Participantes que no se pudieron agregar y recibieron el enlace
+}
+
+// CreateGroup crea un grupo con los participantes indicados (JIDs o números)
+func (c *Client) CreateGroup(name string, participants []string) (*types.GroupInfo, error) {
+	jids, err := parseJIDs(participants)
+	if err != nil {
+		return nil, err
+	}
+
+	groupInfo, err := c.wa().CreateGroup(whatsmeow.ReqCreateGroup{
+		Name:         name,
+		Participants: jids,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo crear el grupo %q: %v", name, err)
+	}
+
+	log.Printf("WA: Grupo creado: %s (%s)", name, groupInfo.JID)
+	info := newGroupInfo(groupInfo)
+	if failed := participantFailures(groupInfo.Participants); len(failed) > 0 {
+		return &info, &ParticipantError{Action: "agregar", Failed: failed}
+	}
+	return &info, nil
+}
+
+// AddParticipants agrega participantes a un grupo
+func (c *Client) AddParticipants(group string, participants []string) error {
+	return c.updateParticipants(group, participants, whatsmeow.ParticipantChangeAdd, "agregar")
+}
+
+// RemoveParticipants elimina participantes de un grupo
+func (c *Client) RemoveParticipants(group string, participants []string) error {
+	return c.updateParticipants(group, participants, whatsmeow.ParticipantChangeRemove, "eliminar")
+}
+
+// PromoteAdmins convierte participantes en administradores del grupo
+func (c *Client) PromoteAdmins(group string, participants []string) error {
+	return c.updateParticipants(group, participants, whatsmeow.ParticipantChangePromote, "promover")
+}
+
+// DemoteAdmins quita el rol de administrador a participantes del grupo
+func (c *Client) DemoteAdmins(group string, participants []string) error {
+	return c.updateParticipants(group, participants, whatsmeow.ParticipantChangeDemote, "degradar")
+}
+
+func (c *Client) updateParticipants(group string, participants []string, action whatsmeow.ParticipantChange, actionName string) error {
+	groupJID, err := ParseJID(group)
+	if err != nil {
+		return err
+	}
+	jids, err := parseJIDs(participants)
+	if err != nil {
+		return err
+	}
+
+	result, err := c.wa().UpdateGroupParticipants(groupJID, jids, action)
+	if err != nil {
+		return fmt.Errorf("no se pudo %s participantes en %s: %v", actionName, groupJID, err)
+	}
+
+	if failed := participantFailures(result); len(failed) > 0 {
+		return &ParticipantError{Action: actionName, Failed: failed}
+	}
+	return nil
+}
+
+// SetGroupSubject cambia el nombre (asunto) del grupo
+func (c *Client) SetGroupSubject(group, subject string) error {
+	groupJID, err := ParseJID(group)
+	if err != nil {
+		return err
+	}
+	if err := c.wa().SetGroupName(groupJID, subject); err != nil {
+		return fmt.Errorf("no se pudo cambiar el nombre de %s: %v", groupJID, err)
+	}
+	return nil
+}
+
+// SetGroupDescription cambia la descripción del grupo
+func (c *Client) SetGroupDescription(group, description string) error {
+	groupJID, err := ParseJID(group)
+	if err != nil {
+		return err
+	}
+	if err := c.wa().SetGroupDescription(groupJID, description); err != nil {
+		return fmt.Errorf("no se pudo cambiar la descripción de %s: %v", groupJID, err)
+	}
+	return nil
+}
+
+// GetInviteLink devuelve el enlace de invitación actual del grupo
+func (c *Client) GetInviteLink(group string) (string, error) {
+	return c.inviteLink(group, false)
+}
+
+// RevokeInviteLink invalida el enlace actual y devuelve uno nuevo
+func (c *Client) RevokeInviteLink(group string) (string, error) {
+	return c.inviteLink(group, true)
+}
+
+func (c *Client) inviteLink(group string, reset bool) (string, error) {
+	groupJID, err := ParseJID(group)
+	if err != nil {
+		return "", err
+	}
+	link, err := c.wa().GetGroupInviteLink(groupJID, reset)
+	if err != nil {
+		return "", fmt.Errorf("no se pudo obtener el enlace de invitación de %s: %v", groupJID, err)
+	}
+	return link, nil
+}
+
+// CreateWarRoom crea un grupo para atender un incidente: agrega a las guardias y
+// a los contactos del cliente, promueve a las guardias a administradores y envía
+// el enlace de invitación por privado a quienes no se pudo agregar directamente.
+func (c *Client) CreateWarRoom(ctx context.Context, req WarRoomRequest) (*WarRoom, error) {
+	participants := append(append([]string{}, req.OnCall...), req.Customers...)
+	if len(participants) == 0 {
+		return nil, fmt.Errorf("la sala de incidente necesita al menos un participante")
+	}
+
+	group, err := c.CreateGroup(req.Name, participants)
+	if group == nil {
+		return nil, err
+	}
+
+	room := &WarRoom{Group: *group}
+	var failed map[string]int
+	if perr, ok := err.(*ParticipantError); ok {
+		failed = perr.Failed
+	}
+
+	if req.Description != "" {
+		if err := c.SetGroupDescription(group.JID.String(), req.Description); err != nil {
+			log.Printf("WA: %v", err)
+		}
+	}
+
+	if len(req.OnCall) > 0 {
+		if err := c.PromoteAdmins(group.JID.String(), req.OnCall); err != nil {
+			log.Printf("WA: %v", err)
+		}
+	}
+
+	room.InviteLink, err = c.GetInviteLink(group.JID.String())
+	if err != nil {
+		log.Printf("WA: %v", err)
+	}
+
+	// Quien tiene privacidad restringida solo puede entrar con el enlace
+	for jid, code := range failed {
+		if code != participantErrorPrivacy || room.InviteLink == "" {
+			continue
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		text := fmt.Sprintf("Te invitamos al grupo de atención *%s*:\n%s", req.Name, room.InviteLink)
+		if err := c.SendTextMessage(jid, text); err != nil {
+			log.Printf("WA: No se pudo enviar la invitación a %s: %v", jid, err)
+			continue
+		}
+		room.Invited = append(room.Invited, jid)
+	}
+
+	return room, nil
+}
+
+func parseJIDs(values []string) ([]waTypes.JID, error) {
+	jids := make([]waTypes.JID, 0, len(values))
+	for _, v := range values {
+		jid, err := ParseJID(v)
+		if err != nil {
+			return nil, err
+		}
+		jids = append(jids, jid)
+	}
+	return jids, nil
+}
+
+func participantFailures(participants []waTypes.GroupParticipant) map[string]int {
+	failed := make(map[string]int)
+	for _, p := range participants {
+		if p.Error != 0 {
+			failed[p.JID.String()] = p.Error
+		}
+	}
+	return failed
+}
+
+func newGroupInfo(g *waTypes.GroupInfo) types.GroupInfo {
+	return types.GroupInfo{
+		JID:              g.JID,
+		Name:             g.Name,
+		Topic:            g.Topic,
+		Owner:            g.OwnerJID,
+		CreatedAt:        g.GroupCreated,
+		ParticipantCount: len(g.Participants),
+	}
+}
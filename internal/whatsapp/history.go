@@ -0,0 +1,68 @@
+package whatsapp
+
+import (
+	"sync"
+
+	"Lisa/pkg/types"
+)
+
+// defaultHistoryLimit cantidad de mensajes recientes que se guardan por chat
+const defaultHistoryLimit = 100
+
+// History guarda en memoria los últimos mensajes de cada chat, para poder
+// armar resúmenes y transcripciones sin consultar a WhatsApp
+type History struct {
+	mu    sync.RWMutex
+	limit int
+	chats map[string][]types.MessageInfo
+}
+
+func NewHistory(limit int) *History {
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+	return &History{
+		limit: limit,
+		chats: make(map[string][]types.MessageInfo),
+	}
+}
+
+// Add agrega un mensaje al historial de su chat, descartando los más antiguos
+func (h *History) Add(info types.MessageInfo) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	messages := append(h.chats[info.From], info)
+	if len(messages) > h.limit {
+		messages = messages[len(messages)-h.limit:]
+	}
+	h.chats[info.From] = messages
+}
+
+// Recent devuelve hasta n mensajes recientes de un chat, del más antiguo al más nuevo
+func (h *History) Recent(chat string, n int) []types.MessageInfo {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	messages := h.chats[chat]
+	if n > 0 && len(messages) > n {
+		messages = messages[len(messages)-n:]
+	}
+
+	result := make([]types.MessageInfo, len(messages))
+	copy(result, messages)
+	return result
+}
+
+// Find busca un mensaje por ID dentro del historial de un chat
+func (h *History) Find(chat, id string) (types.MessageInfo, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, msg := range h.chats[chat] {
+		if msg.ID == id {
+			return msg, true
+		}
+	}
+	return types.MessageInfo{}, false
+}
@@ -0,0 +1,221 @@
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"mime"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	waTypes "go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+
+	"Lisa/pkg/types"
+)
+
+const (
+	// avatarTTL las URLs de las fotos de perfil son firmadas y caducan
+	avatarTTL = time.Hour
+
+	// mediaCacheSize mensajes con archivo que se recuerdan por chat para adjuntarlos a tickets
+	mediaCacheSize = 50
+)
+
+// MediaTooLargeError el archivo supera WA_MEDIA_MAX_MB y no se descargó
+type MediaTooLargeError struct {
+	Size  int64
+	Limit int64
+}
+
+func (e *MediaTooLargeError) Error() string {
+	return fmt.Sprintf("el archivo pesa %d MB y el límite es %d MB", e.Size>>20, e.Limit>>20)
+}
+
+// NewMediaMessage arma el mensaje con los metadatos del archivo (tipo, nombre,
+// tamaño), sin descargar el contenido
+func NewMediaMessage(msg *events.Message) *types.MediaMessage {
+	media := types.NewWhatsAppMessageFromEvent(msg)
+	media.Type = types.GetMessageType(msg)
+
+	m := msg.Message
+	switch media.Type {
+	case types.MessageTypeImage:
+		img := m.GetImageMessage()
+		media.MimeType, media.Caption, media.Size = img.GetMimetype(), img.GetCaption(), int64(img.GetFileLength())
+	case types.MessageTypeVideo:
+		video := m.GetVideoMessage()
+		media.MimeType, media.Caption, media.Size = video.GetMimetype(), video.GetCaption(), int64(video.GetFileLength())
+		media.Duration = int(video.GetSeconds())
+	case types.MessageTypeAudio:
+		audio := m.GetAudioMessage()
+		media.MimeType, media.Size = audio.GetMimetype(), int64(audio.GetFileLength())
+		media.Duration = int(audio.GetSeconds())
+	case types.MessageTypeDocument:
+		doc := m.GetDocumentMessage()
+		media.MimeType, media.Caption, media.Size = doc.GetMimetype(), doc.GetCaption(), int64(doc.GetFileLength())
+		media.Filename = doc.GetFileName()
+	case types.MessageTypeSticker:
+		sticker := m.GetStickerMessage()
+		media.MimeType, media.Size = sticker.GetMimetype(), int64(sticker.GetFileLength())
+	}
+
+	if media.Filename == "" && media.MimeType != "" {
+		media.Filename = media.Info.ID + mediaExtension(media.MimeType)
+	}
+	return &media
+}
+
+// downloadable devuelve la parte descargable del mensaje, o nil si no tiene archivo
+func downloadable(msg *events.Message) whatsmeow.DownloadableMessage {
+	m := msg.Message
+	switch {
+	case m.GetImageMessage() != nil:
+		return m.GetImageMessage()
+	case m.GetVideoMessage() != nil:
+		return m.GetVideoMessage()
+	case m.GetAudioMessage() != nil:
+		return m.GetAudioMessage()
+	case m.GetDocumentMessage() != nil:
+		return m.GetDocumentMessage()
+	case m.GetStickerMessage() != nil:
+		return m.GetStickerMessage()
+	default:
+		return nil
+	}
+}
+
+// DownloadMedia descarga el archivo de un mensaje respetando WA_MEDIA_MAX_MB.
+// Si el mensaje no tiene archivo devuelve solo los metadatos.
+func (c *Client) DownloadMedia(ctx context.Context, msg *events.Message) (*types.MediaMessage, error) {
+	media := NewMediaMessage(msg)
+
+	file := downloadable(msg)
+	if file == nil {
+		return media, nil
+	}
+	if c.mediaMaxBytes > 0 && media.Size > c.mediaMaxBytes {
+		return media, &MediaTooLargeError{Size: media.Size, Limit: c.mediaMaxBytes}
+	}
+
+	data, err := c.wa().Download(ctx, file)
+	if err != nil {
+		return media, fmt.Errorf("no se pudo descargar el archivo de %s: %v", msg.Info.ID, err)
+	}
+
+	media.Data = data
+	media.Size = int64(len(data))
+	media.NeedsProcessing = false
+	return media, nil
+}
+
+// mediaExtension extensión de archivo para un tipo MIME ("audio/ogg; codecs=opus" -> ".ogg")
+func mediaExtension(mimeType string) string {
+	base := strings.TrimSpace(strings.Split(mimeType, ";")[0])
+	switch base {
+	case "image/jpeg":
+		return ".jpg"
+	case "audio/ogg":
+		return ".ogg"
+	case "audio/mpeg":
+		return ".mp3"
+	case "video/mp4":
+		return ".mp4"
+	case "image/webp":
+		return ".webp"
+	}
+	if exts, err := mime.ExtensionsByType(base); err == nil && len(exts) > 0 {
+		return exts[0]
+	}
+	return ""
+}
+
+// mediaCache mensajes con archivo recientes por chat. Se guarda el evento y
+// el archivo se descarga solo cuando hace falta (por ejemplo, al crear un ticket).
+type mediaCache struct {
+	mu    sync.Mutex
+	chats map[string][]*events.Message
+}
+
+func (m *mediaCache) add(msg *events.Message) {
+	if downloadable(msg) == nil {
+		return
+	}
+	chat := msg.Info.Chat.String()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	messages := append(m.chats[chat], msg)
+	if len(messages) > mediaCacheSize {
+		messages = messages[len(messages)-mediaCacheSize:]
+	}
+	m.chats[chat] = messages
+}
+
+// ConversationMedia descarga los archivos de los mensajes indicados del chat
+// que sigan en memoria. Los que no se pueden descargar (por ejemplo, por
+// superar WA_MEDIA_MAX_MB) se omiten.
+func (c *Client) ConversationMedia(ctx context.Context, chat string, messageIDs []string) []*types.MediaMessage {
+	wanted := make(map[string]bool, len(messageIDs))
+	for _, id := range messageIDs {
+		wanted[id] = true
+	}
+
+	c.media.mu.Lock()
+	var pending []*events.Message
+	for _, msg := range c.media.chats[chat] {
+		if wanted[msg.Info.ID] {
+			pending = append(pending, msg)
+		}
+	}
+	c.media.mu.Unlock()
+
+	var files []*types.MediaMessage
+	for _, msg := range pending {
+		media, err := c.DownloadMedia(ctx, msg)
+		if err != nil {
+			log.Printf("WA: No se adjunta el archivo de %s: %v", msg.Info.ID, err)
+			continue
+		}
+		files = append(files, media)
+	}
+	return files
+}
+
+// avatarCache fotos de perfil por usuario
+type avatarCache struct {
+	mu      sync.Mutex
+	entries map[string]avatarEntry
+}
+
+type avatarEntry struct {
+	url     string
+	fetched time.Time
+}
+
+// AvatarURL devuelve la URL de la foto de perfil de un contacto, o "" si no
+// tiene o su privacidad no la muestra. Las respuestas vacías también se
+// guardan para no consultar a WhatsApp en cada mensaje.
+func (c *Client) AvatarURL(jid waTypes.JID) string {
+	key := jid.ToNonAD().String()
+
+	c.avatars.mu.Lock()
+	entry, ok := c.avatars.entries[key]
+	c.avatars.mu.Unlock()
+	if ok && time.Since(entry.fetched) < avatarTTL {
+		return entry.url
+	}
+
+	entry = avatarEntry{fetched: time.Now()}
+	info, err := c.wa().GetProfilePictureInfo(jid.ToNonAD(), &whatsmeow.GetProfilePictureParams{Preview: true})
+	if err == nil && info != nil {
+		entry.url = info.URL
+	}
+
+	c.avatars.mu.Lock()
+	c.avatars.entries[key] = entry
+	c.avatars.mu.Unlock()
+	return entry.url
+}
@@ -1 +1,155 @@
 package whatsapp
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	waTypes "go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// ParsedCommand comando reconocido dentro de un mensaje
+type ParsedCommand struct {
+	Name      string   // Nombre del comando en minúsculas, sin prefijo
+	Args      []string // Argumentos ya separados (respetando comillas)
+	RawArgs   string   // Texto completo después del nombre del comando
+	Prefix    string   // Prefijo usado ("" si se invocó por mención)
+	Mentioned bool     // true si el bot fue mencionado con @
+}
+
+// ParseCommand interpreta un texto como comando si empieza con alguno de los
+// prefijos. Si mentioned es true el prefijo es opcional, porque la mención al
+// bot ya indica que el mensaje va dirigido a él.
+func ParseCommand(text string, prefixes []string, mentioned bool) (*ParsedCommand, bool) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, false
+	}
+
+	prefix := ""
+	for _, p := range prefixes {
+		if p != "" && strings.HasPrefix(text, p) {
+			prefix = p
+			break
+		}
+	}
+	if prefix == "" && !mentioned {
+		return nil, false
+	}
+	text = strings.TrimSpace(strings.TrimPrefix(text, prefix))
+
+	name, rest, _ := strings.Cut(text, " ")
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return nil, false
+	}
+
+	rest = strings.TrimSpace(rest)
+	return &ParsedCommand{
+		Name:      name,
+		Args:      SplitArgs(rest),
+		RawArgs:   rest,
+		Prefix:    prefix,
+		Mentioned: mentioned,
+	}, true
+}
+
+// SplitArgs separa argumentos por espacios respetando comillas dobles y simples
+func SplitArgs(s string) []string {
+	var (
+		args    []string
+		current strings.Builder
+		quote   rune
+		inArg   bool
+	)
+
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'' || r == '“' || r == '”':
+			if r == '“' {
+				quote = '”'
+			} else {
+				quote = r
+			}
+			inArg = true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+
+	return args
+}
+
+// messageText extrae el texto de un mensaje (conversación, texto extendido o caption)
+func messageText(msg *events.Message) string {
+	if text := msg.Message.GetConversation(); text != "" {
+		return text
+	}
+	if ext := msg.Message.GetExtendedTextMessage(); ext != nil {
+		return ext.GetText()
+	}
+	if img := msg.Message.GetImageMessage(); img != nil {
+		return img.GetCaption()
+	}
+	if vid := msg.Message.GetVideoMessage(); vid != nil {
+		return vid.GetCaption()
+	}
+	return ""
+}
+
+// mentionedJIDs devuelve los JIDs mencionados en un mensaje de texto extendido
+func mentionedJIDs(msg *events.Message) []waTypes.JID {
+	ext := msg.Message.GetExtendedTextMessage()
+	if ext == nil || ext.GetContextInfo() == nil {
+		return nil
+	}
+
+	var jids []waTypes.JID
+	for _, raw := range ext.GetContextInfo().GetMentionedJID() {
+		if jid, err := waTypes.ParseJID(raw); err == nil {
+			jids = append(jids, jid)
+		}
+	}
+	return jids
+}
+
+// stripMentions elimina del texto las menciones (@numero) a los usuarios indicados
+func stripMentions(text string, users ...string) string {
+	for _, user := range users {
+		if user != "" {
+			text = strings.ReplaceAll(text, "@"+user, "")
+		}
+	}
+	return strings.TrimSpace(text)
+}
+
+// ParseJID acepta un JID completo o un número de teléfono (con o sin +)
+func ParseJID(s string) (waTypes.JID, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "@") {
+		return waTypes.ParseJID(s)
+	}
+
+	phone := strings.TrimPrefix(s, "+")
+	if phone == "" || strings.IndexFunc(phone, func(r rune) bool { return r < '0' || r > '9' }) >= 0 {
+		return waTypes.JID{}, fmt.Errorf("JID o número inválido: %q", s)
+	}
+	return waTypes.NewJID(phone, waTypes.DefaultUserServer), nil
+}
@@ -0,0 +1,105 @@
+package whatsapp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCommand(t *testing.T) {
+	prefixes := []string{"!", "/"}
+	tests := []struct {
+		name      string
+		text      string
+		mentioned bool
+		want      *ParsedCommand
+	}{
+		{name: "sin prefijo", text: "hola equipo"},
+		{name: "vacío", text: "   "},
+		{name: "solo el prefijo", text: "! "},
+		{
+			name: "con prefijo",
+			text: "  !Ticket  No carga la app ",
+			want: &ParsedCommand{Name: "ticket", Args: []string{"No", "carga", "la", "app"}, RawArgs: "No carga la app", Prefix: "!"},
+		},
+		{
+			name: "segundo prefijo",
+			text: "/estado PROJ-12",
+			want: &ParsedCommand{Name: "estado", Args: []string{"PROJ-12"}, RawArgs: "PROJ-12", Prefix: "/"},
+		},
+		{
+			name: "argumentos entre comillas",
+			text: `!buscar "no carga" 'app móvil'`,
+			want: &ParsedCommand{Name: "buscar", Args: []string{"no carga", "app móvil"}, RawArgs: `"no carga" 'app móvil'`, Prefix: "!"},
+		},
+		{
+			name:      "mención sin prefijo",
+			text:      "ayuda",
+			mentioned: true,
+			want:      &ParsedCommand{Name: "ayuda", Args: nil, RawArgs: "", Mentioned: true},
+		},
+		{
+			name:      "mención con prefijo",
+			text:      "!ayuda",
+			mentioned: true,
+			want:      &ParsedCommand{Name: "ayuda", Args: nil, RawArgs: "", Prefix: "!", Mentioned: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseCommand(tt.text, prefixes, tt.mentioned)
+			if ok != (tt.want != nil) {
+				t.Fatalf("ParseCommand(%q) ok = %v, se esperaba %v", tt.text, ok, tt.want != nil)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseCommand(%q) = %+v, se esperaba %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"   ", nil},
+		{"uno dos\ttres", []string{"uno", "dos", "tres"}},
+		{`"dos palabras" suelta`, []string{"dos palabras", "suelta"}},
+		{`'simples' y "dobles"`, []string{"simples", "y", "dobles"}},
+		{"“tipográficas de iPhone” fin", []string{"tipográficas de iPhone", "fin"}},
+		{`pegado"junto con"esto`, []string{"pegadojunto conesto"}},
+		{`vacío ""`, []string{"vacío", ""}},
+		{`"sin cerrar`, []string{"sin cerrar"}},
+		{`"comilla 'adentro'"`, []string{"comilla 'adentro'"}},
+	}
+	for _, tt := range tests {
+		if got := SplitArgs(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitArgs(%q) = %q, se esperaba %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseJID(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "5491122334455", want: "5491122334455@s.whatsapp.net"},
+		{in: " +5491122334455 ", want: "5491122334455@s.whatsapp.net"},
+		{in: "120363000000000000@g.us", want: "120363000000000000@g.us"},
+		{in: "+54 9 11", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseJID(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseJID(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if err == nil && got.String() != tt.want {
+			t.Errorf("ParseJID(%q) = %s, se esperaba %s", tt.in, got, tt.want)
+		}
+	}
+}
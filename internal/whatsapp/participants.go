@@ -0,0 +1,154 @@
+package whatsapp
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	waTypes "go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+
+	"Lisa/internal/config"
+)
+
+// handleGroupInfo procesa cambios de un grupo: nombre, descripción y participantes
+func (c *Client) handleGroupInfo(evt *events.GroupInfo) {
+	if evt.Name != nil {
+		c.groups.Update(evt.JID, func(info *waTypes.GroupInfo) { info.GroupName = *evt.Name })
+	}
+	if evt.Topic != nil {
+		c.groups.Update(evt.JID, func(info *waTypes.GroupInfo) { info.GroupTopic = *evt.Topic })
+	}
+
+	if len(evt.Join)+len(evt.Leave)+len(evt.Promote)+len(evt.Demote) == 0 {
+		return
+	}
+
+	c.groups.Update(evt.JID, func(info *waTypes.GroupInfo) {
+		for _, jid := range evt.Join {
+			if findParticipant(info, jid) < 0 {
+				info.Participants = append(info.Participants, waTypes.GroupParticipant{JID: jid})
+			}
+		}
+		for _, jid := range evt.Leave {
+			if i := findParticipant(info, jid); i >= 0 {
+				info.Participants = append(info.Participants[:i], info.Participants[i+1:]...)
+			}
+		}
+		for _, jid := range evt.Promote {
+			if i := findParticipant(info, jid); i >= 0 {
+				info.Participants[i].IsAdmin = true
+			}
+		}
+		for _, jid := range evt.Demote {
+			if i := findParticipant(info, jid); i >= 0 {
+				info.Participants[i].IsAdmin = false
+			}
+		}
+	})
+
+	groupName := c.groups.Name(evt.JID)
+	var actor waTypes.JID
+	if evt.Sender != nil {
+		actor = *evt.Sender
+	}
+	timestamp := evt.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	publish := func(eventType EventType, participants []waTypes.JID, reason string) {
+		for _, jid := range participants {
+			evtActor := actor
+			if evtActor.User == jid.User {
+				evtActor = waTypes.JID{}
+			}
+			log.Printf("WA [GRUPO:%s] %s: %s", groupName, eventType, jid)
+			c.events.Publish(Event{
+				Type:        eventType,
+				Chat:        evt.JID,
+				ChatName:    groupName,
+				Participant: jid,
+				Actor:       evtActor,
+				Reason:      reason,
+				Timestamp:   timestamp,
+			})
+		}
+	}
+
+	publish(EventParticipantJoined, evt.Join, evt.JoinReason)
+	publish(EventParticipantLeft, evt.Leave, "")
+	publish(EventParticipantPromoted, evt.Promote, "")
+	publish(EventParticipantDemoted, evt.Demote, "")
+
+	templates := c.templatesFor(evt.JID)
+	if templates.Welcome != "" && len(evt.Join) > 0 {
+		go c.sendGroupTemplate(evt.JID, groupName, templates.Welcome, evt.Join)
+	}
+	if templates.Goodbye != "" && len(evt.Leave) > 0 {
+		go c.sendGroupTemplate(evt.JID, groupName, templates.Goodbye, evt.Leave)
+	}
+}
+
+// templatesFor devuelve las plantillas del grupo, completando con las globales
+func (c *Client) templatesFor(group waTypes.JID) config.GroupTemplates {
+	templates := c.defaultTemplates
+	if override, ok := c.groupTemplates[group.String()]; ok {
+		if override.Welcome != "" {
+			templates.Welcome = override.Welcome
+		}
+		if override.Goodbye != "" {
+			templates.Goodbye = override.Goodbye
+		}
+	}
+	return templates
+}
+
+// sendGroupTemplate envía una plantilla de bienvenida o despedida. Variables
+// disponibles: {nombre}, {mencion} y {grupo}. Con varios participantes se
+// envía un solo mensaje con los nombres separados por comas.
+func (c *Client) sendGroupTemplate(group waTypes.JID, groupName, template string, participants []waTypes.JID) {
+	names := make([]string, 0, len(participants))
+	mentions := make([]string, 0, len(participants))
+	mentionedJIDs := make([]string, 0, len(participants))
+	for _, jid := range participants {
+		names = append(names, c.contactName(jid))
+		mentions = append(mentions, "@"+jid.User)
+		mentionedJIDs = append(mentionedJIDs, jid.String())
+	}
+
+	text := strings.NewReplacer(
+		"{nombre}", strings.Join(names, ", "),
+		"{mencion}", strings.Join(mentions, " "),
+		"{grupo}", groupName,
+	).Replace(template)
+
+	msg := &waE2E.Message{
+		ExtendedTextMessage: &waE2E.ExtendedTextMessage{
+			Text: proto.String(text),
+		},
+	}
+	if strings.Contains(template, "{mencion}") {
+		msg.ExtendedTextMessage.ContextInfo = &waE2E.ContextInfo{MentionedJID: mentionedJIDs}
+	}
+
+	if _, err := c.wa().SendMessage(c.ctx, group, msg); err != nil {
+		log.Printf("WA: No se pudo enviar mensaje de grupo a %s: %v", groupName, err)
+	}
+}
+
+// contactName devuelve el nombre conocido de un contacto o su número
+func (c *Client) contactName(jid waTypes.JID) string {
+	contact, err := c.wa().Store.Contacts.GetContact(c.ctx, jid)
+	if err == nil && contact.Found {
+		for _, name := range []string{contact.FullName, contact.PushName, contact.BusinessName} {
+			if name != "" {
+				return name
+			}
+		}
+	}
+	return fmt.Sprintf("+%s", jid.User)
+}
@@ -0,0 +1,273 @@
+package whatsapp
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"golang.org/x/net/proxy"
+
+	"Lisa/internal/config"
+)
+
+// proxyProbeAddr destino de la prueba del proxy tras una desconexión
+const proxyProbeAddr = "web.whatsapp.com:443"
+
+// proxySettings proxies ya validados para el websocket y la multimedia
+type proxySettings struct {
+	websocket *url.URL
+	media     *url.URL
+}
+
+// newProxySettings valida la configuración de proxy. Si no hay proxy de
+// multimedia se usa el mismo que para el websocket.
+func newProxySettings(cfg config.WhatsAppConfig) (*proxySettings, error) {
+	if cfg.ProxyURL == "" && cfg.MediaProxyURL == "" {
+		return nil, nil
+	}
+
+	settings := &proxySettings{}
+	var err error
+	if cfg.ProxyURL != "" {
+		settings.websocket, err = parseProxyURL(cfg.ProxyURL, cfg.ProxyUser, cfg.ProxyPassword)
+		if err != nil {
+			return nil, fmt.Errorf("WA_PROXY_URL inválido: %v", err)
+		}
+	}
+
+	settings.media = settings.websocket
+	if cfg.MediaProxyURL != "" {
+		settings.media, err = parseProxyURL(cfg.MediaProxyURL, cfg.ProxyUser, cfg.ProxyPassword)
+		if err != nil {
+			return nil, fmt.Errorf("WA_MEDIA_PROXY_URL inválido: %v", err)
+		}
+	}
+
+	return settings, nil
+}
+
+// parseProxyURL valida el esquema y agrega las credenciales si no vienen en el URL
+func parseProxyURL(raw, user, password string) (*url.URL, error) {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	switch parsed.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("esquema %q no soportado (usa http, https o socks5)", parsed.Scheme)
+	}
+	if parsed.Host == "" {
+		return nil, fmt.Errorf("falta el host del proxy")
+	}
+
+	if parsed.User == nil && user != "" {
+		parsed.User = url.UserPassword(user, password)
+	}
+	return parsed, nil
+}
+
+// apply configura los proxies en el cliente de whatsmeow. Primero se configura
+// el proxy de multimedia para ambos usos y luego se reemplaza solo el del
+// websocket, porque whatsmeow no permite un SOCKS5 solo para multimedia. El
+// websocket siempre usa proxyDialer para reconocer las fallas del proxy.
+func (p *proxySettings) apply(whatsAppClient *whatsmeow.Client) error {
+	if p == nil {
+		return nil
+	}
+
+	if p.media != nil {
+		if err := setProxy(whatsAppClient, p.media, whatsmeow.SetProxyOptions{}); err != nil {
+			return err
+		}
+	}
+
+	if p.websocket == nil {
+		whatsAppClient.SetProxy(nil, whatsmeow.SetProxyOptions{NoMedia: true})
+	} else {
+		dialer, err := newProxyDialer(p.websocket)
+		if err != nil {
+			return err
+		}
+		whatsAppClient.SetSOCKSProxy(dialer, whatsmeow.SetProxyOptions{NoMedia: true})
+	}
+
+	log.Printf("WA: Usando proxy %s (multimedia: %s)", redactProxy(p.websocket), redactProxy(p.media))
+	return nil
+}
+
+func setProxy(whatsAppClient *whatsmeow.Client, proxyURL *url.URL, opts whatsmeow.SetProxyOptions) error {
+	if proxyURL.Scheme == "socks5" {
+		dialer, err := newProxyDialer(proxyURL)
+		if err != nil {
+			return err
+		}
+		whatsAppClient.SetSOCKSProxy(dialer, opts)
+		return nil
+	}
+
+	whatsAppClient.SetProxy(http.ProxyURL(proxyURL), opts)
+	return nil
+}
+
+// probe abre y cierra una conexión a WhatsApp a través del proxy del
+// websocket, para saber si una desconexión se debe al proxy
+func (p *proxySettings) probe(ctx context.Context) error {
+	if p == nil || p.websocket == nil {
+		return nil
+	}
+	dialer, err := newProxyDialer(p.websocket)
+	if err != nil {
+		return err
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", proxyProbeAddr)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// ProxyError falla al conectar con el proxy o al abrir el túnel a través de él
+type ProxyError struct {
+	Proxy string
+	Err   error
+}
+
+func (e *ProxyError) Error() string {
+	return fmt.Sprintf("proxy %s: %v", e.Proxy, e.Err)
+}
+
+func (e *ProxyError) Unwrap() error {
+	return e.Err
+}
+
+// proxyDialer abre conexiones a través de un proxy SOCKS5 o HTTP (CONNECT) y
+// devuelve sus fallas como *ProxyError
+type proxyDialer struct {
+	proxyURL *url.URL
+	socks    proxy.Dialer // nil en proxies HTTP
+}
+
+func newProxyDialer(proxyURL *url.URL) (*proxyDialer, error) {
+	d := &proxyDialer{proxyURL: proxyURL}
+	if proxyURL.Scheme == "socks5" {
+		socks, err := proxy.FromURL(proxyURL, proxy.Direct)
+		if err != nil {
+			return nil, fmt.Errorf("proxy SOCKS5 inválido: %v", err)
+		}
+		d.socks = socks
+	}
+	return d, nil
+}
+
+func (d *proxyDialer) Dial(network, addr string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, addr)
+}
+
+func (d *proxyDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	var conn net.Conn
+	var err error
+	if d.socks != nil {
+		if contextDialer, ok := d.socks.(proxy.ContextDialer); ok {
+			conn, err = contextDialer.DialContext(ctx, network, addr)
+		} else {
+			conn, err = d.socks.Dial(network, addr)
+		}
+	} else {
+		conn, err = d.connect(ctx, network, addr)
+	}
+	if err != nil {
+		return nil, &ProxyError{Proxy: d.proxyURL.Redacted(), Err: err}
+	}
+	return conn, nil
+}
+
+// connect abre un túnel HTTP CONNECT hasta addr
+func (d *proxyDialer) connect(ctx context.Context, network, addr string) (net.Conn, error) {
+	host := d.proxyURL.Host
+	if d.proxyURL.Port() == "" {
+		port := "80"
+		if d.proxyURL.Scheme == "https" {
+			port = "443"
+		}
+		host = net.JoinHostPort(d.proxyURL.Hostname(), port)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, host)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+	if d.proxyURL.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: d.proxyURL.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if user := d.proxyURL.User; user != nil {
+		password, _ := user.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	// El servidor no envía nada hasta que el cliente habla, así que el buffer
+	// no se queda con datos del túnel
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("el proxy respondió %s", resp.Status)
+	}
+	return conn, nil
+}
+
+func redactProxy(proxyURL *url.URL) string {
+	if proxyURL == nil {
+		return "directo"
+	}
+	return proxyURL.Redacted()
+}
+
+// isProxyError indica si el error viene del proxy: del dialer del websocket
+// (*ProxyError) o de la conexión de multimedia de net/http o SOCKS5
+func isProxyError(err error) bool {
+	var proxyErr *ProxyError
+	if errors.As(err, &proxyErr) {
+		return true
+	}
+
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && (opErr.Op == "proxyconnect" || strings.HasPrefix(opErr.Op, "socks"))
+}
@@ -0,0 +1,322 @@
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	waTypes "go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// commandTimeout tiempo máximo que puede tardar un comando
+const commandTimeout = 60 * time.Second
+
+// Permission nivel de permiso requerido para ejecutar un comando
+type Permission int
+
+const (
+	PermissionEveryone Permission = iota
+	PermissionGroupAdmin
+	PermissionBotAdmin
+)
+
+func (p Permission) String() string {
+	switch p {
+	case PermissionGroupAdmin:
+		return "administradores del grupo"
+	case PermissionBotAdmin:
+		return "administradores del bot"
+	default:
+		return "todos"
+	}
+}
+
+// CommandHandler ejecuta un comando ya validado
+type CommandHandler func(cmd *CommandContext) error
+
+// Command definición de un comando del chat
+type Command struct {
+	Name        string
+	Aliases     []string
+	Description string
+	Usage       string // Argumentos, ej: "<CLAVE> [estado]"
+	MinArgs     int
+	Permission  Permission
+	GroupOnly   bool
+	Handler     CommandHandler
+}
+
+// CommandContext información disponible para el handler de un comando
+type CommandContext struct {
+	Ctx     context.Context
+	Client  *Client
+	Router  *CommandRouter
+	Message *events.Message
+	Command *Command
+	Parsed  *ParsedCommand
+	Args    []string
+}
+
+// Reply responde citando el mensaje que invocó el comando
+func (c *CommandContext) Reply(text string) error {
+	return c.Client.ReplyToMessage(c.Message, text)
+}
+
+func (c *CommandContext) Chat() waTypes.JID {
+	return c.Message.Info.Chat
+}
+
+func (c *CommandContext) Sender() waTypes.JID {
+	return c.Message.Info.Sender
+}
+
+// CommandRouter reconoce comandos por prefijo o mención y los despacha a sus handlers
+type CommandRouter struct {
+	mu       sync.RWMutex
+	prefixes []string
+	admins   map[string]bool
+	commands map[string]*Command
+	ordered  []*Command
+}
+
+func NewCommandRouter(prefixes, adminJIDs []string) *CommandRouter {
+	r := &CommandRouter{
+		prefixes: prefixes,
+		admins:   make(map[string]bool),
+		commands: make(map[string]*Command),
+	}
+	for _, admin := range adminJIDs {
+		if jid, err := ParseJID(admin); err == nil {
+			r.admins[jid.User] = true
+		}
+	}
+
+	r.MustRegister(Command{
+		Name:        "ayuda",
+		Aliases:     []string{"help"},
+		Description: "Muestra los comandos disponibles",
+		Usage:       "[comando]",
+		Handler:     r.handleHelp,
+	})
+
+	return r
+}
+
+// Register agrega un comando al registro. Falla si el nombre o un alias ya existe.
+func (r *CommandRouter) Register(cmd Command) error {
+	if cmd.Name == "" || cmd.Handler == nil {
+		return fmt.Errorf("comando inválido: nombre y handler son obligatorios")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := append([]string{cmd.Name}, cmd.Aliases...)
+	for _, name := range names {
+		if _, exists := r.commands[strings.ToLower(name)]; exists {
+			return fmt.Errorf("comando duplicado: %s", name)
+		}
+	}
+
+	c := &cmd
+	for _, name := range names {
+		r.commands[strings.ToLower(name)] = c
+	}
+	r.ordered = append(r.ordered, c)
+	sort.Slice(r.ordered, func(i, j int) bool { return r.ordered[i].Name < r.ordered[j].Name })
+
+	return nil
+}
+
+// MustRegister igual que Register pero entra en pánico si hay error (para registros estáticos)
+func (r *CommandRouter) MustRegister(cmd Command) {
+	if err := r.Register(cmd); err != nil {
+		panic(err)
+	}
+}
+
+// Lookup busca un comando por nombre o alias
+func (r *CommandRouter) Lookup(name string) (*Command, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	cmd, ok := r.commands[strings.ToLower(name)]
+	return cmd, ok
+}
+
+// Commands devuelve los comandos registrados ordenados por nombre
+func (r *CommandRouter) Commands() []*Command {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]*Command(nil), r.ordered...)
+}
+
+// Prefix devuelve el prefijo principal, usado en los textos de ayuda
+func (r *CommandRouter) Prefix() string {
+	if len(r.prefixes) == 0 {
+		return ""
+	}
+	return r.prefixes[0]
+}
+
+// IsBotAdmin indica si un JID está configurado como administrador del bot
+func (r *CommandRouter) IsBotAdmin(jid waTypes.JID) bool {
+	return r.admins[jid.User]
+}
+
+// Match determina si un mensaje es un comando: por prefijo, o por mención al bot en grupos
+func (r *CommandRouter) Match(c *Client, msg *events.Message) (*ParsedCommand, bool) {
+	text := messageText(msg)
+	if text == "" {
+		return nil, false
+	}
+
+	mentioned := false
+	if msg.Info.IsGroup {
+		own := c.ownUsers()
+		for _, jid := range mentionedJIDs(msg) {
+			for _, user := range own {
+				if jid.User == user {
+					mentioned = true
+				}
+			}
+		}
+		if mentioned {
+			text = stripMentions(text, own...)
+		}
+	}
+
+	return ParseCommand(text, r.prefixes, mentioned)
+}
+
+// Dispatch ejecuta el comando contenido en el mensaje, si lo hay. Devuelve true
+// si el mensaje era un comando. El handler corre en su propia goroutine para no
+// bloquear el procesamiento de eventos de whatsmeow.
+func (r *CommandRouter) Dispatch(c *Client, msg *events.Message) bool {
+	parsed, ok := r.Match(c, msg)
+	if !ok {
+		return false
+	}
+
+	cmd, found := r.Lookup(parsed.Name)
+	if !found {
+		// Una mención sin comando conocido no es necesariamente para el bot, y
+		// los clientes pueden escribir textos que empiezan con un prefijo
+		// ("!!! urgente", "/home/..."): solo se responde si mencionan al bot o
+		// lo escribe un administrador
+		if parsed.Prefix == "" || (!parsed.Mentioned && !r.IsBotAdmin(msg.Info.Sender)) {
+			return false
+		}
+		go r.reply(c, msg, fmt.Sprintf("Comando desconocido: %s%s. Usa %sayuda para ver los comandos disponibles.",
+			parsed.Prefix, parsed.Name, r.Prefix()))
+		return true
+	}
+
+	go r.run(c, msg, cmd, parsed)
+	return true
+}
+
+func (r *CommandRouter) run(c *Client, msg *events.Message, cmd *Command, parsed *ParsedCommand) {
+	log.Printf("WA: Comando %s de %s en %s", cmd.Name, msg.Info.Sender, msg.Info.Chat)
+
+	if cmd.GroupOnly && !msg.Info.IsGroup {
+		r.reply(c, msg, "Este comando solo funciona en grupos.")
+		return
+	}
+
+	if !r.allowed(c, msg, cmd.Permission) {
+		r.reply(c, msg, fmt.Sprintf("No tienes permiso para usar %s%s (solo %s).", r.Prefix(), cmd.Name, cmd.Permission))
+		return
+	}
+
+	if len(parsed.Args) < cmd.MinArgs {
+		r.reply(c, msg, "Uso: "+r.UsageText(cmd))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, commandTimeout)
+	defer cancel()
+
+	err := cmd.Handler(&CommandContext{
+		Ctx:     ctx,
+		Client:  c,
+		Router:  r,
+		Message: msg,
+		Command: cmd,
+		Parsed:  parsed,
+		Args:    parsed.Args,
+	})
+	if err != nil {
+		log.Printf("WA: Error ejecutando comando %s: %v", cmd.Name, err)
+		r.reply(c, msg, fmt.Sprintf("ERROR: %v", err))
+	}
+}
+
+// allowed verifica si el remitente tiene el permiso requerido
+func (r *CommandRouter) allowed(c *Client, msg *events.Message, perm Permission) bool {
+	if perm == PermissionEveryone || r.IsBotAdmin(msg.Info.Sender) {
+		return true
+	}
+	if perm == PermissionGroupAdmin && msg.Info.IsGroup {
+		return c.IsGroupAdmin(msg.Info.Chat, msg.Info.Sender)
+	}
+	return false
+}
+
+func (r *CommandRouter) reply(c *Client, msg *events.Message, text string) {
+	if err := c.ReplyToMessage(msg, text); err != nil {
+		log.Printf("WA: No se pudo responder al comando: %v", err)
+	}
+}
+
+// UsageText devuelve la línea de uso de un comando
+func (r *CommandRouter) UsageText(cmd *Command) string {
+	usage := r.Prefix() + cmd.Name
+	if cmd.Usage != "" {
+		usage += " " + cmd.Usage
+	}
+	return usage
+}
+
+// HelpText genera la ayuda a partir del registro de comandos
+func (r *CommandRouter) HelpText() string {
+	var sb strings.Builder
+	sb.WriteString("*Comandos de Lisa*\n")
+	for _, cmd := range r.Commands() {
+		sb.WriteString(fmt.Sprintf("\n%s\n    %s", r.UsageText(cmd), cmd.Description))
+	}
+	sb.WriteString(fmt.Sprintf("\n\nUsa %sayuda <comando> para más detalles. En grupos también puedes mencionarme.", r.Prefix()))
+	return sb.String()
+}
+
+// CommandHelpText genera la ayuda detallada de un comando
+func (r *CommandRouter) CommandHelpText(cmd *Command) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("*%s%s*\n%s\n\nUso: %s", r.Prefix(), cmd.Name, cmd.Description, r.UsageText(cmd)))
+	if len(cmd.Aliases) > 0 {
+		sb.WriteString("\nAlias: " + strings.Join(cmd.Aliases, ", "))
+	}
+	if cmd.Permission != PermissionEveryone {
+		sb.WriteString("\nPermitido a: " + cmd.Permission.String())
+	}
+	if cmd.GroupOnly {
+		sb.WriteString("\nSolo en grupos")
+	}
+	return sb.String()
+}
+
+func (r *CommandRouter) handleHelp(cmd *CommandContext) error {
+	if len(cmd.Args) > 0 {
+		name := strings.TrimLeft(cmd.Args[0], strings.Join(r.prefixes, ""))
+		target, ok := r.Lookup(name)
+		if !ok {
+			return cmd.Reply(fmt.Sprintf("No existe el comando %s.", cmd.Args[0]))
+		}
+		return cmd.Reply(r.CommandHelpText(target))
+	}
+	return cmd.Reply(r.HelpText())
+}
@@ -0,0 +1,125 @@
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"google.golang.org/protobuf/proto"
+
+	"Lisa/pkg/types"
+)
+
+// Quote mensaje citado al responder. Text se usa como vista previa de la cita.
+type Quote struct {
+	ID     string
+	Sender string
+	Text   string
+}
+
+func (q *Quote) contextInfo() *waE2E.ContextInfo {
+	if q == nil || q.ID == "" {
+		return nil
+	}
+	ctxInfo := &waE2E.ContextInfo{
+		StanzaID:      proto.String(q.ID),
+		QuotedMessage: &waE2E.Message{Conversation: proto.String(q.Text)},
+	}
+	if q.Sender != "" {
+		ctxInfo.Participant = proto.String(q.Sender)
+	}
+	return ctxInfo
+}
+
+// SendText envía texto a un chat, opcionalmente como respuesta a otro mensaje.
+// Devuelve el ID del mensaje enviado.
+func (c *Client) SendText(ctx context.Context, chat, text string, quote *Quote) (string, error) {
+	to, err := ParseJID(chat)
+	if err != nil {
+		return "", err
+	}
+
+	msg := &waE2E.Message{Conversation: proto.String(text)}
+	if ctxInfo := quote.contextInfo(); ctxInfo != nil {
+		msg = &waE2E.Message{
+			ExtendedTextMessage: &waE2E.ExtendedTextMessage{
+				Text:        proto.String(text),
+				ContextInfo: ctxInfo,
+			},
+		}
+	}
+
+	resp, err := c.wa().SendMessage(ctx, to, msg)
+	if err != nil {
+		return "", fmt.Errorf("no se pudo enviar el mensaje a %s: %v", to, err)
+	}
+	return resp.ID, nil
+}
+
+// SendMedia sube un archivo y lo envía como imagen, video, audio o documento
+// según su tipo MIME. El Caption del archivo se envía como texto del mensaje.
+func (c *Client) SendMedia(ctx context.Context, chat string, media *types.MediaMessage, quote *Quote) (string, error) {
+	to, err := ParseJID(chat)
+	if err != nil {
+		return "", err
+	}
+	if len(media.Data) == 0 {
+		return "", fmt.Errorf("el archivo %s esta vacio", media.Filename)
+	}
+	if c.mediaMaxBytes > 0 && int64(len(media.Data)) > c.mediaMaxBytes {
+		return "", &MediaTooLargeError{Size: int64(len(media.Data)), Limit: c.mediaMaxBytes}
+	}
+
+	mediaType := whatsmeow.MediaDocument
+	switch {
+	case strings.HasPrefix(media.MimeType, "image/"):
+		mediaType = whatsmeow.MediaImage
+	case strings.HasPrefix(media.MimeType, "video/"):
+		mediaType = whatsmeow.MediaVideo
+	case strings.HasPrefix(media.MimeType, "audio/"):
+		mediaType = whatsmeow.MediaAudio
+	}
+
+	up, err := c.wa().Upload(ctx, media.Data, mediaType)
+	if err != nil {
+		return "", fmt.Errorf("no se pudo subir %s: %v", media.Filename, err)
+	}
+
+	ctxInfo := quote.contextInfo()
+	var msg *waE2E.Message
+	switch mediaType {
+	case whatsmeow.MediaImage:
+		msg = &waE2E.Message{ImageMessage: &waE2E.ImageMessage{
+			URL: proto.String(up.URL), DirectPath: proto.String(up.DirectPath), MediaKey: up.MediaKey,
+			FileEncSHA256: up.FileEncSHA256, FileSHA256: up.FileSHA256, FileLength: proto.Uint64(up.FileLength),
+			Mimetype: proto.String(media.MimeType), Caption: proto.String(media.Caption), ContextInfo: ctxInfo,
+		}}
+	case whatsmeow.MediaVideo:
+		msg = &waE2E.Message{VideoMessage: &waE2E.VideoMessage{
+			URL: proto.String(up.URL), DirectPath: proto.String(up.DirectPath), MediaKey: up.MediaKey,
+			FileEncSHA256: up.FileEncSHA256, FileSHA256: up.FileSHA256, FileLength: proto.Uint64(up.FileLength),
+			Mimetype: proto.String(media.MimeType), Caption: proto.String(media.Caption), ContextInfo: ctxInfo,
+		}}
+	case whatsmeow.MediaAudio:
+		msg = &waE2E.Message{AudioMessage: &waE2E.AudioMessage{
+			URL: proto.String(up.URL), DirectPath: proto.String(up.DirectPath), MediaKey: up.MediaKey,
+			FileEncSHA256: up.FileEncSHA256, FileSHA256: up.FileSHA256, FileLength: proto.Uint64(up.FileLength),
+			Mimetype: proto.String(media.MimeType), ContextInfo: ctxInfo,
+		}}
+	default:
+		msg = &waE2E.Message{DocumentMessage: &waE2E.DocumentMessage{
+			URL: proto.String(up.URL), DirectPath: proto.String(up.DirectPath), MediaKey: up.MediaKey,
+			FileEncSHA256: up.FileEncSHA256, FileSHA256: up.FileSHA256, FileLength: proto.Uint64(up.FileLength),
+			Mimetype: proto.String(media.MimeType), FileName: proto.String(media.Filename),
+			Title: proto.String(media.Filename), Caption: proto.String(media.Caption), ContextInfo: ctxInfo,
+		}}
+	}
+
+	resp, err := c.wa().SendMessage(ctx, to, msg)
+	if err != nil {
+		return "", fmt.Errorf("no se pudo enviar %s a %s: %v", media.Filename, to, err)
+	}
+	return resp.ID, nil
+}
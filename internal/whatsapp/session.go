@@ -1 +1,222 @@
 package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// ConnectionState estado de la conexión con WhatsApp
+type ConnectionState string
+
+const (
+	StateDisconnected ConnectionState = "disconnected"
+	StateConnecting   ConnectionState = "connecting"
+	StatePairing      ConnectionState = "pairing"
+	StateConnected    ConnectionState = "connected"
+	StateLoggedOut    ConnectionState = "logged_out"
+	StateProxyError   ConnectionState = "proxy_error"
+)
+
+// proxyProbeTimeout tiempo máximo de la prueba del proxy tras una desconexión
+const proxyProbeTimeout = 15 * time.Second
+
+// pairDisplayName nombre con el que aparece Lisa en "Dispositivos vinculados".
+// WhatsApp solo acepta el formato "Navegador (SO)".
+const pairDisplayName = "Chrome (Linux)"
+
+// State devuelve el estado actual de la conexión
+func (c *Client) State() ConnectionState {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.state
+}
+
+// StateReason devuelve el motivo del último cambio de estado (por ejemplo, el error del proxy)
+func (c *Client) StateReason() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.stateReason
+}
+
+// setState actualiza el estado y lo publica en el bus si cambió
+func (c *Client) setState(state ConnectionState, reason string) {
+	c.mu.Lock()
+	changed := c.state != state
+	c.state = state
+	c.stateReason = reason
+	c.mu.Unlock()
+
+	if changed {
+		c.events.Publish(Event{
+			Type:      EventConnectionState,
+			State:     state,
+			Reason:    reason,
+			Timestamp: time.Now(),
+		})
+	}
+}
+
+// connectError registra la falla de conexión en el estado, distinguiendo las
+// fallas del proxy para que no se confundan con problemas de WhatsApp
+func (c *Client) connectError(err error) error {
+	if c.proxy != nil && isProxyError(err) {
+		c.setState(StateProxyError, err.Error())
+		return fmt.Errorf("no se pudo conectar a traves del proxy: %v", err)
+	}
+	c.setState(StateDisconnected, err.Error())
+	return fmt.Errorf("no se pudo conectar: %v", err)
+}
+
+// reconnectError registra las fallas de las reconexiones automáticas de
+// whatsmeow. Devuelve true para que siga reintentando.
+func (c *Client) reconnectError(err error) bool {
+	if c.proxy != nil && isProxyError(err) {
+		c.setState(StateProxyError, err.Error())
+	} else if c.State() != StateConnected {
+		c.setState(StateDisconnected, err.Error())
+	}
+	return true
+}
+
+// checkProxy prueba el proxy tras una desconexión o un keepalive fallido y,
+// si no responde, deja el estado en error de proxy
+func (c *Client) checkProxy() {
+	if c.proxy == nil || c.ctx.Err() != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.ctx, proxyProbeTimeout)
+	defer cancel()
+
+	if err := c.proxy.probe(ctx); err != nil && isProxyError(err) {
+		log.Printf("WA: El proxy no responde: %v", err)
+		c.setState(StateProxyError, err.Error())
+	}
+}
+
+// pair vincula un dispositivo nuevo por QR o, si hay WA_PAIR_PHONE configurado,
+// por código de vinculación. Los códigos expiran a los pocos minutos, así que
+// se generan nuevos hasta que la vinculación termine o se cancele el contexto.
+func (c *Client) pair() error {
+	for {
+		paired, err := c.pairOnce()
+		if err != nil {
+			if c.State() == StatePairing {
+				c.setState(StateLoggedOut, err.Error())
+			}
+			return err
+		}
+		if paired {
+			return nil
+		}
+		if c.ctx.Err() != nil {
+			return c.ctx.Err()
+		}
+		log.Println("WA: El codigo de vinculacion expiro, generando uno nuevo...")
+	}
+}
+
+func (c *Client) pairOnce() (bool, error) {
+	whatsAppClient := c.wa()
+
+	qrChan, err := whatsAppClient.GetQRChannel(c.ctx)
+	if err != nil {
+		return false, fmt.Errorf("no se pudo iniciar la vinculacion: %v", err)
+	}
+
+	c.setState(StatePairing, "")
+	if err := whatsAppClient.Connect(); err != nil {
+		return false, c.connectError(err)
+	}
+
+	if c.pairPhone == "" {
+		log.Println("WA: Escanea el codigo QR para iniciar sesion...")
+	}
+
+	// PairPhone exige la conexión completa, que whatsmeow señala con el
+	// primer código QR: el código de vinculación se pide recién entonces
+	pairCodeSent := false
+	for evt := range qrChan {
+		switch evt.Event {
+		case whatsmeow.QRChannelEventCode:
+			// Con código de vinculación el QR no hace falta
+			if c.pairPhone == "" {
+				c.DisplayQRInTerminal(evt.Code)
+				continue
+			}
+			if pairCodeSent {
+				continue
+			}
+			code, err := whatsAppClient.PairPhone(c.ctx, c.pairPhone, true, whatsmeow.PairClientChrome, pairDisplayName)
+			if err != nil {
+				return false, fmt.Errorf("no se pudo generar el codigo de vinculacion: %v", err)
+			}
+			pairCodeSent = true
+			log.Printf("WA: Codigo de vinculacion para %s: %s", c.pairPhone, code)
+			c.events.Publish(Event{Type: EventPairingCode, Reason: code, Timestamp: time.Now()})
+		case whatsmeow.QRChannelSuccess.Event:
+			log.Println("WA: Dispositivo vinculado exitosamente")
+			c.events.Publish(Event{Type: EventPaired, Timestamp: time.Now()})
+			return true, nil
+		case whatsmeow.QRChannelTimeout.Event:
+			return false, nil
+		default:
+			if evt.Error != nil {
+				return false, fmt.Errorf("fallo la vinculacion (%s): %v", evt.Event, evt.Error)
+			}
+			return false, fmt.Errorf("fallo la vinculacion: %s", evt.Event)
+		}
+	}
+
+	return false, nil
+}
+
+// handleLoggedOut se ejecuta cuando WhatsApp invalida la sesión (el usuario
+// desvinculó el dispositivo o la sesión expiró). El dispositivo guardado ya no
+// sirve, así que se elimina y se inicia una vinculación nueva.
+func (c *Client) handleLoggedOut(evt *events.LoggedOut) {
+	reason := evt.Reason.String()
+	c.logger.Warnf("Cliente WhatsApp sesion cerrada (%s)", reason)
+
+	c.setState(StateLoggedOut, reason)
+	c.events.Publish(Event{Type: EventLoggedOut, Reason: reason, Timestamp: time.Now()})
+
+	go c.repair()
+}
+
+// repair reemplaza el dispositivo inválido por uno nuevo y vuelve a vincular
+func (c *Client) repair() {
+	// Evitar dos re-vinculaciones simultáneas si llegan eventos repetidos
+	if !c.repairing.CompareAndSwap(false, true) {
+		return
+	}
+	defer c.repairing.Store(false)
+
+	old := c.wa()
+	old.RemoveEventHandlers()
+	old.Disconnect()
+
+	// whatsmeow puede haber borrado el dispositivo antes que nosotros
+	if old.Store.ID != nil {
+		if err := old.Store.Delete(c.ctx); err != nil {
+			log.Printf("WA: No se pudo eliminar el dispositivo anterior: %v", err)
+		}
+	}
+
+	whatsAppClient, err := c.newWhatsAppClient(c.container.NewDevice())
+	if err != nil {
+		log.Printf("WA: No se pudo crear el cliente para re-vincular: %v", err)
+		return
+	}
+	c.setClient(whatsAppClient)
+	c.groups.Clear()
+
+	log.Println("WA: Iniciando nueva vinculacion...")
+	if err := c.pair(); err != nil {
+		log.Printf("WA: La re-vinculacion fallo: %v", err)
+	}
+}
@@ -1 +1,7 @@
 package types
+
+// Origen de un mensaje que entra al triaje
+const (
+	SourceWhatsApp = "whatsapp"
+	SourceDiscord  = "discord"
+)
@@ -1 +1,26 @@
 package types
+
+// IssueCategory categoría que la IA asigna a un mensaje
+type IssueCategory string
+
+const (
+	CategoryBug      IssueCategory = "bug"
+	CategoryRequest  IssueCategory = "request"
+	CategoryQuestion IssueCategory = "question"
+	CategoryOther    IssueCategory = "other"
+)
+
+// Classification resultado de clasificar un mensaje con la IA
+type Classification struct {
+	Category   IssueCategory `json:"category"`
+	Confidence float64       `json:"confidence"` // 0 a 1
+	Summary    string        `json:"summary"`
+	Priority   string        `json:"priority,omitempty"`
+	IssueType  string        `json:"issue_type,omitempty"`
+	Suggestion string        `json:"suggestion,omitempty"` // Respuesta sugerida para el cliente
+}
+
+// Actionable indica si el mensaje es un problema o pedido que amerita triaje
+func (c *Classification) Actionable() bool {
+	return c != nil && (c.Category == CategoryBug || c.Category == CategoryRequest)
+}
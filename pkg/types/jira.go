@@ -1 +1,86 @@
 package types
+
+import "time"
+
+// TicketDraft datos necesarios para crear un ticket desde una conversación
+type TicketDraft struct {
+	ProjectKey  string   `json:"project_key,omitempty"`
+	Summary     string   `json:"summary"`
+	Description string   `json:"description"`
+	IssueType   string   `json:"issue_type,omitempty"`
+	Priority    string   `json:"priority,omitempty"`
+	Labels      []string `json:"labels,omitempty"`
+	Components  []string `json:"components,omitempty"`
+	Reporter    string   `json:"reporter,omitempty"`
+
+	// Origen del ticket (chat de WhatsApp, canal de Discord, etc.)
+	SourceChat string `json:"source_chat,omitempty"`
+}
+
+// TicketInfo resumen de un ticket existente
+type TicketInfo struct {
+	Key        string    `json:"key"`
+	Summary    string    `json:"summary"`
+	Status     string    `json:"status"`
+	Priority   string    `json:"priority,omitempty"`
+	Assignee   string    `json:"assignee,omitempty"`
+	AssigneeID string    `json:"assignee_id,omitempty"`
+	URL        string    `json:"url"`
+	Updated    time.Time `json:"updated"`
+}
+
+// ProjectInfo proyecto de Jira
+type ProjectInfo struct {
+	Key  string `json:"key"`
+	Name string `json:"name"`
+}
+
+// Transition transición de workflow disponible para un ticket
+type Transition struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	ToStatus string `json:"to_status"`
+}
+
+// JiraUser usuario de Jira que puede asignarse a un ticket
+type JiraUser struct {
+	AccountID   string `json:"account_id"`
+	DisplayName string `json:"display_name"`
+	Email       string `json:"email,omitempty"`
+}
+
+// TicketQuery criterios de búsqueda de tickets. Los vacíos se ignoran y los
+// demás se combinan.
+type TicketQuery struct {
+	// Text texto mencionado en el resumen, la descripción o los comentarios
+	Text string `json:"text,omitempty"`
+	// Chat chat de WhatsApp (o canal de Discord) desde el que se creó el ticket
+	Chat string `json:"chat,omitempty"`
+	// Customer nombre o teléfono del cliente
+	Customer   string `json:"customer,omitempty"`
+	ProjectKey string `json:"project_key,omitempty"`
+	OpenOnly   bool   `json:"open_only,omitempty"`
+}
+
+// Tipos de novedades de un ticket
+const (
+	TicketEventStatus     = "status"
+	TicketEventComment    = "comment"
+	TicketEventAssignee   = "assignee"
+	TicketEventResolution = "resolution"
+)
+
+// TicketEvent novedad de un ticket recibida de Jira
+type TicketEvent struct {
+	Type    string `json:"type"`
+	Key     string `json:"key"`
+	Summary string `json:"summary"`
+	URL     string `json:"url"`
+	// Author quién hizo el cambio o escribió el comentario
+	Author string `json:"author,omitempty"`
+	// From y To valores anterior y nuevo (estado, asignado o resolución)
+	From      string    `json:"from,omitempty"`
+	To        string    `json:"to,omitempty"`
+	Comment   string    `json:"comment,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
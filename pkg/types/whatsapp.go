@@ -3,6 +3,7 @@ package types
 import (
 	"time"
 
+	"go.mau.fi/whatsmeow/proto/waE2E"
 	"go.mau.fi/whatsmeow/types"
 	"go.mau.fi/whatsmeow/types/events"
 )
@@ -12,12 +13,22 @@ type MessageInfo struct {
 	ID        string    `json:"id"`
 	From      string    `json:"from"`
 	To        string    `json:"to"`
+	Sender    string    `json:"sender"`
 	PushName  string    `json:"push_name"`
 	Text      string    `json:"text"`
 	Timestamp time.Time `json:"timestamp"`
 	IsGroup   bool      `json:"is_group"`
 	GroupName string    `json:"group_name,omitempty"`
 	IsFromMe  bool      `json:"is_from_me"`
+
+	// Source origen del mensaje (SourceWhatsApp o SourceDiscord). En los
+	// mensajes de Discord, From es el ID del canal.
+	Source string `json:"source,omitempty"`
+
+	// Mensaje citado cuando es una respuesta
+	QuotedID     string `json:"quoted_id,omitempty"`
+	QuotedSender string `json:"quoted_sender,omitempty"`
+	QuotedText   string `json:"quoted_text,omitempty"`
 }
 
 // MediaMessage representa un archivo multimedia de WhatsApp
@@ -128,14 +139,48 @@ func NewMessageInfoFromEvent(msg *events.Message) MessageInfo {
 		text = msg.Message.ExtendedTextMessage.GetText()
 	}
 
-	return MessageInfo{
+	info := MessageInfo{
 		ID:        msg.Info.ID,
 		From:      msg.Info.Chat.String(),
+		Sender:    msg.Info.Sender.String(),
 		PushName:  msg.Info.PushName,
 		Text:      text,
 		Timestamp: msg.Info.Timestamp,
 		IsGroup:   msg.Info.IsGroup,
 		IsFromMe:  msg.Info.IsFromMe,
+		Source:    SourceWhatsApp,
+	}
+
+	if ctxInfo := contextInfo(msg.Message); ctxInfo.GetStanzaID() != "" {
+		info.QuotedID = ctxInfo.GetStanzaID()
+		info.QuotedSender = ctxInfo.GetParticipant()
+		quoted := ctxInfo.GetQuotedMessage()
+		info.QuotedText = quoted.GetConversation()
+		if info.QuotedText == "" {
+			info.QuotedText = quoted.GetExtendedTextMessage().GetText()
+		}
+	}
+
+	return info
+}
+
+// contextInfo devuelve el contexto (respuesta, menciones) del tipo de mensaje que lo tenga
+func contextInfo(msg *waE2E.Message) *waE2E.ContextInfo {
+	switch {
+	case msg.GetExtendedTextMessage() != nil:
+		return msg.GetExtendedTextMessage().GetContextInfo()
+	case msg.GetImageMessage() != nil:
+		return msg.GetImageMessage().GetContextInfo()
+	case msg.GetVideoMessage() != nil:
+		return msg.GetVideoMessage().GetContextInfo()
+	case msg.GetAudioMessage() != nil:
+		return msg.GetAudioMessage().GetContextInfo()
+	case msg.GetDocumentMessage() != nil:
+		return msg.GetDocumentMessage().GetContextInfo()
+	case msg.GetStickerMessage() != nil:
+		return msg.GetStickerMessage().GetContextInfo()
+	default:
+		return nil
 	}
 }
 